@@ -0,0 +1,319 @@
+/*
+Copyright 2026 The Knative Authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package main
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/tls"
+	"crypto/x509"
+	"errors"
+	"fmt"
+	"net"
+	"sort"
+	"sync"
+	"time"
+
+	"go.uber.org/atomic"
+	"golang.org/x/time/rate"
+
+	ctrl "knative.dev/control-protocol/pkg"
+	"knative.dev/control-protocol/pkg/certificates"
+	"knative.dev/control-protocol/pkg/network"
+	"knative.dev/control-protocol/pkg/reconciler"
+)
+
+const (
+	poolKey = "ctrl-bench"
+
+	// sendErrorBackoff is the wait of a worker after a failed send, so a service failing immediately doesn't spin the worker
+	sendErrorBackoff = 100 * time.Millisecond
+)
+
+type config struct {
+	servers       int
+	remote        []string
+	tls           bool
+	certsDir      string
+	tlsServerName string
+	duration      time.Duration
+	rate          float64
+	payloadSize   int
+	concurrency   int
+	opcode        uint
+}
+
+func (c config) validate() error {
+	if len(c.remote) == 0 && c.servers <= 0 {
+		return errors.New("at least one server is required")
+	}
+	if c.concurrency <= 0 {
+		return errors.New("concurrency must be positive")
+	}
+	if c.duration <= 0 {
+		return errors.New("duration must be positive")
+	}
+	if c.payloadSize < 0 || c.rate < 0 {
+		return errors.New("payload size and rate cannot be negative")
+	}
+	if c.opcode >= uint(ctrl.MinReservedOpCode) {
+		return fmt.Errorf("opcode %d is reserved", c.opcode)
+	}
+	return nil
+}
+
+type payload []byte
+
+func (p payload) MarshalBinary() ([]byte, error) {
+	return p, nil
+}
+
+// run starts the servers, if needed, and drives them until the duration elapses or the context is closed
+func run(ctx context.Context, cfg config) (*result, error) {
+	if err := cfg.validate(); err != nil {
+		return nil, err
+	}
+
+	ctx, cancelFn := context.WithCancel(ctx)
+	defer cancelFn()
+
+	hosts, dialerFactory, err := setupServers(ctx, cfg)
+	if err != nil {
+		return nil, err
+	}
+
+	var pool reconciler.ControlPlaneConnectionPool
+	if dialerFactory != nil {
+		pool = reconciler.NewControlPlaneConnectionPool(dialerFactory)
+	} else {
+		pool = reconciler.NewInsecureControlPlaneConnectionPool()
+	}
+	defer pool.Close(ctx)
+
+	baseline := sampleRuntime()
+
+	dialStart := time.Now()
+	services, err := pool.ReconcileConnections(ctx, poolKey, hosts, nil, nil)
+	var dialFailures map[string]error
+	if err != nil {
+		// Drive the servers which connected, reporting the others
+		var reconcileErr *reconciler.ReconcileError
+		if !errors.As(err, &reconcileErr) {
+			return nil, fmt.Errorf("cannot connect to the servers: %w", err)
+		}
+		dialFailures = reconcileErr.Failed
+	}
+	dialTime := time.Since(dialStart)
+	if len(services) == 0 {
+		return nil, errors.New("no connected server")
+	}
+	svcs := make([]ctrl.Service, 0, len(services))
+	for _, h := range hosts {
+		if svc, ok := services[h]; ok {
+			svcs = append(svcs, svc)
+		}
+	}
+
+	res := drive(ctx, cfg, svcs)
+	res.servers = len(svcs)
+	res.tls = cfg.tls
+	res.dialTime = dialTime
+	res.dialFailures = dialFailures
+	res.baseline = baseline
+	return res, nil
+}
+
+func drive(ctx context.Context, cfg config, svcs []ctrl.Service) *result {
+	p := make(payload, cfg.payloadSize)
+	_, _ = rand.Read(p)
+
+	var limiter *rate.Limiter
+	if cfg.rate > 0 {
+		limiter = rate.NewLimiter(rate.Limit(cfg.rate), 1)
+	}
+
+	runCtx, cancelFn := context.WithTimeout(ctx, cfg.duration)
+	defer cancelFn()
+
+	peak := newRuntimeSampler()
+	go peak.run(runCtx, 100*time.Millisecond)
+
+	var next atomic.Uint64
+	var errorsCount atomic.Uint64
+	latencies := make([][]time.Duration, cfg.concurrency)
+
+	start := time.Now()
+	var wg sync.WaitGroup
+	for w := 0; w < cfg.concurrency; w++ {
+		wg.Add(1)
+		go func(w int) {
+			defer wg.Done()
+			for runCtx.Err() == nil {
+				if limiter != nil && limiter.Wait(runCtx) != nil {
+					return
+				}
+				svc := svcs[next.Inc()%uint64(len(svcs))]
+				sendStart := time.Now()
+				err := send(runCtx, svc, ctrl.OpCode(cfg.opcode), p)
+				if err != nil {
+					if runCtx.Err() == nil {
+						errorsCount.Inc()
+					}
+					if !backoff(runCtx, sendErrorBackoff) {
+						return
+					}
+					continue
+				}
+				latencies[w] = append(latencies[w], time.Since(sendStart))
+			}
+		}(w)
+	}
+	wg.Wait()
+	elapsed := time.Since(start)
+
+	var all []time.Duration
+	for _, l := range latencies {
+		all = append(all, l...)
+	}
+	sort.Slice(all, func(i, j int) bool { return all[i] < all[j] })
+
+	return &result{
+		elapsed:     elapsed,
+		concurrency: cfg.concurrency,
+		payloadSize: cfg.payloadSize,
+		acked:       len(all),
+		errors:      errorsCount.Load(),
+		latencies:   all,
+		peak:        peak.peak(),
+		final:       sampleRuntime(),
+	}
+}
+
+// backoff waits for d, returning false if ctx is done in the meantime
+func backoff(ctx context.Context, d time.Duration) bool {
+	timer := time.NewTimer(d)
+	defer timer.Stop()
+	select {
+	case <-timer.C:
+		return true
+	case <-ctx.Done():
+		return false
+	}
+}
+
+// send sends the message interrupting the wait for the ack when ctx is done, so the run doesn't overrun its duration
+func send(ctx context.Context, svc ctrl.Service, opcode ctrl.OpCode, p payload) error {
+	if sender, ok := svc.(ctrl.ContextSender); ok {
+		return sender.SendAndWaitForAckWithContext(ctx, opcode, p)
+	}
+	return svc.SendAndWaitForAck(opcode, p)
+}
+
+// setupServers returns the hosts to drive and the dialer factory to use, nil for insecure connections.
+func setupServers(ctx context.Context, cfg config) ([]string, reconciler.TLSDialerFactory, error) {
+	if len(cfg.remote) != 0 {
+		if !cfg.tls {
+			return cfg.remote, nil, nil
+		}
+		tlsConfig, err := network.LoadClientTLSConfigFromDir(cfg.certsDir, cfg.tlsServerName)
+		if err != nil {
+			return nil, nil, fmt.Errorf("cannot load the tls configuration: %w", err)
+		}
+		return cfg.remote, staticTLSDialerFactory{config: tlsConfig}, nil
+	}
+
+	var serverTLSConfig func() (*tls.Config, error)
+	var dialerFactory reconciler.TLSDialerFactory
+	if cfg.tls {
+		serverConfig, clientConfig, err := generateTLSConfigs(ctx)
+		if err != nil {
+			return nil, nil, fmt.Errorf("cannot generate the certificates: %w", err)
+		}
+		serverTLSConfig = func() (*tls.Config, error) {
+			return serverConfig, nil
+		}
+		dialerFactory = staticTLSDialerFactory{config: clientConfig}
+	}
+
+	hosts := make([]string, 0, cfg.servers)
+	for i := 0; i < cfg.servers; i++ {
+		server, err := network.StartControlServer(ctx, serverTLSConfig, network.WithPort(0))
+		if err != nil {
+			return nil, nil, fmt.Errorf("cannot start the server %d: %w", i, err)
+		}
+		server.MessageHandler(ctrl.MessageHandlerFunc(func(ctx context.Context, message ctrl.ServiceMessage) {
+			message.Ack()
+		}))
+		hosts = append(hosts, fmt.Sprintf("127.0.0.1:%d", server.ListeningPort()))
+	}
+	return hosts, dialerFactory, nil
+}
+
+// generateTLSConfigs generates an ephemeral CA and the server and client certificates
+func generateTLSConfigs(ctx context.Context) (*tls.Config, *tls.Config, error) {
+	const namespace = "ctrl-bench"
+	caKP, err := certificates.CreateCACerts(ctx, 24*time.Hour)
+	if err != nil {
+		return nil, nil, err
+	}
+	caCert, caKey, err := caKP.Parse()
+	if err != nil {
+		return nil, nil, err
+	}
+	certPool := x509.NewCertPool()
+	certPool.AddCert(caCert)
+
+	serverKP, err := certificates.CreateCert(ctx, caKey, caCert, 24*time.Hour, certificates.DataPlaneUserName(namespace), certificates.LegacyFakeDnsName)
+	if err != nil {
+		return nil, nil, err
+	}
+	serverCert, err := tls.X509KeyPair(serverKP.CertBytes(), serverKP.PrivateKeyBytes())
+	if err != nil {
+		return nil, nil, err
+	}
+
+	clientKP, err := certificates.CreateCert(ctx, caKey, caCert, 24*time.Hour, certificates.DataPlaneRoutingName(""))
+	if err != nil {
+		return nil, nil, err
+	}
+	clientCert, err := tls.X509KeyPair(clientKP.CertBytes(), clientKP.PrivateKeyBytes())
+	if err != nil {
+		return nil, nil, err
+	}
+
+	return &tls.Config{
+		Certificates: []tls.Certificate{serverCert},
+		ClientCAs:    certPool,
+		ClientAuth:   tls.RequireAndVerifyClientCert,
+	}, &tls.Config{
+		Certificates: []tls.Certificate{clientCert},
+		RootCAs:      certPool,
+		ServerName:   certificates.DataPlaneUserName(namespace),
+	}, nil
+}
+
+type staticTLSDialerFactory struct {
+	config *tls.Config
+}
+
+func (f staticTLSDialerFactory) GenerateTLSDialer(baseDialOptions *net.Dialer) (*tls.Dialer, error) {
+	dialOptions := *baseDialOptions
+	return &tls.Dialer{
+		NetDialer: &dialOptions,
+		Config:    f.config,
+	}, nil
+}
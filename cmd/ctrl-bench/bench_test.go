@@ -0,0 +1,122 @@
+/*
+Copyright 2026 The Knative Authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package main
+
+import (
+	"bytes"
+	"context"
+	"encoding"
+	"errors"
+	"fmt"
+	"net"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+
+	ctrl "knative.dev/control-protocol/pkg"
+	"knative.dev/control-protocol/pkg/network"
+)
+
+func TestPercentile(t *testing.T) {
+	r := &result{}
+	require.Zero(t, r.percentile(50))
+
+	for i := 1; i <= 100; i++ {
+		r.latencies = append(r.latencies, time.Duration(i)*time.Millisecond)
+	}
+	require.Equal(t, 50*time.Millisecond, r.percentile(50))
+	require.Equal(t, 99*time.Millisecond, r.percentile(99))
+	require.Equal(t, 100*time.Millisecond, r.percentile(100))
+	require.Equal(t, time.Millisecond, r.percentile(0))
+}
+
+func TestFormatBytes(t *testing.T) {
+	require.Equal(t, "512B", formatBytes(512))
+	require.Equal(t, "1.5KiB", formatBytes(1536))
+	require.Equal(t, "2.0MiB", formatBytes(2*1024*1024))
+}
+
+func TestRun(t *testing.T) {
+	for _, useTLS := range []bool{false, true} {
+		res, err := run(context.TODO(), config{
+			servers:     2,
+			tls:         useTLS,
+			duration:    200 * time.Millisecond,
+			payloadSize: 16,
+			concurrency: 2,
+			opcode:      1,
+		})
+		require.NoError(t, err)
+		require.Equal(t, 2, res.servers)
+		require.NotZero(t, res.acked)
+		require.Zero(t, res.errors)
+	}
+}
+
+func TestRun_PartiallyConnected(t *testing.T) {
+	ctx, cancelFn := context.WithCancel(context.TODO())
+	t.Cleanup(cancelFn)
+
+	server, err := network.StartInsecureControlServer(ctx, network.WithPort(0))
+	require.NoError(t, err)
+	server.MessageHandler(ctrl.MessageHandlerFunc(func(ctx context.Context, message ctrl.ServiceMessage) {
+		message.Ack()
+	}))
+	// Nobody listens on the closed listener port
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+	closedHost := ln.Addr().String()
+	require.NoError(t, ln.Close())
+
+	res, err := run(ctx, config{
+		remote:      []string{fmt.Sprintf("127.0.0.1:%d", server.ListeningPort()), closedHost},
+		duration:    200 * time.Millisecond,
+		payloadSize: 16,
+		concurrency: 2,
+		opcode:      1,
+	})
+	require.NoError(t, err)
+	require.Equal(t, 1, res.servers)
+	require.NotZero(t, res.acked)
+	require.Contains(t, res.dialFailures, closedHost)
+
+	var out bytes.Buffer
+	res.print(&out)
+	require.Contains(t, out.String(), "cannot connect to 1 server(s)")
+}
+
+type failingService struct {
+	ctrl.Service
+}
+
+func (failingService) SendAndWaitForAck(opcode ctrl.OpCode, payload encoding.BinaryMarshaler) error {
+	return errors.New("closed")
+}
+
+func TestDrive_BacksOffOnErrors(t *testing.T) {
+	res := drive(context.TODO(), config{
+		duration:    200 * time.Millisecond,
+		concurrency: 2,
+		opcode:      1,
+	}, []ctrl.Service{failingService{}})
+
+	require.Zero(t, res.acked)
+	// Every worker fails at most once per backoff
+	require.NotZero(t, res.errors)
+	require.LessOrEqual(t, res.errors, uint64(2*(200*time.Millisecond/sendErrorBackoff+1)))
+}
@@ -0,0 +1,78 @@
+/*
+Copyright 2026 The Knative Authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+// ctrl-bench is a load generator for the control protocol.
+// It drives a set of control servers through a reconciler.ControlPlaneConnectionPool,
+// reporting throughput, ack latency percentiles, memory and goroutines.
+//
+// Usage:
+//
+//	ctrl-bench -servers 100 -concurrency 32 -rate 5000 -payload-size 256 -duration 30s [-tls]
+//	ctrl-bench -remote 10.0.0.1:9000,10.0.0.2:9000 -tls -certs-dir /etc/control-secret
+//
+// In-process servers ack every message. Remote servers must ack the benchmark opcode, e.g. ctrl-fake-server can be used.
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"os"
+	"os/signal"
+	"strings"
+	"syscall"
+	"time"
+
+	"go.uber.org/zap"
+	"knative.dev/pkg/logging"
+
+	"knative.dev/control-protocol/pkg/certificates"
+	"knative.dev/control-protocol/pkg/network"
+)
+
+func main() {
+	cfg := config{}
+	flag.IntVar(&cfg.servers, "servers", 10, "Number of in-process control servers. Ignored when -remote is set")
+	remote := flag.String("remote", "", "Comma separated list of remote control servers to drive, instead of starting in-process servers")
+	flag.BoolVar(&cfg.tls, "tls", false, "Use mTLS")
+	flag.StringVar(&cfg.certsDir, "certs-dir", network.DefaultCertsDir, "Directory containing the certificates of the control secret, used with -tls and -remote")
+	flag.StringVar(&cfg.tlsServerName, "tls-server-name", certificates.DataPlaneUserName("myns"), "Server name expected in the server certificate, used with -tls and -remote")
+	flag.DurationVar(&cfg.duration, "duration", 10*time.Second, "Duration of the benchmark")
+	flag.Float64Var(&cfg.rate, "rate", 0, "Overall messages per second. 0 means as fast as possible")
+	flag.IntVar(&cfg.payloadSize, "payload-size", 64, "Payload size in bytes")
+	flag.IntVar(&cfg.concurrency, "concurrency", 16, "Number of concurrent senders")
+	flag.UintVar(&cfg.opcode, "opcode", 1, "Opcode of the benchmark messages")
+	verbose := flag.Bool("v", false, "Enable the debug logs of the control protocol")
+	flag.Parse()
+
+	if *remote != "" {
+		cfg.remote = strings.Split(*remote, ",")
+	}
+
+	logger := zap.NewNop()
+	if *verbose {
+		logger, _ = zap.NewDevelopment()
+	}
+	ctx, cancelFn := signal.NotifyContext(logging.WithLogger(context.Background(), logger.Sugar()), os.Interrupt, syscall.SIGTERM)
+	defer cancelFn()
+
+	res, err := run(ctx, cfg)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		os.Exit(1)
+	}
+	res.print(os.Stdout)
+}
@@ -0,0 +1,167 @@
+/*
+Copyright 2026 The Knative Authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package main
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"runtime"
+	"sort"
+	"sync"
+	"time"
+)
+
+type result struct {
+	servers     int
+	tls         bool
+	concurrency int
+	payloadSize int
+	dialTime    time.Duration
+	// dialFailures are the servers which cannot be connected, with the error
+	dialFailures map[string]error
+
+	elapsed time.Duration
+	acked   int
+	errors  uint64
+	// latencies are sorted
+	latencies []time.Duration
+
+	baseline runtimeSample
+	peak     runtimeSample
+	final    runtimeSample
+}
+
+// percentile returns the latency at the provided percentile, in the range [0, 100]
+func (r *result) percentile(p float64) time.Duration {
+	if len(r.latencies) == 0 {
+		return 0
+	}
+	i := int(float64(len(r.latencies))*p/100+0.5) - 1
+	if i < 0 {
+		i = 0
+	}
+	if i >= len(r.latencies) {
+		i = len(r.latencies) - 1
+	}
+	return r.latencies[i]
+}
+
+func (r *result) throughput() float64 {
+	if r.elapsed <= 0 {
+		return 0
+	}
+	return float64(r.acked) / r.elapsed.Seconds()
+}
+
+func (r *result) print(w io.Writer) {
+	fmt.Fprintf(w, "servers=%d tls=%v concurrency=%d payload=%dB duration=%v dial=%v\n",
+		r.servers, r.tls, r.concurrency, r.payloadSize, r.elapsed.Round(time.Millisecond), r.dialTime.Round(time.Millisecond))
+	if len(r.dialFailures) != 0 {
+		hosts := make([]string, 0, len(r.dialFailures))
+		for host := range r.dialFailures {
+			hosts = append(hosts, host)
+		}
+		sort.Strings(hosts)
+		fmt.Fprintf(w, "cannot connect to %d server(s):\n", len(hosts))
+		for _, host := range hosts {
+			fmt.Fprintf(w, "  %s: %v\n", host, r.dialFailures[host])
+		}
+	}
+	fmt.Fprintf(w, "acked=%d errors=%d throughput=%.1f msg/s (%.2f MiB/s)\n",
+		r.acked, r.errors, r.throughput(), r.throughput()*float64(r.payloadSize)/(1024*1024))
+	if len(r.latencies) != 0 {
+		fmt.Fprintf(w, "ack latency p50=%v p90=%v p99=%v p99.9=%v max=%v\n",
+			r.percentile(50), r.percentile(90), r.percentile(99), r.percentile(99.9), r.latencies[len(r.latencies)-1])
+	}
+	fmt.Fprintf(w, "goroutines baseline=%d peak=%d\n", r.baseline.goroutines, r.peak.goroutines)
+	fmt.Fprintf(w, "heap baseline=%s peak=%s, allocated during the run=%s, sys=%s\n",
+		formatBytes(r.baseline.heapAlloc), formatBytes(r.peak.heapAlloc), formatBytes(r.final.totalAlloc-r.baseline.totalAlloc), formatBytes(r.final.sys))
+}
+
+func formatBytes(b uint64) string {
+	const unit = 1024
+	if b < unit {
+		return fmt.Sprintf("%dB", b)
+	}
+	div, exp := uint64(unit), 0
+	for n := b / unit; n >= unit; n /= unit {
+		div *= unit
+		exp++
+	}
+	return fmt.Sprintf("%.1f%ciB", float64(b)/float64(div), "KMGTPE"[exp])
+}
+
+type runtimeSample struct {
+	goroutines int
+	heapAlloc  uint64
+	totalAlloc uint64
+	sys        uint64
+}
+
+func sampleRuntime() runtimeSample {
+	var m runtime.MemStats
+	runtime.ReadMemStats(&m)
+	return runtimeSample{
+		goroutines: runtime.NumGoroutine(),
+		heapAlloc:  m.HeapAlloc,
+		totalAlloc: m.TotalAlloc,
+		sys:        m.Sys,
+	}
+}
+
+// runtimeSampler tracks the peak of goroutines and heap during the run
+type runtimeSampler struct {
+	mutex sync.Mutex
+	max   runtimeSample
+}
+
+func newRuntimeSampler() *runtimeSampler {
+	return &runtimeSampler{max: sampleRuntime()}
+}
+
+func (s *runtimeSampler) run(ctx context.Context, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ticker.C:
+			s.sample()
+		case <-ctx.Done():
+			return
+		}
+	}
+}
+
+func (s *runtimeSampler) sample() {
+	sample := sampleRuntime()
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	if sample.goroutines > s.max.goroutines {
+		s.max.goroutines = sample.goroutines
+	}
+	if sample.heapAlloc > s.max.heapAlloc {
+		s.max.heapAlloc = sample.heapAlloc
+	}
+}
+
+func (s *runtimeSampler) peak() runtimeSample {
+	s.sample()
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	return s.max
+}
@@ -0,0 +1,210 @@
+/*
+Copyright 2026 The Knative Authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+// ctrl-decode decodes captured control protocol frames.
+// The input can be the raw tcp payload exported from tcpdump or wireshark, a hex dump of it, or a wire tap recording.
+//
+// Usage:
+//
+//	ctrl-decode [-format auto|raw|hex|wiretap] [-decode <opcode>=<format>]... [file]
+//
+// When no file is provided, the input is read from stdin.
+package main
+
+import (
+	"encoding/hex"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+	"unicode/utf8"
+
+	ctrl "knative.dev/control-protocol/pkg"
+	"knative.dev/control-protocol/pkg/decode"
+	"knative.dev/control-protocol/pkg/message"
+)
+
+// payloadFormats are the payload formats which can be associated to an opcode with -decode
+var payloadFormats = map[string]decode.PayloadDecoder{
+	"async-command-result": message.ParseAsyncCommandResult,
+	"json": func(b []byte) (interface{}, error) {
+		var v interface{}
+		if err := json.Unmarshal(b, &v); err != nil {
+			return nil, err
+		}
+		return v, nil
+	},
+	"string": func(b []byte) (interface{}, error) {
+		if !utf8.Valid(b) {
+			return nil, fmt.Errorf("invalid utf-8 string")
+		}
+		return string(b), nil
+	},
+}
+
+// decodeFlags collects the -decode flags
+type decodeFlags map[ctrl.OpCode]string
+
+func (d decodeFlags) String() string {
+	var parts []string
+	for opcode, format := range d {
+		parts = append(parts, fmt.Sprintf("%d=%s", opcode, format))
+	}
+	sort.Strings(parts)
+	return strings.Join(parts, ",")
+}
+
+func (d decodeFlags) Set(s string) error {
+	opcodeStr, format, ok := strings.Cut(s, "=")
+	if !ok {
+		return fmt.Errorf("expecting <opcode>=<format>, got %q", s)
+	}
+	opcode, err := strconv.ParseUint(opcodeStr, 10, 8)
+	if err != nil {
+		return fmt.Errorf("invalid opcode %q: %w", opcodeStr, err)
+	}
+	if _, ok := payloadFormats[format]; !ok {
+		return fmt.Errorf("unknown payload format %q, expecting one of %s", format, strings.Join(payloadFormatNames(), ", "))
+	}
+	d[ctrl.OpCode(opcode)] = format
+	return nil
+}
+
+func payloadFormatNames() []string {
+	var names []string
+	for name := range payloadFormats {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+func main() {
+	format := flag.String("format", "auto", "Input format: auto, raw, hex or wiretap")
+	decoders := decodeFlags{}
+	flag.Var(decoders, "decode", fmt.Sprintf("Decode the payloads of an opcode, in the form <opcode>=<format>. Can be repeated. Formats: %s", strings.Join(payloadFormatNames(), ", ")))
+	flag.Parse()
+
+	if err := run(os.Stdout, *format, decoders, flag.Args()); err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		os.Exit(1)
+	}
+}
+
+func run(out io.Writer, formatName string, decoders decodeFlags, args []string) error {
+	format, err := decode.ParseFormat(formatName)
+	if err != nil {
+		return err
+	}
+
+	var data []byte
+	switch len(args) {
+	case 0:
+		data, err = ioutil.ReadAll(os.Stdin)
+	case 1:
+		data, err = ioutil.ReadFile(args[0])
+	default:
+		return fmt.Errorf("expecting at most one input file")
+	}
+	if err != nil {
+		return err
+	}
+
+	var opts []decode.DecoderOption
+	for opcode, name := range decoders {
+		opts = append(opts, decode.WithPayloadDecoder(opcode, payloadFormats[name]))
+	}
+	frames, decodeErr := decode.NewDecoder(opts...).Decode(data, format)
+	for i := range frames {
+		printFrame(out, &frames[i])
+	}
+	return decodeErr
+}
+
+func printFrame(w io.Writer, f *decode.Frame) {
+	fmt.Fprintf(w, "#%d offset=%d", f.Index, f.Offset)
+	if f.Tapped {
+		fmt.Fprintf(w, " %s %s", f.Time.Format(time.RFC3339Nano), f.Direction)
+		if f.Peer != "" {
+			fmt.Fprintf(w, " peer=%s", f.Peer)
+		}
+	}
+	fmt.Fprintf(w, " version=%d flags=0x%02x opcode=%s uuid=%s length=%d\n",
+		f.Message.Version(), f.Flags(), f.OpCodeName(), f.Message.UUID(), f.Message.Length())
+
+	if f.TraceContext != nil {
+		fmt.Fprintf(w, "  trace context: %s\n", hex.EncodeToString(f.TraceContext))
+	}
+
+	switch {
+	case f.IsAck():
+		for _, i := range f.Acks {
+			fmt.Fprintf(w, "  acks #%d\n", i)
+		}
+		if unmatched := len(f.AckedIDs) - len(f.Acks); unmatched > 0 {
+			fmt.Fprintf(w, "  acks %d message(s) not in the capture\n", unmatched)
+		}
+		if f.AckError != "" {
+			fmt.Fprintf(w, "  ack error: %q\n", f.AckError)
+		}
+	case f.Decoded != nil:
+		fmt.Fprintf(w, "  decoded: %s\n", describe(f.Decoded))
+	default:
+		printPayload(w, f.Payload)
+	}
+
+	if !f.IsAck() && !f.IsCredit() && f.AckedBy >= 0 {
+		fmt.Fprintf(w, "  acked by #%d\n", f.AckedBy)
+	}
+	for _, problem := range f.Problems {
+		fmt.Fprintf(w, "  problem: %s\n", problem)
+	}
+}
+
+func describe(v interface{}) string {
+	switch v := v.(type) {
+	case message.AsyncCommandResult:
+		if v.IsFailed() {
+			return fmt.Sprintf("async command result, command id 0x%s, error %q", hex.EncodeToString(v.CommandId), v.Error)
+		}
+		return fmt.Sprintf("async command result, command id 0x%s, succeeded", hex.EncodeToString(v.CommandId))
+	case string:
+		return strconv.Quote(v)
+	default:
+		if b, err := json.Marshal(v); err == nil {
+			return string(b)
+		}
+		return fmt.Sprintf("%+v", v)
+	}
+}
+
+func printPayload(w io.Writer, payload []byte) {
+	if len(payload) == 0 {
+		return
+	}
+	if decode.IsPrintable(payload) {
+		fmt.Fprintf(w, "  payload: %q\n", payload)
+		return
+	}
+	fmt.Fprintf(w, "  payload:\n")
+	decode.WritePayload(w, payload, "    ")
+}
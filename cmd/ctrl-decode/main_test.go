@@ -0,0 +1,62 @@
+/*
+Copyright 2026 The Knative Authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package main
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/require"
+
+	ctrl "knative.dev/control-protocol/pkg"
+	"knative.dev/control-protocol/pkg/message"
+)
+
+func TestDecodeFlags(t *testing.T) {
+	d := decodeFlags{}
+	require.NoError(t, d.Set("2=async-command-result"))
+	require.NoError(t, d.Set("3=json"))
+	require.Equal(t, "2=async-command-result,3=json", d.String())
+
+	require.Error(t, d.Set("2"))
+	require.Error(t, d.Set("256=json"))
+	require.Error(t, d.Set("2=nope"))
+}
+
+func TestRun(t *testing.T) {
+	payload, err := message.AsyncCommandResult{CommandId: message.Int64CommandId(1)}.MarshalBinary()
+	require.NoError(t, err)
+	msg := ctrl.NewMessage(uuid.New(), 2, payload)
+	ack := ctrl.NewMessage(msg.UUID(), uint8(ctrl.AckOpCode), []byte("yuck"))
+
+	var input bytes.Buffer
+	_, err = msg.WriteTo(&input)
+	require.NoError(t, err)
+	_, err = ack.WriteTo(&input)
+	require.NoError(t, err)
+	file := filepath.Join(t.TempDir(), "capture")
+	require.NoError(t, os.WriteFile(file, input.Bytes(), 0600))
+
+	var out bytes.Buffer
+	require.NoError(t, run(&out, "raw", decodeFlags{2: "async-command-result"}, []string{file}))
+	require.Contains(t, out.String(), "decoded: async command result, command id 0x0000000000000001, succeeded")
+	require.Contains(t, out.String(), "acked by #1")
+	require.Contains(t, out.String(), "ack error: \"yuck\"")
+}
@@ -0,0 +1,89 @@
+/*
+Copyright 2026 The Knative Authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+// ctrl-fake-server is a control server answering according to a script,
+// to develop controllers locally without running the data plane.
+// Look at test.FakeServerScript for the script format.
+//
+// Usage:
+//
+//	ctrl-fake-server -script script.yaml [-port 9000] [-tls [-certs-dir /etc/control-secret]]
+package main
+
+import (
+	"context"
+	"crypto/tls"
+	"flag"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"os/signal"
+	"syscall"
+
+	"go.uber.org/zap"
+	"knative.dev/pkg/logging"
+
+	"knative.dev/control-protocol/pkg/network"
+	"knative.dev/control-protocol/pkg/test"
+)
+
+func main() {
+	scriptFile := flag.String("script", "", "YAML or JSON script describing how to answer to the messages. If not set, every message is acked")
+	port := flag.Int("port", 9000, "Port to listen on")
+	useTLS := flag.Bool("tls", false, "Accept only mTLS connections")
+	certsDir := flag.String("certs-dir", network.DefaultCertsDir, "Directory containing the certificates of the control secret, used with -tls")
+	flag.Parse()
+
+	if err := run(*scriptFile, *port, *useTLS, *certsDir); err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		os.Exit(1)
+	}
+}
+
+func run(scriptFile string, port int, useTLS bool, certsDir string) error {
+	script := &test.FakeServerScript{}
+	if scriptFile != "" {
+		b, err := ioutil.ReadFile(scriptFile)
+		if err != nil {
+			return err
+		}
+		script, err = test.ParseFakeServerScript(b)
+		if err != nil {
+			return fmt.Errorf("invalid script %s: %w", scriptFile, err)
+		}
+	}
+
+	logger, err := zap.NewDevelopment()
+	if err != nil {
+		return err
+	}
+	ctx, cancelFn := signal.NotifyContext(logging.WithLogger(context.Background(), logger.Sugar()), os.Interrupt, syscall.SIGTERM)
+	defer cancelFn()
+
+	var tlsConfigLoader func() (*tls.Config, error)
+	if useTLS {
+		tlsConfigLoader = func() (*tls.Config, error) {
+			return network.LoadServerTLSConfigFromDir(certsDir)
+		}
+	}
+
+	server, err := test.StartFakeServer(ctx, script, tlsConfigLoader, test.WithControlServerOptions(network.WithPort(port)))
+	if err != nil {
+		return err
+	}
+	<-server.ClosedCh()
+	return nil
+}
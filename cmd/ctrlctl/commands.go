@@ -0,0 +1,207 @@
+/*
+Copyright 2026 The Knative Authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package main
+
+import (
+	"context"
+	"encoding"
+	"errors"
+	"flag"
+	"fmt"
+	"os"
+	"sort"
+	"time"
+
+	"github.com/google/uuid"
+
+	ctrl "knative.dev/control-protocol/pkg"
+	"knative.dev/control-protocol/pkg/service"
+)
+
+func runSend(args []string) error {
+	fs := flag.NewFlagSet("send", flag.ExitOnError)
+	conn := addConnectionFlags(fs)
+	opcode := fs.Uint("opcode", 0, "Opcode of the message to send")
+	payloadFile := fs.String("payload-file", "", "File containing the payload, or - to read it from stdin")
+	payloadHex := fs.String("payload-hex", "", "Payload encoded in hex")
+	payloadText := fs.String("payload", "", "Payload as text")
+	_ = fs.Parse(args)
+
+	if *opcode >= uint(ctrl.MinReservedOpCode) {
+		return fmt.Errorf("opcode %d is reserved", *opcode)
+	}
+	payload, err := readPayload(*payloadFile, *payloadHex, *payloadText, os.Stdin)
+	if err != nil {
+		return err
+	}
+
+	ctx, svc, cancelFn, err := conn.connect()
+	if err != nil {
+		return err
+	}
+	defer cancelFn()
+
+	id := uuid.New()
+	latency, err := sendAndMeasure(ctx, svc, id, ctrl.OpCode(*opcode), rawPayload(payload))
+	if err != nil {
+		fmt.Printf("uuid=%s latency=%v ack error: %v\n", id, latency, err)
+		return errors.New("message not acked successfully")
+	}
+	fmt.Printf("uuid=%s latency=%v acked\n", id, latency)
+	return nil
+}
+
+func runListen(args []string) error {
+	fs := flag.NewFlagSet("listen", flag.ExitOnError)
+	conn := addConnectionFlags(fs)
+	count := fs.Int("count", 0, "Exit after receiving this number of messages. 0 means listen until interrupted")
+	nack := fs.String("nack", "", "Ack the received messages with this error, rather than acking them successfully")
+	_ = fs.Parse(args)
+
+	ctx, svc, cancelFn, err := conn.connect()
+	if err != nil {
+		return err
+	}
+	defer cancelFn()
+
+	received := make(chan struct{}, 1)
+	svc.MessageHandler(ctrl.MessageHandlerFunc(func(ctx context.Context, message ctrl.ServiceMessage) {
+		printMessage(os.Stdout, time.Now(), message)
+		if *nack != "" {
+			message.AckWithError(errors.New(*nack))
+		} else {
+			message.Ack()
+		}
+		received <- struct{}{}
+	}))
+	svc.ErrorHandler(ctrl.ErrorHandlerFunc(func(ctx context.Context, err error) {
+		fmt.Fprintf(os.Stderr, "Connection error: %v\n", err)
+	}))
+
+	for i := 0; *count == 0 || i < *count; i++ {
+		select {
+		case <-received:
+		case <-ctx.Done():
+			return nil
+		}
+	}
+	return nil
+}
+
+func runPing(args []string) error {
+	fs := flag.NewFlagSet("ping", flag.ExitOnError)
+	conn := addConnectionFlags(fs)
+	opcode := fs.Uint("opcode", uint(ctrl.MinReservedOpCode)-1, "Opcode of the ping messages. An ack with error, e.g. because the opcode is unknown to the server, still counts as a reply")
+	count := fs.Int("count", 4, "Number of pings to send. 0 means ping until interrupted")
+	interval := fs.Duration("interval", time.Second, "Interval between the pings")
+	_ = fs.Parse(args)
+
+	if *opcode >= uint(ctrl.MinReservedOpCode) {
+		return fmt.Errorf("opcode %d is reserved", *opcode)
+	}
+
+	ctx, svc, cancelFn, err := conn.connect()
+	if err != nil {
+		return err
+	}
+	defer cancelFn()
+
+	var rtts []time.Duration
+	sent := 0
+	for *count == 0 || sent < *count {
+		if sent > 0 {
+			select {
+			case <-time.After(*interval):
+			case <-ctx.Done():
+			}
+		}
+		if ctx.Err() != nil {
+			break
+		}
+
+		id := uuid.New()
+		rtt, err := sendAndMeasure(ctx, svc, id, ctrl.OpCode(*opcode), nil)
+		sent++
+		var ackErr *ackError
+		switch {
+		case err == nil, errors.As(err, &ackErr):
+			rtts = append(rtts, rtt)
+			fmt.Printf("reply from %s: seq=%d time=%v\n", conn.host, sent, rtt)
+		default:
+			fmt.Printf("no reply from %s: seq=%d error=%v\n", conn.host, sent, err)
+		}
+	}
+
+	fmt.Println(pingStatistics(conn.host, sent, rtts))
+	if len(rtts) == 0 {
+		return errors.New("no replies")
+	}
+	return nil
+}
+
+// ackError is an error propagated by the other end with the ack
+type ackError struct {
+	err error
+}
+
+func (e *ackError) Error() string {
+	return e.err.Error()
+}
+
+func (e *ackError) Unwrap() error {
+	return e.err
+}
+
+// sendAndMeasure sends the message and returns the time elapsed until the ack.
+// Errors acked by the other end are wrapped in ackError.
+func sendAndMeasure(ctx context.Context, svc ctrl.Service, id uuid.UUID, opcode ctrl.OpCode, payload encoding.BinaryMarshaler) (time.Duration, error) {
+	sender, ok := svc.(ctrl.ContextSender)
+	if !ok {
+		return 0, fmt.Errorf("the control service doesn't support sending with context")
+	}
+
+	start := time.Now()
+	err := sender.SendAndWaitForAckWithContext(ctrl.ContextWithMessageUUID(ctx, id), opcode, payload)
+	elapsed := time.Since(start)
+	if err != nil && ctx.Err() == nil && isAckedError(err) {
+		return elapsed, &ackError{err: err}
+	}
+	return elapsed, err
+}
+
+// isAckedError returns true if the error was acked by the other end, rather than generated locally
+func isAckedError(err error) bool {
+	var timeoutErr interface{ Timeout() bool }
+	if errors.As(err, &timeoutErr) {
+		return false
+	}
+	return !errors.Is(err, context.Canceled) && !errors.Is(err, context.DeadlineExceeded) && !service.IsAckTimeout(err)
+}
+
+func pingStatistics(host string, sent int, rtts []time.Duration) string {
+	stats := fmt.Sprintf("--- %s ping statistics ---\n%d sent, %d replies", host, sent, len(rtts))
+	if len(rtts) == 0 {
+		return stats
+	}
+	sorted := append([]time.Duration(nil), rtts...)
+	sort.Slice(sorted, func(i, j int) bool { return sorted[i] < sorted[j] })
+	var total time.Duration
+	for _, rtt := range sorted {
+		total += rtt
+	}
+	return fmt.Sprintf("%s\nrtt min/avg/max = %v/%v/%v", stats, sorted[0], total/time.Duration(len(sorted)), sorted[len(sorted)-1])
+}
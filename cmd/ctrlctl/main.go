@@ -0,0 +1,139 @@
+/*
+Copyright 2026 The Knative Authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+// ctrlctl is a command line client to debug a running control server.
+//
+// Usage:
+//
+//	ctrlctl send -host 10.0.0.1:9000 -opcode 1 -payload-hex 0a0b0c
+//	ctrlctl listen -host 10.0.0.1:9000
+//	ctrlctl ping -host 10.0.0.1:9000 -count 5
+package main
+
+import (
+	"context"
+	"crypto/tls"
+	"flag"
+	"fmt"
+	"net"
+	"os"
+	"os/signal"
+	"syscall"
+	"time"
+
+	"go.uber.org/zap"
+	"knative.dev/pkg/logging"
+
+	ctrl "knative.dev/control-protocol/pkg"
+	"knative.dev/control-protocol/pkg/certificates"
+	"knative.dev/control-protocol/pkg/network"
+)
+
+type command struct {
+	description string
+	run         func(args []string) error
+}
+
+var commands = map[string]command{
+	"send":   {"Send a message and print the ack result", runSend},
+	"listen": {"Print the inbound messages", runListen},
+	"ping":   {"Measure the round trip time", runPing},
+}
+
+func main() {
+	if len(os.Args) < 2 {
+		usage()
+		os.Exit(2)
+	}
+	cmd, ok := commands[os.Args[1]]
+	if !ok {
+		usage()
+		os.Exit(2)
+	}
+	if err := cmd.run(os.Args[2:]); err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		os.Exit(1)
+	}
+}
+
+func usage() {
+	fmt.Fprintf(os.Stderr, "Usage: %s <command> [flags]\n\nCommands:\n", os.Args[0])
+	for _, name := range []string{"send", "listen", "ping"} {
+		fmt.Fprintf(os.Stderr, "  %-8s %s\n", name, commands[name].description)
+	}
+	fmt.Fprintf(os.Stderr, "\nRun '%s <command> -h' for the flags of the command.\n", os.Args[0])
+}
+
+// connectionFlags are the flags shared by all the commands
+type connectionFlags struct {
+	host          string
+	tls           bool
+	certsDir      string
+	tlsServerName string
+	dialTimeout   time.Duration
+	verbose       bool
+}
+
+func addConnectionFlags(fs *flag.FlagSet) *connectionFlags {
+	f := &connectionFlags{}
+	fs.StringVar(&f.host, "host", "", "Control server address, in the form host[:port]. The port defaults to 9000")
+	fs.BoolVar(&f.tls, "tls", false, "Connect using mTLS")
+	fs.StringVar(&f.certsDir, "certs-dir", network.DefaultCertsDir, "Directory containing the certificates of the control secret, used with -tls")
+	fs.StringVar(&f.tlsServerName, "tls-server-name", certificates.DataPlaneUserName("myns"), "Server name expected in the server certificate, used with -tls")
+	fs.DurationVar(&f.dialTimeout, "dial-timeout", 5*time.Second, "Timeout of the initial dial")
+	fs.BoolVar(&f.verbose, "v", false, "Enable the debug logs of the control protocol")
+	return f
+}
+
+// connect starts the control client. The returned context is closed on SIGINT and SIGTERM.
+func (f *connectionFlags) connect() (context.Context, ctrl.Service, context.CancelFunc, error) {
+	if f.host == "" {
+		return nil, nil, nil, fmt.Errorf("-host is required")
+	}
+
+	logger := zap.NewNop()
+	if f.verbose {
+		var err error
+		logger, err = zap.NewDevelopment()
+		if err != nil {
+			return nil, nil, nil, err
+		}
+	}
+	ctx, cancelFn := signal.NotifyContext(logging.WithLogger(context.Background(), logger.Sugar()), os.Interrupt, syscall.SIGTERM)
+
+	var dialer network.Dialer = &net.Dialer{
+		KeepAlive: network.KeepAlive,
+		Timeout:   f.dialTimeout,
+	}
+	if f.tls {
+		tlsConfig, err := network.LoadClientTLSConfigFromDir(f.certsDir, f.tlsServerName)
+		if err != nil {
+			cancelFn()
+			return nil, nil, nil, fmt.Errorf("cannot load the tls configuration: %w", err)
+		}
+		dialer = &tls.Dialer{
+			NetDialer: dialer.(*net.Dialer),
+			Config:    tlsConfig,
+		}
+	}
+
+	svc, err := network.StartControlClient(ctx, dialer, f.host)
+	if err != nil {
+		cancelFn()
+		return nil, nil, nil, err
+	}
+	return ctx, svc, cancelFn, nil
+}
@@ -0,0 +1,79 @@
+/*
+Copyright 2026 The Knative Authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package main
+
+import (
+	"encoding/hex"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"strings"
+	"time"
+
+	ctrl "knative.dev/control-protocol/pkg"
+	"knative.dev/control-protocol/pkg/decode"
+)
+
+type rawPayload []byte
+
+func (r rawPayload) MarshalBinary() ([]byte, error) {
+	return r, nil
+}
+
+// readPayload reads the payload from exactly one of the sources, or returns an empty payload if none is set.
+// file can be "-" to read from stdin.
+func readPayload(file string, hexPayload string, text string, stdin io.Reader) ([]byte, error) {
+	set := 0
+	for _, s := range []string{file, hexPayload, text} {
+		if s != "" {
+			set++
+		}
+	}
+	if set > 1 {
+		return nil, fmt.Errorf("only one of -payload-file, -payload-hex and -payload can be used")
+	}
+
+	switch {
+	case file == "-":
+		return ioutil.ReadAll(stdin)
+	case file != "":
+		return ioutil.ReadFile(file)
+	case hexPayload != "":
+		b, err := hex.DecodeString(strings.Join(strings.Fields(hexPayload), ""))
+		if err != nil {
+			return nil, fmt.Errorf("cannot decode the hex payload: %w", err)
+		}
+		return b, nil
+	case text != "":
+		return []byte(text), nil
+	default:
+		return nil, nil
+	}
+}
+
+// printMessage pretty prints the message header and payload
+func printMessage(w io.Writer, at time.Time, message ctrl.ServiceMessage) {
+	headers := message.Headers()
+	fmt.Fprintf(w, "%s opcode=%d uuid=%s version=%d length=%d\n",
+		at.Format(time.RFC3339Nano),
+		headers.OpCode(),
+		headers.UUID().String(),
+		headers.Version(),
+		headers.Length(),
+	)
+	decode.WritePayload(w, message.Payload(), "  ")
+}
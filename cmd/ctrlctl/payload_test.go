@@ -0,0 +1,89 @@
+/*
+Copyright 2026 The Knative Authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestReadPayload(t *testing.T) {
+	file := filepath.Join(t.TempDir(), "payload")
+	require.NoError(t, os.WriteFile(file, []byte("from file"), 0600))
+
+	tests := []struct {
+		name      string
+		file      string
+		hex       string
+		text      string
+		stdin     string
+		want      []byte
+		wantError bool
+	}{{
+		name: "empty",
+	}, {
+		name: "file",
+		file: file,
+		want: []byte("from file"),
+	}, {
+		name:  "stdin",
+		file:  "-",
+		stdin: "from stdin",
+		want:  []byte("from stdin"),
+	}, {
+		name: "hex with spaces",
+		hex:  "0a0b 0c",
+		want: []byte{0x0a, 0x0b, 0x0c},
+	}, {
+		name:      "invalid hex",
+		hex:       "zz",
+		wantError: true,
+	}, {
+		name: "text",
+		text: "Funky!",
+		want: []byte("Funky!"),
+	}, {
+		name:      "multiple sources",
+		text:      "Funky!",
+		hex:       "0a",
+		wantError: true,
+	}}
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			got, err := readPayload(tc.file, tc.hex, tc.text, strings.NewReader(tc.stdin))
+			if tc.wantError {
+				require.Error(t, err)
+				return
+			}
+			require.NoError(t, err)
+			require.Equal(t, tc.want, got)
+		})
+	}
+}
+
+func TestPingStatistics(t *testing.T) {
+	stats := pingStatistics("localhost", 3, []time.Duration{3 * time.Millisecond, time.Millisecond})
+	require.Contains(t, stats, "3 sent, 2 replies")
+	require.Contains(t, stats, "rtt min/avg/max = 1ms/2ms/3ms")
+
+	require.NotContains(t, pingStatistics("localhost", 1, nil), "rtt")
+}
@@ -189,7 +189,12 @@ func ParseCert(certPemBytes []byte, privateKeyPemBytes []byte) (*x509.Certificat
 
 // ValidateCert checks the expiration of the certificate
 func ValidateCert(cert *x509.Certificate, rotationThreshold time.Duration) error {
-	if !cert.NotAfter.After(time.Now().Add(rotationThreshold)) {
+	return ValidateCertAt(cert, rotationThreshold, time.Now())
+}
+
+// ValidateCertAt checks the expiration of the certificate at the provided time
+func ValidateCertAt(cert *x509.Certificate, rotationThreshold time.Duration, now time.Time) error {
+	if !cert.NotAfter.After(now.Add(rotationThreshold)) {
 		return fmt.Errorf("certificate is going to expire %v", cert.NotAfter)
 	}
 	return nil
@@ -32,6 +32,7 @@ import (
 	"k8s.io/apimachinery/pkg/util/sets"
 	"k8s.io/client-go/kubernetes"
 	listerv1 "k8s.io/client-go/listers/core/v1"
+	"k8s.io/utils/clock"
 	pkgreconciler "knative.dev/pkg/reconciler"
 	"knative.dev/pkg/system"
 
@@ -63,6 +64,7 @@ type reconciler struct {
 	caSecretName        string
 	secretTypeLabelName string
 	enqueueAfter        func(key types.NamespacedName, delay time.Duration)
+	clock               clock.PassiveClock
 
 	logger *zap.SugaredLogger
 }
@@ -90,7 +92,7 @@ func (r *reconciler) ReconcileKind(ctx context.Context, secret *corev1.Secret) p
 		r.logger.Errorf("Error accessing CA certificate secret %q %q: %v", system.Namespace(), r.caSecretName, err)
 		return err
 	}
-	caCert, caPk, err := parseAndValidateSecret(caSecret, nil)
+	caCert, caPk, err := parseAndValidateSecret(caSecret, r.clock.Now(), nil)
 	if err != nil {
 		r.logger.Infof("CA cert invalid: %v", err)
 
@@ -119,7 +121,7 @@ func (r *reconciler) ReconcileKind(ctx context.Context, secret *corev1.Secret) p
 		return fmt.Errorf("unknown cert type: %v", r.secretTypeLabelName)
 	}
 
-	cert, _, err := parseAndValidateSecret(secret, caSecret.Data[certificates.SecretCertKey], sans...)
+	cert, _, err := parseAndValidateSecret(secret, r.clock.Now(), caSecret.Data[certificates.SecretCertKey], sans...)
 	if err != nil {
 		r.logger.Infof("Secret invalid: %v", err)
 		// Check the secret to reconcile type
@@ -145,7 +147,7 @@ func (r *reconciler) ReconcileKind(ctx context.Context, secret *corev1.Secret) p
 }
 
 // All sans provided are required to be lower case
-func parseAndValidateSecret(secret *corev1.Secret, caCert []byte, sans ...string) (*x509.Certificate, *rsa.PrivateKey, error) {
+func parseAndValidateSecret(secret *corev1.Secret, now time.Time, caCert []byte, sans ...string) (*x509.Certificate, *rsa.PrivateKey, error) {
 	certBytes, ok := secret.Data[certificates.SecretCertKey]
 	if !ok {
 		return nil, nil, fmt.Errorf("missing cert bytes")
@@ -168,7 +170,7 @@ func parseAndValidateSecret(secret *corev1.Secret, caCert []byte, sans ...string
 	if err != nil {
 		return nil, nil, err
 	}
-	if err := certificates.ValidateCert(cert, rotationThreshold); err != nil {
+	if err := certificates.ValidateCertAt(cert, rotationThreshold, now); err != nil {
 		return nil, nil, err
 	}
 
@@ -186,7 +188,7 @@ func (r *reconciler) enqueueBeforeExpiration(secret *corev1.Secret, cert *x509.C
 	r.enqueueAfter(types.NamespacedName{
 		Namespace: secret.Namespace,
 		Name:      secret.Name,
-	}, time.Until(when))
+	}, when.Sub(r.clock.Now()))
 }
 
 func (r *reconciler) commitUpdatedSecret(ctx context.Context, secret *corev1.Secret, keyPair *certificates.KeyPair, caCert []byte) error {
@@ -27,6 +27,7 @@ import (
 	corev1 "k8s.io/api/core/v1"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/apimachinery/pkg/types"
+	clocktesting "k8s.io/utils/clock/testing"
 	fakekubeclient "knative.dev/pkg/client/injection/kube/client/fake"
 	fakesecretinformer "knative.dev/pkg/client/injection/kube/informers/core/v1/secret/filtered/fake"
 
@@ -147,6 +148,7 @@ func TestReconcile(t *testing.T) {
 		name                   string
 		key                    string
 		executeReconcilerTwice bool
+		clockStep              time.Duration
 		objects                []*corev1.Secret
 		asserts                map[string]func(*testing.T, *corev1.Secret)
 	}{{
@@ -161,6 +163,20 @@ func TestReconcile(t *testing.T) {
 				require.Equal(t, wellFormedControlPlaneSecret, secret)
 			},
 		},
+	}, {
+		name:      "well formed secret CA and control plane secret about to expire",
+		key:       namespace + "/control-plane-ctrl",
+		clockStep: expirationInterval - rotationThreshold,
+		objects:   []*corev1.Secret{wellFormedCaSecret, wellFormedControlPlaneSecret},
+		asserts: map[string]func(*testing.T, *corev1.Secret){
+			wellFormedCaSecret.Name: func(t *testing.T, secret *corev1.Secret) {
+				require.Equal(t, wellFormedCaSecret, secret)
+			},
+			wellFormedControlPlaneSecret.Name: func(t *testing.T, secret *corev1.Secret) {
+				validControlPlaneCert(t, secret)
+				require.NotEqual(t, wellFormedControlPlaneSecret.Data[certificates.SecretCertKey], secret.Data[certificates.SecretCertKey])
+			},
+		},
 	}, {
 		name:    "well formed secret CA and data plane user secret exists",
 		key:     namespace + "/data-plane-ctrl",
@@ -330,7 +346,8 @@ func TestReconcile(t *testing.T) {
 	}}
 	for _, test := range tests {
 		t.Run(test.name, func(t *testing.T) {
-			ctx, ctrl := setupTest(t, NewControllerFactory("my"))
+			fakeClock := clocktesting.NewFakePassiveClock(time.Now().Add(test.clockStep))
+			ctx, ctrl := setupTest(t, NewControllerFactory("my", WithClock(fakeClock)))
 
 			for _, s := range test.objects {
 				_, err := fakekubeclient.Get(ctx).CoreV1().Secrets(s.Namespace).Create(ctx, s, metav1.CreateOptions{})
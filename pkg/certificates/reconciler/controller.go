@@ -21,6 +21,7 @@ import (
 
 	v1 "k8s.io/client-go/informers/core/v1"
 	"k8s.io/client-go/tools/cache"
+	"k8s.io/utils/clock"
 	kubeclient "knative.dev/pkg/client/injection/kube/client"
 	"knative.dev/pkg/injection"
 	"knative.dev/pkg/system"
@@ -41,8 +42,19 @@ const (
 	secretRoutingId        = "routing-id"
 )
 
+// ControllerOption configures the control certificates reconciler
+type ControllerOption func(*reconciler)
+
+// WithClock sets the clock used to check the certificates expiration and to enqueue their rotation.
+// Tests can provide a fake clock, to advance the time deterministically.
+func WithClock(clock clock.PassiveClock) ControllerOption {
+	return func(r *reconciler) {
+		r.clock = clock
+	}
+}
+
 // NewControllerFactory generates a ControllerConstructor for the control certificates reconciler.
-func NewControllerFactory(componentName string) injection.ControllerConstructor {
+func NewControllerFactory(componentName string, opts ...ControllerOption) injection.ControllerConstructor {
 	return func(
 		ctx context.Context,
 		cmw configmap.Watcher,
@@ -61,8 +73,12 @@ func NewControllerFactory(componentName string) injection.ControllerConstructor
 			caSecretName:        caSecretName,
 			secretTypeLabelName: labelName,
 
+			clock:  clock.RealClock{},
 			logger: logging.FromContext(ctx),
 		}
+		for _, fn := range opts {
+			fn(r)
+		}
 
 		impl := NewFilteredImpl(ctx, r, secretInformer)
 		r.enqueueAfter = impl.EnqueueKeyAfter
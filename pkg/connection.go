@@ -31,3 +31,6 @@ type Connection interface {
 	// (like cannot re-establish the connection after several attempts)
 	Errors() <-chan error
 }
+
+// ConnectionWrapper wraps a connection in another connection to offer additional functionality
+type ConnectionWrapper func(Connection) Connection
@@ -0,0 +1,52 @@
+/*
+Copyright 2026 The Knative Authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package control
+
+import (
+	"context"
+
+	"github.com/google/uuid"
+)
+
+type traceContextKey struct{}
+
+// ContextWithTraceContext returns a context carrying the serialized trace context.
+// When sending, the trace context is attached to the outbound message.
+// When receiving, the context passed to the MessageHandler carries the trace context of the inbound message, if any.
+func ContextWithTraceContext(ctx context.Context, traceContext []byte) context.Context {
+	return context.WithValue(ctx, traceContextKey{}, traceContext)
+}
+
+// TraceContextFromContext returns the serialized trace context carried by the context, or nil.
+func TraceContextFromContext(ctx context.Context) []byte {
+	traceContext, _ := ctx.Value(traceContextKey{}).([]byte)
+	return traceContext
+}
+
+type messageUUIDKey struct{}
+
+// ContextWithMessageUUID returns a context carrying the uuid to use when sending the message,
+// similarly to UUIDSender.SendWithUUIDAndWaitForAck.
+func ContextWithMessageUUID(ctx context.Context, id uuid.UUID) context.Context {
+	return context.WithValue(ctx, messageUUIDKey{}, id)
+}
+
+// MessageUUIDFromContext returns the message uuid carried by the context, if any.
+func MessageUUIDFromContext(ctx context.Context) (uuid.UUID, bool) {
+	id, ok := ctx.Value(messageUUIDKey{}).(uuid.UUID)
+	return id, ok
+}
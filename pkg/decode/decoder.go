@@ -0,0 +1,330 @@
+/*
+Copyright 2026 The Knative Authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package decode
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+
+	"github.com/google/uuid"
+
+	ctrl "knative.dev/control-protocol/pkg"
+	"knative.dev/control-protocol/pkg/wiretap"
+)
+
+// Format is the format of the decoder input
+type Format int
+
+const (
+	// FormatAuto detects the format from the input
+	FormatAuto Format = iota
+	// FormatRaw is the raw byte stream, as sent on the wire, e.g. a tcp payload exported from tcpdump
+	FormatRaw
+	// FormatHex is a hex dump of the raw byte stream, see ParseHex for the supported dump formats
+	FormatHex
+	// FormatWireTap is a recording of wiretap.Tap
+	FormatWireTap
+)
+
+// ParseFormat parses the format name
+func ParseFormat(s string) (Format, error) {
+	switch s {
+	case "auto", "":
+		return FormatAuto, nil
+	case "raw":
+		return FormatRaw, nil
+	case "hex":
+		return FormatHex, nil
+	case "wiretap":
+		return FormatWireTap, nil
+	default:
+		return FormatAuto, fmt.Errorf("unknown format %q, expecting one of auto, raw, hex, wiretap", s)
+	}
+}
+
+func (f Format) String() string {
+	switch f {
+	case FormatRaw:
+		return "raw"
+	case FormatHex:
+		return "hex"
+	case FormatWireTap:
+		return "wiretap"
+	default:
+		return "auto"
+	}
+}
+
+// DetectFormat guesses the format of the input
+func DetectFormat(data []byte) Format {
+	if wiretap.IsRecording(data) {
+		return FormatWireTap
+	}
+	// The first byte of a raw stream is the protocol version, which is not printable
+	if len(data) > 0 && looksLikeText(data) {
+		if _, err := ParseHex(data); err == nil {
+			return FormatHex
+		}
+	}
+	return FormatRaw
+}
+
+// PayloadDecoder decodes the payload of a message.
+// The signature matches reconciler.PayloadParser, so the same parsers can be used, e.g. message.ParseAsyncCommandResult.
+type PayloadDecoder func([]byte) (interface{}, error)
+
+type DecoderOption func(*Decoder)
+
+// WithPayloadDecoder decodes the payloads of the messages with the provided opcode using fn
+func WithPayloadDecoder(opcode ctrl.OpCode, fn PayloadDecoder) DecoderOption {
+	return func(d *Decoder) {
+		d.payloadDecoders[opcode] = fn
+	}
+}
+
+// Decoder decodes captured control protocol frames
+type Decoder struct {
+	payloadDecoders map[ctrl.OpCode]PayloadDecoder
+}
+
+func NewDecoder(opts ...DecoderOption) *Decoder {
+	d := &Decoder{
+		payloadDecoders: make(map[ctrl.OpCode]PayloadDecoder),
+	}
+	for _, fn := range opts {
+		fn(d)
+	}
+	return d
+}
+
+// Decode decodes all the frames of the input, pairing the acks with the messages they answer.
+// If a frame is truncated or cannot be parsed, Decode returns the frames decoded so far and a *FrameError.
+func (d *Decoder) Decode(data []byte, format Format) ([]Frame, error) {
+	if format == FormatAuto {
+		format = DetectFormat(data)
+	}
+
+	var frames []Frame
+	var err error
+	switch format {
+	case FormatWireTap:
+		frames, err = d.decodeWireTap(data)
+	case FormatHex:
+		var raw []byte
+		raw, err = ParseHex(data)
+		if err != nil {
+			return nil, fmt.Errorf("cannot parse the hex dump: %w", err)
+		}
+		frames, err = d.decodeRaw(raw)
+	default:
+		frames, err = d.decodeRaw(data)
+	}
+
+	pairAcks(frames)
+	return frames, err
+}
+
+func (d *Decoder) decodeRaw(data []byte) ([]Frame, error) {
+	var frames []Frame
+	offset := int64(0)
+	for offset < int64(len(data)) {
+		index := len(frames)
+		remaining := data[offset:]
+		if len(remaining) < headerLength {
+			return frames, &FrameError{
+				Index:  index,
+				Offset: offset,
+				Reason: fmt.Sprintf("truncated header: got %d of %d bytes", len(remaining), headerLength),
+			}
+		}
+		if payloadLength := binary.BigEndian.Uint32(remaining[20:24]); uint64(len(remaining)-headerLength) < uint64(payloadLength) {
+			return frames, &FrameError{
+				Index:  index,
+				Offset: offset,
+				Reason: fmt.Sprintf("truncated payload: got %d of %d bytes", len(remaining)-headerLength, payloadLength),
+			}
+		}
+
+		var msg ctrl.Message
+		n, err := msg.ReadFrom(bytes.NewReader(remaining))
+		if err != nil {
+			return frames, &FrameError{
+				Index:  index,
+				Offset: offset,
+				Reason: fmt.Sprintf("cannot read the message: %v", err),
+			}
+		}
+
+		frame := Frame{Index: index, Offset: offset}
+		d.decodeMessage(&frame, msg)
+		frames = append(frames, frame)
+		offset += n
+	}
+	return frames, nil
+}
+
+func (d *Decoder) decodeWireTap(data []byte) ([]Frame, error) {
+	var frames []Frame
+	reader := wiretap.NewReader(bytes.NewReader(data))
+	for {
+		record, err := reader.Next()
+		if errors.Is(err, io.EOF) {
+			return frames, nil
+		}
+		if err != nil {
+			return frames, &FrameError{
+				Index:  len(frames),
+				Offset: int64(len(frames)),
+				Reason: err.Error(),
+			}
+		}
+
+		frame := Frame{
+			Index:     len(frames),
+			Offset:    int64(len(frames)),
+			Tapped:    true,
+			Time:      record.Time,
+			Direction: record.Direction,
+			Peer:      record.Peer,
+		}
+		d.decodeMessage(&frame, record.Message)
+		frames = append(frames, frame)
+	}
+}
+
+func (d *Decoder) decodeMessage(frame *Frame, msg ctrl.Message) {
+	frame.Message = msg
+	frame.Payload = msg.Payload()
+	frame.AckedBy = -1
+
+	if msg.Version() > ctrl.ActualProtocolVersion {
+		frame.Problems = append(frame.Problems, fmt.Sprintf("unsupported protocol version %d", msg.Version()))
+	}
+	if unknown := frame.Flags() &^ uint8(ctrl.BatchedAckFlag|ctrl.TraceContextFlag); unknown != 0 {
+		frame.Problems = append(frame.Problems, fmt.Sprintf("unknown flags 0x%02x", unknown))
+	}
+
+	if msg.Check(ctrl.TraceContextFlag) {
+		stripped := msg
+		traceContext, err := stripped.StripTraceContext()
+		if err != nil {
+			frame.Problems = append(frame.Problems, fmt.Sprintf("malformed trace context: %v", err))
+		} else {
+			frame.TraceContext = traceContext
+			frame.Payload = stripped.Payload()
+		}
+	}
+
+	if frame.IsAck() {
+		d.decodeAck(frame)
+		return
+	}
+	if msg.Check(ctrl.BatchedAckFlag) {
+		frame.Problems = append(frame.Problems, "batched ack flag set on a message which is not an ack")
+	}
+
+	if fn, ok := d.payloadDecoders[ctrl.OpCode(msg.OpCode())]; ok {
+		decoded, err := decodePayload(fn, frame.Payload)
+		if err != nil {
+			frame.Problems = append(frame.Problems, fmt.Sprintf("cannot decode the payload: %v", err))
+		} else {
+			frame.Decoded = decoded
+		}
+	}
+}
+
+func (d *Decoder) decodeAck(frame *Frame) {
+	if !frame.Message.Check(ctrl.BatchedAckFlag) {
+		frame.AckedIDs = []uuid.UUID{frame.Message.UUID()}
+		frame.AckError = string(frame.Payload)
+		return
+	}
+	if len(frame.Payload)%16 != 0 {
+		frame.Problems = append(frame.Problems, fmt.Sprintf("malformed batched ack: payload length %d is not a multiple of 16", len(frame.Payload)))
+		return
+	}
+	for i := 0; i < len(frame.Payload); i += 16 {
+		var id uuid.UUID
+		copy(id[:], frame.Payload[i:i+16])
+		frame.AckedIDs = append(frame.AckedIDs, id)
+	}
+}
+
+// decodePayload invokes the decoder, recovering from panics caused by malformed payloads
+func decodePayload(fn PayloadDecoder, payload []byte) (decoded interface{}, err error) {
+	defer func() {
+		if r := recover(); r != nil {
+			err = fmt.Errorf("decoder panicked: %v", r)
+		}
+	}()
+	return fn(payload)
+}
+
+// Flags returns the flags of the frame
+func (f *Frame) Flags() uint8 {
+	var flags uint8
+	for i := 0; i < 8; i++ {
+		if f.Message.Check(ctrl.MessageFlag(1 << i)) {
+			flags |= 1 << i
+		}
+	}
+	return flags
+}
+
+type pendingKey struct {
+	direction wiretap.Direction
+	id        uuid.UUID
+}
+
+// pairAcks links every ack with the message it answers.
+// When the direction is known, an ack answers a message sent in the opposite direction.
+func pairAcks(frames []Frame) {
+	pending := make(map[pendingKey]int)
+	for i := range frames {
+		frame := &frames[i]
+		if frame.IsCredit() {
+			continue
+		}
+		if !frame.IsAck() {
+			pending[pendingKey{direction: frame.Direction, id: frame.Message.UUID()}] = i
+			continue
+		}
+
+		direction := frame.Direction
+		if frame.Tapped {
+			direction = opposite(direction)
+		}
+		for _, id := range frame.AckedIDs {
+			key := pendingKey{direction: direction, id: id}
+			if j, ok := pending[key]; ok {
+				frames[j].AckedBy = i
+				frame.Acks = append(frame.Acks, j)
+				delete(pending, key)
+			}
+		}
+	}
+}
+
+func opposite(d wiretap.Direction) wiretap.Direction {
+	if d == wiretap.Inbound {
+		return wiretap.Outbound
+	}
+	return wiretap.Inbound
+}
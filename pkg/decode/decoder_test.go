@@ -0,0 +1,235 @@
+/*
+Copyright 2026 The Knative Authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package decode_test
+
+import (
+	"bytes"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/require"
+
+	ctrl "knative.dev/control-protocol/pkg"
+	"knative.dev/control-protocol/pkg/decode"
+	"knative.dev/control-protocol/pkg/message"
+	"knative.dev/control-protocol/pkg/wiretap"
+)
+
+func TestDecoder_RawStream(t *testing.T) {
+	result := message.AsyncCommandResult{CommandId: message.Int64CommandId(1), Error: "funky error"}
+	resultPayload, err := result.MarshalBinary()
+	require.NoError(t, err)
+
+	msg1 := ctrl.NewMessage(uuid.New(), 1, []byte("Funky!"), ctrl.WithTraceContext([]byte("trace")))
+	msg2 := ctrl.NewMessage(uuid.New(), 2, resultPayload)
+	ack1 := ctrl.NewMessage(msg1.UUID(), uint8(ctrl.AckOpCode), []byte("yuck"))
+	id2 := msg2.UUID()
+	ack2 := ctrl.NewMessage(uuid.Nil, uint8(ctrl.AckOpCode), id2[:], ctrl.WithFlags(uint8(ctrl.BatchedAckFlag)))
+
+	frames, err := decode.NewDecoder(
+		decode.WithPayloadDecoder(2, message.ParseAsyncCommandResult),
+	).Decode(writeMessages(t, msg1, msg2, ack1, ack2), decode.FormatAuto)
+	require.NoError(t, err)
+	require.Len(t, frames, 4)
+
+	require.Equal(t, int64(0), frames[0].Offset)
+	require.Equal(t, []byte("trace"), frames[0].TraceContext)
+	require.Equal(t, []byte("Funky!"), frames[0].Payload)
+	require.Equal(t, 2, frames[0].AckedBy)
+	require.Empty(t, frames[0].Problems)
+
+	require.Equal(t, int64(24+int(msg1.Length())), frames[1].Offset)
+	require.Equal(t, result, frames[1].Decoded)
+	require.Equal(t, 3, frames[1].AckedBy)
+
+	require.True(t, frames[2].IsAck())
+	require.Equal(t, []int{0}, frames[2].Acks)
+	require.Equal(t, "yuck", frames[2].AckError)
+
+	require.Equal(t, []int{1}, frames[3].Acks)
+	require.Empty(t, frames[3].AckError)
+}
+
+func TestFrame_OpCodeName(t *testing.T) {
+	registration, err := message.Registration{Key: "ns-a/broker"}.MarshalBinary()
+	require.NoError(t, err)
+
+	frames, err := decode.NewDecoder().Decode(writeMessages(t,
+		ctrl.NewMessage(uuid.New(), 1, []byte("Funky!")),
+		ctrl.NewMessage(uuid.New(), uint8(ctrl.RegistrationOpCode), registration),
+		ctrl.NewMessage(uuid.Nil, uint8(ctrl.CreditOpCode), nil),
+		ctrl.NewMessage(uuid.New(), uint8(ctrl.AckOpCode), nil),
+	), decode.FormatAuto)
+	require.NoError(t, err)
+
+	names := make([]string, 0, len(frames))
+	for _, f := range frames {
+		names = append(names, f.OpCodeName())
+	}
+	require.Equal(t, []string{"1", "registration", "credit", "ack"}, names)
+}
+
+func TestDecoder_Truncated(t *testing.T) {
+	msg := ctrl.NewMessage(uuid.New(), 1, []byte("Funky!"))
+	data := writeMessages(t, msg, msg)
+
+	tests := map[string]struct {
+		length int
+		reason string
+	}{
+		"header": {
+			length: 30 + 10,
+			reason: "frame #1 at offset 30: truncated header: got 10 of 24 bytes",
+		},
+		"payload": {
+			length: 30 + 24 + 2,
+			reason: "frame #1 at offset 30: truncated payload: got 2 of 6 bytes",
+		},
+	}
+	for name, tc := range tests {
+		t.Run(name, func(t *testing.T) {
+			frames, err := decode.NewDecoder().Decode(data[:tc.length], decode.FormatRaw)
+			require.Len(t, frames, 1)
+			var frameErr *decode.FrameError
+			require.True(t, errors.As(err, &frameErr))
+			require.EqualError(t, err, tc.reason)
+		})
+	}
+}
+
+func TestDecoder_MalformedFrames(t *testing.T) {
+	frames, err := decode.NewDecoder(
+		decode.WithPayloadDecoder(1, message.ParseAsyncCommandResult),
+	).Decode(writeMessages(t,
+		ctrl.NewMessage(uuid.New(), 1, []byte{0, 0}),
+		ctrl.NewMessage(uuid.New(), uint8(ctrl.AckOpCode), []byte{1, 2, 3}, ctrl.WithFlags(uint8(ctrl.BatchedAckFlag))),
+		ctrl.NewMessage(uuid.New(), 2, []byte{0, 10, 1}, ctrl.WithFlags(uint8(ctrl.TraceContextFlag)|0x80), ctrl.WithVersion(3)),
+	), decode.FormatRaw)
+	require.NoError(t, err)
+	require.Len(t, frames, 3)
+
+	require.Len(t, frames[0].Problems, 1)
+	require.Contains(t, frames[0].Problems[0], "cannot decode the payload")
+	require.Len(t, frames[1].Problems, 1)
+	require.Contains(t, frames[1].Problems[0], "malformed batched ack")
+	require.Len(t, frames[2].Problems, 3)
+	require.Contains(t, frames[2].Problems[0], "unsupported protocol version 3")
+	require.Contains(t, frames[2].Problems[1], "unknown flags 0x80")
+	require.Contains(t, frames[2].Problems[2], "malformed trace context")
+}
+
+func TestDecoder_HexDumps(t *testing.T) {
+	msg := ctrl.NewMessage(uuid.New(), 1, []byte("Funky!"))
+	data := writeMessages(t, msg)
+
+	dumps := map[string]string{
+		"plain":   hex.EncodeToString(data),
+		"colons":  colonHex(data),
+		"xxd":     xxd(data),
+		"hexdump": hex.Dump(data),
+	}
+	for name, dump := range dumps {
+		t.Run(name, func(t *testing.T) {
+			require.Equal(t, decode.FormatHex, decode.DetectFormat([]byte(dump)))
+			frames, err := decode.NewDecoder().Decode([]byte(dump), decode.FormatAuto)
+			require.NoError(t, err)
+			require.Len(t, frames, 1)
+			require.Equal(t, msg.UUID(), frames[0].Message.UUID())
+			require.Equal(t, []byte("Funky!"), frames[0].Payload)
+		})
+	}
+}
+
+func TestDecoder_WireTap(t *testing.T) {
+	msg := ctrl.NewMessage(uuid.New(), 1, []byte("Funky!"))
+	now := time.Now()
+
+	var buf bytes.Buffer
+	w := wiretap.NewWriter(&buf)
+	for _, r := range []wiretap.Record{
+		{Time: now, Direction: wiretap.Inbound, Message: msg},
+		// Same uuid, but in the same direction: this is not the answer
+		{Time: now, Direction: wiretap.Inbound, Message: ctrl.NewMessage(msg.UUID(), uint8(ctrl.AckOpCode), nil)},
+		{Time: now, Direction: wiretap.Outbound, Message: ctrl.NewMessage(msg.UUID(), uint8(ctrl.AckOpCode), nil)},
+	} {
+		_, err := w.Write(r)
+		require.NoError(t, err)
+	}
+
+	require.Equal(t, decode.FormatWireTap, decode.DetectFormat(buf.Bytes()))
+	frames, err := decode.NewDecoder().Decode(buf.Bytes(), decode.FormatAuto)
+	require.NoError(t, err)
+	require.Len(t, frames, 3)
+	require.True(t, frames[0].Tapped)
+	require.Equal(t, 2, frames[0].AckedBy)
+	require.Empty(t, frames[1].Acks)
+	require.Equal(t, []int{0}, frames[2].Acks)
+}
+
+func writeMessages(t *testing.T, msgs ...ctrl.Message) []byte {
+	var buf bytes.Buffer
+	for _, msg := range msgs {
+		msg := msg
+		_, err := msg.WriteTo(&buf)
+		require.NoError(t, err)
+	}
+	return buf.Bytes()
+}
+
+func colonHex(data []byte) string {
+	parts := make([]string, len(data))
+	for i, b := range data {
+		parts[i] = fmt.Sprintf("%02x", b)
+	}
+	return strings.Join(parts, ":")
+}
+
+func xxd(data []byte) string {
+	var sb strings.Builder
+	for offset := 0; offset < len(data); offset += 16 {
+		line := data[offset:]
+		if len(line) > 16 {
+			line = line[:16]
+		}
+		fmt.Fprintf(&sb, "%08x: ", offset)
+		for i := 0; i < len(line); i += 2 {
+			fmt.Fprintf(&sb, "%s ", hex.EncodeToString(line[i:min(i+2, len(line))]))
+		}
+		sb.WriteString(" ")
+		for _, b := range line {
+			if b >= 0x20 && b <= 0x7e {
+				sb.WriteByte(b)
+			} else {
+				sb.WriteByte('.')
+			}
+		}
+		sb.WriteString("\n")
+	}
+	return sb.String()
+}
+
+func min(a, b int) int {
+	if a < b {
+		return a
+	}
+	return b
+}
@@ -0,0 +1,104 @@
+/*
+Copyright 2026 The Knative Authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+// Package decode implements an offline decoder for captured control protocol frames.
+package decode
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/google/uuid"
+
+	ctrl "knative.dev/control-protocol/pkg"
+	"knative.dev/control-protocol/pkg/wiretap"
+)
+
+const headerLength = 24
+
+// Frame is a decoded control protocol message
+type Frame struct {
+	// Index is the position of the frame in the input
+	Index int
+	// Offset is the offset of the frame in the byte stream. For wire tap recordings, this is the index of the record.
+	Offset int64
+
+	// Tapped is true if the frame comes from a wire tap recording, and Time, Direction and Peer are set
+	Tapped    bool
+	Time      time.Time
+	Direction wiretap.Direction
+	Peer      string
+
+	// Message is the message as read from the wire
+	Message ctrl.Message
+	// TraceContext is the trace context carried by the message, if any
+	TraceContext []byte
+	// Payload is the message payload, without the trace context
+	Payload []byte
+
+	// Decoded is the payload decoded by the PayloadDecoder registered for the opcode, if any
+	Decoded interface{}
+
+	// Problems lists the malformed parts of the frame, which didn't prevent decoding the following frames
+	Problems []string
+
+	// AckedBy is the index of the frame acking this message, or -1 if the ack was not found
+	AckedBy int
+	// Acks are the indexes of the frames acked by this ack frame
+	Acks []int
+	// AckedIDs are the UUIDs acked by this ack frame, including the ones without a matching frame
+	AckedIDs []uuid.UUID
+	// AckError is the error propagated by this ack frame, if any
+	AckError string
+}
+
+// IsAck returns true if the frame is an ack
+func (f *Frame) IsAck() bool {
+	return ctrl.OpCode(f.Message.OpCode()) == ctrl.AckOpCode
+}
+
+// IsCredit returns true if the frame is a flow control credit grant
+func (f *Frame) IsCredit() bool {
+	return ctrl.OpCode(f.Message.OpCode()) == ctrl.CreditOpCode
+}
+
+// OpCodeName returns a human readable name of the frame opcode
+func (f *Frame) OpCodeName() string {
+	switch {
+	case f.IsAck():
+		return "ack"
+	case f.IsCredit():
+		return "credit"
+	case ctrl.OpCode(f.Message.OpCode()) == ctrl.RegistrationOpCode:
+		return "registration"
+	default:
+		return fmt.Sprintf("%d", f.Message.OpCode())
+	}
+}
+
+// FrameError is returned when a frame is truncated or malformed in a way that prevents decoding the rest of the input
+type FrameError struct {
+	// Index is the position of the frame in the input
+	Index int
+	// Offset is the offset of the frame in the byte stream
+	Offset int64
+	// Reason describes what's wrong with the frame
+	Reason string
+}
+
+func (e *FrameError) Error() string {
+	return fmt.Sprintf("frame #%d at offset %d: %s", e.Index, e.Offset, e.Reason)
+}
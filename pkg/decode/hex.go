@@ -0,0 +1,111 @@
+/*
+Copyright 2026 The Knative Authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package decode
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/hex"
+	"fmt"
+	"strings"
+)
+
+// ParseHex parses a hex dump. The supported formats are:
+//
+//   - plain hex, optionally separated by whitespaces or colons, like the tcp.payload field exported by tshark
+//   - xxd output, e.g. "00000000: 0001 0203  ...."
+//   - hexdump -C output, e.g. "00000000  00 01 02 03  |....|"
+//   - tcpdump -x/-X output, e.g. "0x0000:  0001 0203  ...."
+func ParseHex(data []byte) ([]byte, error) {
+	var out []byte
+	scanner := bufio.NewScanner(bytes.NewReader(data))
+	scanner.Buffer(make([]byte, 64*1024), len(data)+1)
+	lineNumber := 0
+	for scanner.Scan() {
+		lineNumber++
+		b, err := parseHexLine(scanner.Text())
+		if err != nil {
+			return nil, fmt.Errorf("line %d: %w", lineNumber, err)
+		}
+		out = append(out, b...)
+	}
+	return out, scanner.Err()
+}
+
+func parseHexLine(line string) ([]byte, error) {
+	line = strings.TrimSpace(line)
+	if line == "" {
+		return nil, nil
+	}
+
+	// hexdump -C prints the ascii column between pipes
+	if i := strings.IndexByte(line, '|'); i >= 0 {
+		line = line[:i]
+	}
+
+	hasOffset := false
+	fields := strings.Fields(line)
+	if len(fields) > 1 && strings.HasSuffix(fields[0], ":") && isHex(strings.TrimPrefix(strings.TrimSuffix(fields[0], ":"), "0x")) {
+		// xxd and tcpdump
+		hasOffset = true
+		line = strings.TrimSpace(line[len(fields[0]):])
+		// The ascii column is separated by two spaces
+		if i := strings.Index(line, "  "); i >= 0 {
+			line = line[:i]
+		}
+	} else if len(fields) > 1 && len(fields[0]) >= 7 && isHex(fields[0]) && len(fields[1]) == 2 {
+		// hexdump -C
+		hasOffset = true
+		line = strings.TrimSpace(line[len(fields[0]):])
+	}
+
+	var out []byte
+	for _, field := range strings.Fields(line) {
+		field = strings.ReplaceAll(field, ":", "")
+		if !hasOffset {
+			field = strings.TrimPrefix(field, "0x")
+		}
+		b, err := hex.DecodeString(field)
+		if err != nil {
+			return nil, fmt.Errorf("invalid hex %q: %w", field, err)
+		}
+		out = append(out, b...)
+	}
+	return out, nil
+}
+
+func isHex(s string) bool {
+	if s == "" {
+		return false
+	}
+	for _, c := range s {
+		if !(c >= '0' && c <= '9' || c >= 'a' && c <= 'f' || c >= 'A' && c <= 'F') {
+			return false
+		}
+	}
+	return true
+}
+
+// looksLikeText returns true if the data contains only printable ascii characters and whitespaces
+func looksLikeText(data []byte) bool {
+	for _, c := range data {
+		if c != '\n' && c != '\r' && c != '\t' && (c < 0x20 || c > 0x7e) {
+			return false
+		}
+	}
+	return true
+}
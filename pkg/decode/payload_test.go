@@ -0,0 +1,46 @@
+/*
+Copyright 2026 The Knative Authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package decode_test
+
+import (
+	"bytes"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	"knative.dev/control-protocol/pkg/decode"
+)
+
+func TestWritePayload(t *testing.T) {
+	var buf bytes.Buffer
+	decode.WritePayload(&buf, []byte("Funky!\nFunkier!"), "  ")
+	require.Equal(t, "  Funky!\n  Funkier!\n", buf.String())
+
+	buf.Reset()
+	decode.WritePayload(&buf, []byte{0x00, 0x01, 0xff}, "  ")
+	require.Equal(t, "  00000000  00 01 ff                                          |...|\n", buf.String())
+
+	buf.Reset()
+	decode.WritePayload(&buf, nil, "  ")
+	require.Empty(t, buf.String())
+}
+
+func TestIsPrintable(t *testing.T) {
+	require.True(t, decode.IsPrintable([]byte("Funky!\t\n")))
+	require.False(t, decode.IsPrintable([]byte{0x00}))
+	require.False(t, decode.IsPrintable([]byte{0xff}))
+}
@@ -0,0 +1,38 @@
+/*
+Copyright 2026 The Knative Authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+// Package reserved allows the control protocol itself to send the messages with a reserved opcode,
+// which the control services reject when sent by the applications.
+package reserved
+
+import (
+	"context"
+
+	ctrl "knative.dev/control-protocol/pkg"
+)
+
+type opCodeKey struct{}
+
+// WithReservedOpCode returns a context allowing the control service to send a message with the reserved opcode
+func WithReservedOpCode(ctx context.Context, opcode ctrl.OpCode) context.Context {
+	return context.WithValue(ctx, opCodeKey{}, opcode)
+}
+
+// Allowed returns true if the context allows sending a message with the reserved opcode
+func Allowed(ctx context.Context, opcode ctrl.OpCode) bool {
+	allowed, ok := ctx.Value(opCodeKey{}).(ctrl.OpCode)
+	return ok && allowed == opcode
+}
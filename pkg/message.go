@@ -18,6 +18,7 @@ package control
 
 import (
 	"encoding/binary"
+	"fmt"
 	"io"
 
 	"github.com/google/uuid"
@@ -32,6 +33,15 @@ const (
 
 type MessageFlag uint8
 
+const (
+	// BatchedAckFlag marks an ack message acking several messages at once.
+	// The payload of such ack contains the concatenated UUIDs of the acked messages.
+	BatchedAckFlag MessageFlag = 1 << iota
+	// TraceContextFlag marks a message carrying a serialized trace context.
+	// The payload of such message is prefixed by the 2 bytes length of the trace context, followed by the trace context itself.
+	TraceContextFlag
+)
+
 /*
 MessageHeader represents a message header
 
@@ -144,6 +154,22 @@ func WithFlags(flags uint8) MessageOpt {
 	}
 }
 
+// WithTraceContext attaches the serialized trace context to the message, prefixing the payload and setting TraceContextFlag.
+// This option must be applied after the payload is set.
+// Both ends of the connection must run a version of the control protocol supporting the trace context,
+// otherwise the other end handles the trace context as part of the payload.
+func WithTraceContext(traceContext []byte) MessageOpt {
+	return func(message *Message) {
+		payload := make([]byte, 2, 2+len(traceContext)+len(message.payload))
+		binary.BigEndian.PutUint16(payload, uint16(len(traceContext)))
+		payload = append(payload, traceContext...)
+		payload = append(payload, message.payload...)
+		message.payload = payload
+		message.length = uint32(len(payload))
+		message.flags |= uint8(TraceContextFlag)
+	}
+}
+
 // NewMessage creates a new message.
 func NewMessage(uuid [16]byte, opcode uint8, payload []byte, opts ...MessageOpt) Message {
 	length := uint32(0)
@@ -172,6 +198,26 @@ func (m Message) Payload() []byte {
 	return m.payload
 }
 
+// StripTraceContext removes the trace context from the payload, clearing TraceContextFlag, and returns it.
+// If the message doesn't carry a trace context, this method returns nil.
+func (m *Message) StripTraceContext() ([]byte, error) {
+	if !m.Check(TraceContextFlag) {
+		return nil, nil
+	}
+	if len(m.payload) < 2 {
+		return nil, fmt.Errorf("payload too short to contain the trace context length: %d", len(m.payload))
+	}
+	traceContextLength := int(binary.BigEndian.Uint16(m.payload[0:2]))
+	if len(m.payload) < 2+traceContextLength {
+		return nil, fmt.Errorf("payload too short to contain the trace context: %d < %d", len(m.payload), 2+traceContextLength)
+	}
+	traceContext := m.payload[2 : 2+traceContextLength]
+	m.payload = m.payload[2+traceContextLength:]
+	m.length = uint32(len(m.payload))
+	m.flags &^= uint8(TraceContextFlag)
+	return traceContext, nil
+}
+
 func (m *Message) ReadFrom(r io.Reader) (count int64, err error) {
 	var b [24]byte
 	var n int
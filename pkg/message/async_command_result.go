@@ -16,7 +16,10 @@ limitations under the License.
 
 package message
 
-import "encoding/binary"
+import (
+	"encoding/binary"
+	"fmt"
+)
 
 // AsyncCommandResult is a data structure representing an asynchronous command result.
 // This can be used in use cases where the controller sends a command, which is acked as soon as is received by the data plane,
@@ -50,10 +53,22 @@ func (k AsyncCommandResult) MarshalBinary() (data []byte, err error) {
 }
 
 func (k *AsyncCommandResult) UnmarshalBinary(data []byte) error {
+	if len(data) < 4 {
+		return fmt.Errorf("async command result too short: %d bytes", len(data))
+	}
 	commandLength := int(binary.BigEndian.Uint32(data[0:4]))
+	if len(data) < 4+commandLength {
+		return fmt.Errorf("async command result too short to contain the command id: %d < %d", len(data), 4+commandLength)
+	}
 	k.CommandId = data[4 : 4+commandLength]
 	if len(data) > 4+commandLength {
+		if len(data) < 4+commandLength+4 {
+			return fmt.Errorf("async command result too short to contain the error length: %d < %d", len(data), 4+commandLength+4)
+		}
 		errLength := int(binary.BigEndian.Uint32(data[4+commandLength : 4+commandLength+4]))
+		if len(data) < 4+commandLength+4+errLength {
+			return fmt.Errorf("async command result too short to contain the error: %d < %d", len(data), 4+commandLength+4+errLength)
+		}
 		k.Error = string(data[4+commandLength+4 : 4+commandLength+4+errLength])
 	}
 	return nil
@@ -55,3 +55,16 @@ func TestAsyncCommandResult_RoundTrip(t *testing.T) {
 		})
 	}
 }
+
+func TestAsyncCommandResult_UnmarshalMalformed(t *testing.T) {
+	valid, err := message.AsyncCommandResult{
+		CommandId: message.Int64CommandId(1),
+		Error:     "funky error",
+	}.MarshalBinary()
+	require.NoError(t, err)
+
+	for _, length := range []int{0, 3, 4 + 4, 4 + 8 + 2, len(valid) - 1} {
+		_, err := message.ParseAsyncCommandResult(valid[:length])
+		require.Error(t, err, "length %d", length)
+	}
+}
@@ -0,0 +1,68 @@
+/*
+Copyright 2026 The Knative Authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package message
+
+import (
+	"encoding/binary"
+	"fmt"
+)
+
+// Registration is sent with control.RegistrationOpCode by a data plane dialing the control plane,
+// to register the connection into the control plane connection pool. The data planes send it with network.WithClientRegistration,
+// since the control services reject the reserved opcodes sent by the applications.
+// Look at reconciler.StartReverseConnectionListener.
+type Registration struct {
+	// Key is the pool key the connection is registered for
+	Key string
+	// Host is the host the connection is registered as. When empty, the control plane uses the remote address of the connection.
+	// Unless the control plane allows otherwise, the host must have the IP of the remote address
+	Host string
+}
+
+func (r Registration) MarshalBinary() (data []byte, err error) {
+	b := make([]byte, 4+len(r.Key)+4+len(r.Host))
+	binary.BigEndian.PutUint32(b[0:4], uint32(len(r.Key)))
+	copy(b[4:4+len(r.Key)], r.Key)
+	binary.BigEndian.PutUint32(b[4+len(r.Key):4+len(r.Key)+4], uint32(len(r.Host)))
+	copy(b[4+len(r.Key)+4:], r.Host)
+	return b, nil
+}
+
+func (r *Registration) UnmarshalBinary(data []byte) error {
+	if len(data) < 4 {
+		return fmt.Errorf("registration too short: %d bytes", len(data))
+	}
+	keyLength := int(binary.BigEndian.Uint32(data[0:4]))
+	if len(data) < 4+keyLength+4 {
+		return fmt.Errorf("registration too short to contain the key and the host length: %d < %d", len(data), 4+keyLength+4)
+	}
+	hostLength := int(binary.BigEndian.Uint32(data[4+keyLength : 4+keyLength+4]))
+	if len(data) < 4+keyLength+4+hostLength {
+		return fmt.Errorf("registration too short to contain the host: %d < %d", len(data), 4+keyLength+4+hostLength)
+	}
+	r.Key = string(data[4 : 4+keyLength])
+	r.Host = string(data[4+keyLength+4 : 4+keyLength+4+hostLength])
+	return nil
+}
+
+func ParseRegistration(data []byte) (interface{}, error) {
+	var r Registration
+	if err := r.UnmarshalBinary(data); err != nil {
+		return nil, err
+	}
+	return r, nil
+}
@@ -0,0 +1,63 @@
+/*
+Copyright 2026 The Knative Authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package message_test
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	"knative.dev/control-protocol/pkg/message"
+)
+
+func TestRegistration_RoundTrip(t *testing.T) {
+	testCases := map[string]message.Registration{
+		"key and host": {
+			Key:  "ns/broker",
+			Host: "10.0.0.1:9000",
+		},
+		"without host": {
+			Key: "ns/broker",
+		},
+		"empty": {},
+	}
+
+	for testName, registration := range testCases {
+		t.Run(testName, func(t *testing.T) {
+			marshalled, err := registration.MarshalBinary()
+			require.NoError(t, err)
+			require.Len(t, marshalled, 4+len(registration.Key)+4+len(registration.Host))
+
+			have, err := message.ParseRegistration(marshalled)
+			require.NoError(t, err)
+			require.Equal(t, registration, have)
+		})
+	}
+}
+
+func TestRegistration_UnmarshalMalformed(t *testing.T) {
+	valid, err := message.Registration{
+		Key:  "ns/broker",
+		Host: "10.0.0.1:9000",
+	}.MarshalBinary()
+	require.NoError(t, err)
+
+	for _, length := range []int{0, 3, 4 + 9, 4 + 9 + 4 + 2, len(valid) - 1} {
+		_, err := message.ParseRegistration(valid[:length])
+		require.Error(t, err, "length %d", length)
+	}
+}
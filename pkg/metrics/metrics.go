@@ -0,0 +1,233 @@
+/*
+Copyright 2026 The Knative Authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+// Package metrics contains the OpenCensus metrics of the control protocol.
+// The views must be registered with RegisterViews, which registers them with knative.dev/pkg/metrics,
+// so they're exported through the standard Knative metrics pipeline.
+package metrics
+
+import (
+	"context"
+	"strconv"
+	"time"
+
+	"go.opencensus.io/stats"
+	"go.opencensus.io/stats/view"
+	"go.opencensus.io/tag"
+	pkgmetrics "knative.dev/pkg/metrics"
+
+	control "knative.dev/control-protocol/pkg"
+)
+
+const (
+	// AckErrorNack is the error type of the acks propagating an error from the other end
+	AckErrorNack = "nack"
+	// AckErrorTimeout is the error type of the acks not received in time
+	AckErrorTimeout = "timeout"
+
+	// WriteQueue is the queue of the messages waiting to be written to the connection
+	WriteQueue = "write"
+	// ReadQueue is the queue of the messages read from the connection, waiting to be handled
+	ReadQueue = "read"
+
+	// ClientRole is the role of the connections dialed by a control client
+	ClientRole = "client"
+	// ServerRole is the role of the connections accepted by a control server
+	ServerRole = "server"
+)
+
+var (
+	OpCodeKey    = tag.MustNewKey("opcode")
+	ErrorTypeKey = tag.MustNewKey("error_type")
+	QueueKey     = tag.MustNewKey("queue")
+	RoleKey      = tag.MustNewKey("role")
+	PoolKeyKey   = tag.MustNewKey("pool_key")
+
+	messagesSentM = stats.Int64(
+		"control_messages_sent",
+		"Number of messages sent",
+		stats.UnitDimensionless,
+	)
+	messagesReceivedM = stats.Int64(
+		"control_messages_received",
+		"Number of messages received",
+		stats.UnitDimensionless,
+	)
+	ackLatencyM = stats.Float64(
+		"control_ack_latency",
+		"Time between sending a message and receiving its ack",
+		stats.UnitMilliseconds,
+	)
+	ackErrorsM = stats.Int64(
+		"control_ack_errors",
+		"Number of sent messages acked with an error or not acked in time",
+		stats.UnitDimensionless,
+	)
+	queueDepthM = stats.Int64(
+		"control_queue_depth",
+		"Number of messages waiting in the connection queues",
+		stats.UnitDimensionless,
+	)
+	reconnectsM = stats.Int64(
+		"control_reconnects",
+		"Number of connections re-established with the other end",
+		stats.UnitDimensionless,
+	)
+	handlerDurationM = stats.Float64(
+		"control_handler_duration",
+		"Time between the delivery of a message to the handler and its ack",
+		stats.UnitMilliseconds,
+	)
+	poolConnectionsM = stats.Int64(
+		"control_pool_connections",
+		"Number of connections in the connection pool",
+		stats.UnitDimensionless,
+	)
+
+	latencyBuckets = pkgmetrics.Buckets125(1, 100000) // 1ms to 100s
+
+	// Views are the views of the control protocol metrics, registered by RegisterViews
+	Views = []*view.View{{
+		Description: messagesSentM.Description(),
+		Measure:     messagesSentM,
+		Aggregation: view.Count(),
+		TagKeys:     []tag.Key{OpCodeKey},
+	}, {
+		Description: messagesReceivedM.Description(),
+		Measure:     messagesReceivedM,
+		Aggregation: view.Count(),
+		TagKeys:     []tag.Key{OpCodeKey},
+	}, {
+		Description: ackLatencyM.Description(),
+		Measure:     ackLatencyM,
+		Aggregation: view.Distribution(latencyBuckets...),
+		TagKeys:     []tag.Key{OpCodeKey},
+	}, {
+		Description: ackErrorsM.Description(),
+		Measure:     ackErrorsM,
+		Aggregation: view.Count(),
+		TagKeys:     []tag.Key{OpCodeKey, ErrorTypeKey},
+	}, {
+		// Depth changes are recorded as deltas, so the sum is the depth of the queues of all the connections
+		Description: queueDepthM.Description(),
+		Measure:     queueDepthM,
+		Aggregation: view.Sum(),
+		TagKeys:     []tag.Key{QueueKey},
+	}, {
+		Description: reconnectsM.Description(),
+		Measure:     reconnectsM,
+		Aggregation: view.Count(),
+		TagKeys:     []tag.Key{RoleKey},
+	}, {
+		Description: handlerDurationM.Description(),
+		Measure:     handlerDurationM,
+		Aggregation: view.Distribution(latencyBuckets...),
+		TagKeys:     []tag.Key{OpCodeKey},
+	}, {
+		// Changes are recorded as deltas, so the sum is the number of connections of every key of the pools
+		Description: poolConnectionsM.Description(),
+		Measure:     poolConnectionsM,
+		Aggregation: view.Sum(),
+		TagKeys:     []tag.Key{PoolKeyKey},
+	}}
+)
+
+type viewOptions struct {
+	withoutPoolKeyTag bool
+}
+
+type ViewOption func(*viewOptions)
+
+// WithoutPoolKeyTag aggregates the number of connections of the connection pool across all the keys.
+// By default every pool key is a different time series, use this when the number of keys is unbounded.
+func WithoutPoolKeyTag() ViewOption {
+	return func(options *viewOptions) {
+		options.withoutPoolKeyTag = true
+	}
+}
+
+// RegisterViews registers the views of the control protocol metrics with knative.dev/pkg/metrics.
+// Until the views are registered, the metrics are not exported.
+func RegisterViews(opts ...ViewOption) error {
+	options := viewOptions{}
+	for _, fn := range opts {
+		fn(&options)
+	}
+
+	views := make([]*view.View, 0, len(Views))
+	for _, v := range Views {
+		if v.Measure == poolConnectionsM && options.withoutPoolKeyTag {
+			untagged := *v
+			untagged.TagKeys = nil
+			v = &untagged
+		}
+		views = append(views, v)
+	}
+	return pkgmetrics.RegisterResourceView(views...)
+}
+
+// RecordMessageSent records a message written by the control service
+func RecordMessageSent(ctx context.Context, opcode control.OpCode) {
+	record(ctx, messagesSentM.M(1), tag.Upsert(OpCodeKey, opCodeTag(opcode)))
+}
+
+// RecordMessageReceived records a message received by the control service, excluding acks
+func RecordMessageReceived(ctx context.Context, opcode control.OpCode) {
+	record(ctx, messagesReceivedM.M(1), tag.Upsert(OpCodeKey, opCodeTag(opcode)))
+}
+
+// RecordAckLatency records the time elapsed between sending a message and receiving its ack
+func RecordAckLatency(ctx context.Context, opcode control.OpCode, latency time.Duration) {
+	record(ctx, ackLatencyM.M(toMillis(latency)), tag.Upsert(OpCodeKey, opCodeTag(opcode)))
+}
+
+// RecordAckError records a sent message which was acked with an error, or not acked in time
+func RecordAckError(ctx context.Context, opcode control.OpCode, errorType string) {
+	record(ctx, ackErrorsM.M(1), tag.Upsert(OpCodeKey, opCodeTag(opcode)), tag.Upsert(ErrorTypeKey, errorType))
+}
+
+// RecordQueueDepthChange records a change of the depth of a connection queue.
+// The connections sample the depth of their queues periodically, so the changes are not recorded for every message.
+func RecordQueueDepthChange(ctx context.Context, queue string, delta int64) {
+	record(ctx, queueDepthM.M(delta), tag.Upsert(QueueKey, queue))
+}
+
+// RecordReconnect records a connection re-established with the other end
+func RecordReconnect(ctx context.Context, role string) {
+	record(ctx, reconnectsM.M(1), tag.Upsert(RoleKey, role))
+}
+
+// RecordHandlerDuration records the time elapsed between the delivery of a message to the handler and its ack
+func RecordHandlerDuration(ctx context.Context, opcode control.OpCode, duration time.Duration) {
+	record(ctx, handlerDurationM.M(toMillis(duration)), tag.Upsert(OpCodeKey, opCodeTag(opcode)))
+}
+
+// RecordPoolConnectionsChange records a change of the number of connections of a key of the connection pool
+func RecordPoolConnectionsChange(ctx context.Context, key string, delta int) {
+	record(ctx, poolConnectionsM.M(int64(delta)), tag.Upsert(PoolKeyKey, key))
+}
+
+func record(ctx context.Context, measurement stats.Measurement, mutators ...tag.Mutator) {
+	pkgmetrics.Record(ctx, measurement, stats.WithTags(mutators...))
+}
+
+func opCodeTag(opcode control.OpCode) string {
+	return strconv.Itoa(int(opcode))
+}
+
+func toMillis(d time.Duration) float64 {
+	return float64(d) / float64(time.Millisecond)
+}
@@ -0,0 +1,137 @@
+/*
+Copyright 2026 The Knative Authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package metrics_test
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+	"go.opencensus.io/stats/view"
+	"go.opencensus.io/tag"
+	pkgmetrics "knative.dev/pkg/metrics"
+
+	"knative.dev/control-protocol/pkg/metrics"
+)
+
+func init() {
+	pkgmetrics.InitForTesting()
+	if err := metrics.RegisterViews(); err != nil {
+		panic(err)
+	}
+}
+
+func TestRegisterViews_Conflict(t *testing.T) {
+	// The pool connections view is already registered with a different tag
+	require.Error(t, metrics.RegisterViews(metrics.WithoutPoolKeyTag()))
+}
+
+func TestRecordMessages(t *testing.T) {
+	ctx := context.TODO()
+
+	metrics.RecordMessageSent(ctx, 1)
+	metrics.RecordMessageSent(ctx, 1)
+	metrics.RecordMessageSent(ctx, 2)
+	metrics.RecordMessageReceived(ctx, 3)
+
+	require.Equal(t, int64(2), countFor(t, "control_messages_sent", tag.Tag{Key: metrics.OpCodeKey, Value: "1"}))
+	require.Equal(t, int64(1), countFor(t, "control_messages_sent", tag.Tag{Key: metrics.OpCodeKey, Value: "2"}))
+	require.Equal(t, int64(1), countFor(t, "control_messages_received", tag.Tag{Key: metrics.OpCodeKey, Value: "3"}))
+}
+
+func TestRecordAcks(t *testing.T) {
+	ctx := context.TODO()
+
+	metrics.RecordAckLatency(ctx, 4, 10*time.Millisecond)
+	metrics.RecordAckLatency(ctx, 4, 30*time.Millisecond)
+	metrics.RecordAckError(ctx, 4, metrics.AckErrorTimeout)
+
+	rows, err := view.RetrieveData("control_ack_latency")
+	require.NoError(t, err)
+	row := findRow(rows, tag.Tag{Key: metrics.OpCodeKey, Value: "4"})
+	require.NotNil(t, row)
+	distribution := row.Data.(*view.DistributionData)
+	require.Equal(t, int64(2), distribution.Count)
+	require.InDelta(t, 20, distribution.Mean, 0.001)
+
+	require.Equal(t, int64(1), countFor(t, "control_ack_errors",
+		tag.Tag{Key: metrics.ErrorTypeKey, Value: metrics.AckErrorTimeout},
+		tag.Tag{Key: metrics.OpCodeKey, Value: "4"},
+	))
+}
+
+func TestRecordQueueDepth(t *testing.T) {
+	ctx := context.TODO()
+
+	metrics.RecordQueueDepthChange(ctx, metrics.WriteQueue, 1)
+	metrics.RecordQueueDepthChange(ctx, metrics.WriteQueue, 1)
+	metrics.RecordQueueDepthChange(ctx, metrics.WriteQueue, -1)
+
+	rows, err := view.RetrieveData("control_queue_depth")
+	require.NoError(t, err)
+	row := findRow(rows, tag.Tag{Key: metrics.QueueKey, Value: metrics.WriteQueue})
+	require.NotNil(t, row)
+	require.Equal(t, float64(1), row.Data.(*view.SumData).Value)
+}
+
+func TestRecordPoolConnections(t *testing.T) {
+	ctx := context.TODO()
+
+	metrics.RecordPoolConnectionsChange(ctx, "my-key", 1)
+	metrics.RecordPoolConnectionsChange(ctx, "my-key", 1)
+	metrics.RecordPoolConnectionsChange(ctx, "my-key", 1)
+	metrics.RecordPoolConnectionsChange(ctx, "my-key", -1)
+
+	rows, err := view.RetrieveData("control_pool_connections")
+	require.NoError(t, err)
+	row := findRow(rows, tag.Tag{Key: metrics.PoolKeyKey, Value: "my-key"})
+	require.NotNil(t, row)
+	require.Equal(t, float64(2), row.Data.(*view.SumData).Value)
+}
+
+func countFor(t *testing.T, viewName string, tags ...tag.Tag) int64 {
+	rows, err := view.RetrieveData(viewName)
+	require.NoError(t, err)
+	row := findRow(rows, tags...)
+	if row == nil {
+		return 0
+	}
+	return row.Data.(*view.CountData).Value
+}
+
+func findRow(rows []*view.Row, tags ...tag.Tag) *view.Row {
+	for _, row := range rows {
+		if len(row.Tags) != len(tags) {
+			continue
+		}
+		matches := true
+		for _, want := range tags {
+			found := false
+			for _, got := range row.Tags {
+				if got == want {
+					found = true
+				}
+			}
+			matches = matches && found
+		}
+		if matches {
+			return row
+		}
+	}
+	return nil
+}
@@ -21,10 +21,13 @@ import (
 	"fmt"
 	"net"
 	"sync"
+	"sync/atomic"
+	"time"
 
 	"go.uber.org/zap"
 
 	ctrl "knative.dev/control-protocol/pkg"
+	"knative.dev/control-protocol/pkg/metrics"
 )
 
 type baseTcpConnection struct {
@@ -34,11 +37,38 @@ type baseTcpConnection struct {
 	writeQueue unboundedMessageQueue
 	readQueue  unboundedMessageQueue
 
+	// flow is nil when the connection doesn't support flow control
+	flow *flowController
+
 	unrecoverableErrors chan error
+
+	// remoteAddr is the remote address of the connection being consumed
+	remoteAddr atomic.Value
+
+	// connLock guards closeConn
+	connLock sync.Mutex
+	// closeConn closes the connection being consumed, nil if no connection is being consumed
+	closeConn context.CancelFunc
+	// onConsumingLocked, if not nil, is invoked with connLock held every time a new connection is consumed, right after setting closeConn
+	onConsumingLocked func()
+	// pickFirst, if not nil, is invoked with the write queue lock held before choosing the next message to write.
+	// When it returns true, its message is written, or none if nil, instead of the one chosen by the flow control.
+	pickFirst func(queue []*ctrl.Message) (*ctrl.Message, int, bool)
 }
 
 var _ ctrl.Connection = (*baseTcpConnection)(nil)
 
+// remoteAddrHolder allows to store different net.Addr implementations in the same atomic.Value
+type remoteAddrHolder struct {
+	addr net.Addr
+}
+
+// RemoteAddr returns the remote address of the last consumed connection, or nil if no connection was consumed yet
+func (t *baseTcpConnection) RemoteAddr() net.Addr {
+	holder, _ := t.remoteAddr.Load().(remoteAddrHolder)
+	return holder.addr
+}
+
 func (t *baseTcpConnection) WriteMessage(msg *ctrl.Message) {
 	t.writeQueue.append(msg)
 }
@@ -55,10 +85,23 @@ func (t *baseTcpConnection) Errors() <-chan error {
 // This method is blocking and returns when either the connection broke or the t.ctx get closed.
 func (t *baseTcpConnection) consumeConnection(conn net.Conn) {
 	t.logger.Infof("Started consuming new conn: %s", conn.RemoteAddr())
+	t.remoteAddr.Store(remoteAddrHolder{addr: conn.RemoteAddr()})
+
+	if t.flow != nil {
+		// Credits are valid only for a single connection
+		t.flow.reset()
+	}
 
 	// This channel get closed also when t.ctx is closed by the last goroutine in this method
 	closedConnCtx, closedConnCancel := context.WithCancel(context.TODO())
 
+	t.connLock.Lock()
+	t.closeConn = closedConnCancel
+	if t.onConsumingLocked != nil {
+		t.onConsumingLocked()
+	}
+	t.connLock.Unlock()
+
 	var wg sync.WaitGroup
 	wg.Add(3)
 
@@ -94,17 +137,22 @@ func (t *baseTcpConnection) consumeConnection(conn net.Conn) {
 		defer wg.Done()
 		for {
 			// Blocking queue polling
-			msg := t.writeQueue.blockingPoll(closedConnCtx)
+			msg := t.pollOutbound(closedConnCtx)
 			if msg == nil {
 				return // Polling was
 			}
 
 			err := connWrite(conn, msg)
+			if err == nil && t.flow != nil {
+				t.flow.onWritten(msg)
+			}
 			if err != nil {
-				// Let's re-enqueue the message
-				t.writeQueue.prepend(msg)
+				// Let's re-enqueue the message, unless it's a credit grant which is valid only for this connection
+				if ctrl.OpCode(msg.OpCode()) != ctrl.CreditOpCode {
+					t.writeQueue.prepend(msg)
+				}
 
-				if isEOF(err) {
+				if isEOF(err) || closedConnCtx.Err() != nil {
 					return // Closed conn
 				}
 
@@ -126,8 +174,8 @@ func (t *baseTcpConnection) consumeConnection(conn net.Conn) {
 			msg, err := connRead(conn)
 
 			if err != nil {
-				// Check closed conn
-				if isEOF(err) {
+				// Check closed conn, either by the remote peer or on purpose
+				if isEOF(err) || closedConnCtx.Err() != nil {
 					return
 				}
 
@@ -138,7 +186,7 @@ func (t *baseTcpConnection) consumeConnection(conn net.Conn) {
 				if !isTransientError(err) {
 					return
 				}
-			} else {
+			} else if t.acceptInbound(msg) {
 				t.readQueue.append(msg)
 			}
 
@@ -151,9 +199,81 @@ func (t *baseTcpConnection) consumeConnection(conn net.Conn) {
 
 	wg.Wait()
 
+	t.connLock.Lock()
+	t.closeConn = nil
+	t.connLock.Unlock()
+
 	t.logger.Debugf("Stopped consuming connection with local %s and remote %s", conn.LocalAddr().String(), conn.RemoteAddr().String())
 }
 
+// pollOutbound blocks until there's a message which can be written to the connection
+func (t *baseTcpConnection) pollOutbound(ctx context.Context) *ctrl.Message {
+	if t.flow == nil && t.pickFirst == nil {
+		return t.writeQueue.blockingPoll(ctx)
+	}
+	return t.writeQueue.blockingPollFunc(ctx, t.pickOutbound)
+}
+
+// pickOutbound chooses the next message to write, look at unboundedMessageQueue.blockingPollFunc
+func (t *baseTcpConnection) pickOutbound(queue []*ctrl.Message) (*ctrl.Message, int) {
+	if t.pickFirst != nil {
+		if msg, i, ok := t.pickFirst(queue); ok {
+			return msg, i
+		}
+	}
+	if t.flow != nil {
+		return t.flow.pick(queue)
+	}
+	if len(queue) == 0 {
+		return nil, -1
+	}
+	return queue[0], 0
+}
+
+// acceptInbound returns true if the message read from the connection should be propagated to the read queue
+func (t *baseTcpConnection) acceptInbound(msg *ctrl.Message) bool {
+	if t.flow == nil {
+		return true
+	}
+	propagate, err := t.flow.onRead(msg)
+	if err != nil {
+		t.logger.Warnf("Discarding malformed credit grant: %v", err)
+	}
+	if !propagate {
+		// We might have new credits, let's wake up the write loop
+		t.writeQueue.signal()
+	}
+	return propagate
+}
+
+// sampleQueueDepth records the depth of the queues every interval, rather than on every message, until t.ctx is closed.
+// Then the messages left in the queues are dropped, so their depth is recorded as zero.
+func (t *baseTcpConnection) sampleQueueDepth(interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	var writeDepth, readDepth int
+	for {
+		select {
+		case <-ticker.C:
+			writeDepth = t.recordQueueDepth(metrics.WriteQueue, writeDepth, t.writeQueue.len())
+			readDepth = t.recordQueueDepth(metrics.ReadQueue, readDepth, t.readQueue.len())
+		case <-t.ctx.Done():
+			t.recordQueueDepth(metrics.WriteQueue, writeDepth, 0)
+			t.recordQueueDepth(metrics.ReadQueue, readDepth, 0)
+			return
+		}
+	}
+}
+
+// recordQueueDepth records the change from the last recorded depth of queue, and returns the new one
+func (t *baseTcpConnection) recordQueueDepth(queue string, recorded int, depth int) int {
+	if depth != recorded {
+		metrics.RecordQueueDepthChange(t.ctx, queue, int64(depth-recorded))
+	}
+	return depth
+}
+
 // cleanup is safe to be invoked only if no connection is being consumed and t.ctx is closed
 func (t *baseTcpConnection) cleanup() {
 	// Let's make sure we unblock some dangling polling
@@ -164,6 +284,13 @@ func (t *baseTcpConnection) cleanup() {
 	close(t.unrecoverableErrors)
 }
 
+func wrapConnection(conn ctrl.Connection, wrappers []ctrl.ConnectionWrapper) ctrl.Connection {
+	for _, wrap := range wrappers {
+		conn = wrap(conn)
+	}
+	return conn
+}
+
 func connRead(conn net.Conn) (*ctrl.Message, error) {
 	msg := &ctrl.Message{}
 	n, err := msg.ReadFrom(conn)
@@ -27,12 +27,23 @@ import (
 	"github.com/google/uuid"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
+	"go.opencensus.io/stats/view"
+	"go.opencensus.io/tag"
 	"go.uber.org/atomic"
 	"go.uber.org/zap"
+	pkgmetrics "knative.dev/pkg/metrics"
 
 	ctrl "knative.dev/control-protocol/pkg"
+	"knative.dev/control-protocol/pkg/metrics"
 )
 
+func init() {
+	pkgmetrics.InitForTesting()
+	if err := metrics.RegisterViews(); err != nil {
+		panic(err)
+	}
+}
+
 func TestBaseTcpConnection_ConsumeConnection_ReturnsAfterConnectionFailure(t *testing.T) {
 	ctx, cancelFn := context.WithCancel(context.TODO())
 	logger, _ := zap.NewDevelopment()
@@ -161,6 +172,54 @@ func TestBaseTcpConnection_ConsumeConnection_BrokenConnectionDoesntLoseOutboundM
 	wg.Wait()
 }
 
+func TestBaseTcpConnection_SampleQueueDepth(t *testing.T) {
+	ctx, cancelFn := context.WithCancel(context.TODO())
+	logger, _ := zap.NewDevelopment()
+
+	tcpConn := &baseTcpConnection{
+		ctx:                 ctx,
+		logger:              logger.Sugar(),
+		writeQueue:          newUnboundedMessageQueue(),
+		readQueue:           newUnboundedMessageQueue(),
+		unrecoverableErrors: make(chan error, 10),
+	}
+
+	sampled := make(chan struct{})
+	go func() {
+		tcpConn.sampleQueueDepth(10 * time.Millisecond)
+		close(sampled)
+	}()
+
+	for i := 0; i < 3; i++ {
+		msg := ctrl.NewMessage(uuid.New(), 10, nil)
+		tcpConn.WriteMessage(&msg)
+	}
+	require.Eventually(t, func() bool {
+		return queueDepth(t, metrics.WriteQueue) == 3
+	}, 5*time.Second, 10*time.Millisecond)
+
+	tcpConn.writeQueue.blockingPoll(ctx)
+	require.Eventually(t, func() bool {
+		return queueDepth(t, metrics.WriteQueue) == 2
+	}, 5*time.Second, 10*time.Millisecond)
+
+	// The messages left in the queue are dropped
+	cancelFn()
+	<-sampled
+	require.Equal(t, float64(0), queueDepth(t, metrics.WriteQueue))
+}
+
+func queueDepth(t *testing.T, queue string) float64 {
+	rows, err := view.RetrieveData("control_queue_depth")
+	require.NoError(t, err)
+	for _, row := range rows {
+		if len(row.Tags) == 1 && row.Tags[0] == (tag.Tag{Key: metrics.QueueKey, Value: queue}) {
+			return row.Data.(*view.SumData).Value
+		}
+	}
+	return 0
+}
+
 type mockConn struct {
 	writeReturn        chan interface{}
 	readReturn         chan interface{}
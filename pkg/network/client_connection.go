@@ -18,14 +18,22 @@ package network
 
 import (
 	"context"
+	"errors"
 	"fmt"
 	"net"
+	"strconv"
 	"strings"
+	"sync"
 	"time"
 
+	"github.com/google/uuid"
+	"k8s.io/utils/clock"
 	"knative.dev/pkg/logging"
 
 	ctrl "knative.dev/control-protocol/pkg"
+	"knative.dev/control-protocol/pkg/internal/reserved"
+	"knative.dev/control-protocol/pkg/message"
+	"knative.dev/control-protocol/pkg/metrics"
 	ctrlservice "knative.dev/control-protocol/pkg/service"
 )
 
@@ -33,27 +41,132 @@ type Dialer interface {
 	DialContext(ctx context.Context, network, addr string) (net.Conn, error)
 }
 
-func StartControlClient(ctx context.Context, dialer Dialer, target string) (ctrl.Service, error) {
-	if !strings.Contains(target, ":") {
-		target = target + ":9000"
+type ControlClientOptions struct {
+	flowControl        FlowControl
+	serviceOptions     []ctrlservice.ServiceOption
+	connectionWrappers []ctrl.ConnectionWrapper
+	clock              clock.WithDelayedExecution
+	stateCallback      ConnectionStateCallback
+	cyclerCallback     func(ConnectionCycler)
+	registrations      []message.Registration
+}
+
+// ConnectionCycler replaces the connection of a control client with a new one, without stopping the control service.
+type ConnectionCycler interface {
+	// CycleConnection closes the current connection and waits until the client reconnects,
+	// dialing again with its Dialer. The messages not yet written are written to the new connection.
+	// It returns an error if the client cannot reconnect, or if ctx is done before it reconnects.
+	CycleConnection(ctx context.Context) error
+}
+
+type ControlClientOption func(*ControlClientOptions)
+
+// WithClientFlowControl enables the credit based flow control of the inbound messages.
+// Look at FlowControl for more details.
+func WithClientFlowControl(flowControl FlowControl) ControlClientOption {
+	return func(options *ControlClientOptions) {
+		options.flowControl = flowControl
+	}
+}
+
+// WithClientServiceOptions configures the options of the control service
+func WithClientServiceOptions(opts ...ctrlservice.ServiceOption) ControlClientOption {
+	return func(options *ControlClientOptions) {
+		options.serviceOptions = append(options.serviceOptions, opts...)
+	}
+}
+
+// WithClientConnectionWrappers wraps the connection used by the control service, in the provided order
+func WithClientConnectionWrappers(wrappers ...ctrl.ConnectionWrapper) ControlClientOption {
+	return func(options *ControlClientOptions) {
+		options.connectionWrappers = append(options.connectionWrappers, wrappers...)
+	}
+}
+
+// WithClientClock sets the clock used to wait between the dial retries and by the control service.
+// Tests can provide a fake clock, to advance the time deterministically.
+func WithClientClock(clock clock.WithDelayedExecution) ControlClientOption {
+	return func(options *ControlClientOptions) {
+		options.clock = clock
+	}
+}
+
+// WithClientStateCallback sets the callback invoked every time the connection changes its state.
+// Look at ConnectionStateCallback for more details.
+func WithClientStateCallback(callback ConnectionStateCallback) ControlClientOption {
+	return func(options *ControlClientOptions) {
+		options.stateCallback = callback
+	}
+}
+
+// WithClientCycler sets a callback invoked with the ConnectionCycler of the client, before StartControlClient returns.
+// This allows to cycle the connection, e.g. after the credentials used by the Dialer rotated.
+func WithClientCycler(callback func(ConnectionCycler)) ControlClientOption {
+	return func(options *ControlClientOptions) {
+		options.cyclerCallback = callback
+	}
+}
+
+// WithClientRegistration sends the provided registrations every time the client connects, before any other queued message,
+// to register the connection into the connection pool of a control plane which cannot dial this data plane.
+// Look at reconciler.StartReverseConnectionListener for more details.
+func WithClientRegistration(registrations ...message.Registration) ControlClientOption {
+	return func(options *ControlClientOptions) {
+		options.registrations = append(options.registrations, registrations...)
+	}
+}
+
+func StartControlClient(ctx context.Context, dialer Dialer, target string, options ...ControlClientOption) (ctrl.Service, error) {
+	opts := ControlClientOptions{
+		clock: clock.RealClock{},
+	}
+
+	for _, fn := range options {
+		fn(&opts)
 	}
+	// Prepend the clock, so it can still be overridden by the service options
+	serviceOptions := append([]ctrlservice.ServiceOption{ctrlservice.WithClock(opts.clock)}, opts.serviceOptions...)
+
+	target = withDefaultPort(target)
 	logging.FromContext(ctx).Infof("Starting control client to %s", target)
 
+	if opts.stateCallback != nil {
+		opts.stateCallback(ConnectionConnecting)
+	}
+
 	// Let's try the dial
-	conn, err := tryDial(ctx, dialer, target, clientInitialDialRetry, clientDialRetryInterval)
+	conn, err := tryDial(ctx, opts.clock, dialer, target, clientInitialDialRetry, clientDialRetryInterval)
 	if err != nil {
 		return nil, fmt.Errorf("cannot perform the initial dial to target %s: %w", target, err)
 	}
 
-	tcpConn := newClientTcpConnection(ctx, dialer)
-	svc := ctrlservice.NewService(ctx, tcpConn)
+	tcpConn := newClientTcpConnection(ctx, dialer, opts.flowControl, opts.clock, opts.stateCallback)
+	svc := ctrlservice.NewService(ctx, wrapConnection(tcpConn, opts.connectionWrappers), serviceOptions...)
+	if len(opts.registrations) != 0 {
+		tcpConn.registrations = opts.registrations
+		tcpConn.registrationSender = svc
+		tcpConn.pickFirst = tcpConn.pickRegistration
+	}
+	if opts.cyclerCallback != nil {
+		opts.cyclerCallback(tcpConn)
+	}
 
 	tcpConn.startPolling(conn)
 
 	return svc, nil
 }
 
-func tryDial(ctx context.Context, dialer Dialer, target string, retries int, interval time.Duration) (net.Conn, error) {
+// withDefaultPort appends DefaultControlPort to target if it has no port.
+// target can be an IPv6 address, either bracketed or not.
+func withDefaultPort(target string) string {
+	if _, _, err := net.SplitHostPort(target); err == nil {
+		return target
+	}
+	host := strings.TrimSuffix(strings.TrimPrefix(target, "["), "]")
+	return net.JoinHostPort(host, strconv.Itoa(DefaultControlPort))
+}
+
+func tryDial(ctx context.Context, clock clock.Clock, dialer Dialer, target string, retries int, interval time.Duration) (net.Conn, error) {
 	var conn net.Conn
 	var err error
 	for i := 0; i < retries; i++ {
@@ -62,10 +175,12 @@ func tryDial(ctx context.Context, dialer Dialer, target string, retries int, int
 			return conn, nil
 		}
 		logging.FromContext(ctx).Warnf("Error while trying to connect: %v", err)
+		timer := clock.NewTimer(interval)
 		select {
 		case <-ctx.Done():
+			timer.Stop()
 			return nil, err
-		case <-time.After(interval):
+		case <-timer.C():
 		}
 	}
 	return nil, err
@@ -74,28 +189,58 @@ func tryDial(ctx context.Context, dialer Dialer, target string, retries int, int
 type clientTcpConnection struct {
 	baseTcpConnection
 
-	dialer Dialer
+	dialer        Dialer
+	clock         clock.Clock
+	stateCallback ConnectionStateCallback
+
+	// cycleWaiters are notified when the client reconnects or stops. Guarded by connLock.
+	cycleWaiters []chan error
+	// stopped is true when the client doesn't reconnect anymore. Guarded by connLock.
+	stopped bool
+
+	// registrations are sent every time the client connects, before the other messages of the write queue
+	registrations      []message.Registration
+	registrationSender ctrl.ContextSender
+
+	// registrationsLock guards pendingRegistrations
+	registrationsLock sync.Mutex
+	// pendingRegistrations are the uuids of the registrations not yet written to the current connection.
+	// While it's not empty, the other messages of the write queue are held.
+	pendingRegistrations map[uuid.UUID]struct{}
 }
 
-func newClientTcpConnection(ctx context.Context, dialer Dialer) *clientTcpConnection {
+var _ ConnectionCycler = (*clientTcpConnection)(nil)
+
+func newClientTcpConnection(ctx context.Context, dialer Dialer, flowControl FlowControl, clock clock.Clock, stateCallback ConnectionStateCallback) *clientTcpConnection {
 	c := &clientTcpConnection{
 		baseTcpConnection: baseTcpConnection{
 			ctx:                 ctx,
 			logger:              logging.FromContext(ctx),
 			writeQueue:          newUnboundedMessageQueue(),
 			readQueue:           newUnboundedMessageQueue(),
+			flow:                newFlowController(flowControl),
 			unrecoverableErrors: make(chan error, 10),
 		},
-		dialer: dialer,
+		dialer:        dialer,
+		clock:         clock,
+		stateCallback: stateCallback,
+	}
+	// Notify the waiters together with setting closeConn, so a CycleConnection invoked in between cannot miss both
+	c.onConsumingLocked = func() {
+		c.notifyCycleWaitersLocked(nil, false)
+		c.register()
 	}
 	return c
 }
 
 func (t *clientTcpConnection) startPolling(initialConn net.Conn) {
+	go t.sampleQueueDepth(queueDepthSamplingInterval)
+
 	// We have 1 goroutine that consumes the connections and it eventually reconnects.
 	// When done, it closed the internal channels
 	go func(initialConn net.Conn) {
 		// Consume the initial connection
+		t.notifyState(ConnectionReady)
 		t.consumeConnection(initialConn)
 
 		// This returns when either the context is closed
@@ -105,9 +250,63 @@ func (t *clientTcpConnection) startPolling(initialConn net.Conn) {
 		t.logger.Infof("Closing control client")
 		t.cleanup()
 		t.logger.Infof("Connection closed")
+
+		state := ConnectionBroken
+		if t.ctx.Err() != nil {
+			state = ConnectionClosed
+		}
+		t.notifyCycleWaiters(fmt.Errorf("the control client is %s", state), true)
+		t.notifyState(state)
 	}(initialConn)
 }
 
+// register sends the registrations to the connection about to be consumed, holding the write queue until they're written
+func (t *clientTcpConnection) register() {
+	if len(t.registrations) == 0 {
+		return
+	}
+
+	ids := make([]uuid.UUID, len(t.registrations))
+	t.registrationsLock.Lock()
+	t.pendingRegistrations = make(map[uuid.UUID]struct{}, len(ids))
+	for i := range ids {
+		ids[i] = uuid.New()
+		t.pendingRegistrations[ids[i]] = struct{}{}
+	}
+	t.registrationsLock.Unlock()
+
+	for i, registration := range t.registrations {
+		go func(id uuid.UUID, registration message.Registration) {
+			ctx := reserved.WithReservedOpCode(ctrl.ContextWithMessageUUID(t.ctx, id), ctrl.RegistrationOpCode)
+			if err := t.registrationSender.SendAndWaitForAckWithContext(ctx, ctrl.RegistrationOpCode, registration); err != nil {
+				t.logger.Warnf("Cannot register the control connection for key %s: %v", registration.Key, err)
+			}
+			// Release the write queue even if the registration was never written, e.g. dropped by a connection wrapper
+			t.registrationsLock.Lock()
+			delete(t.pendingRegistrations, id)
+			t.registrationsLock.Unlock()
+			t.writeQueue.signal()
+		}(ids[i], registration)
+	}
+}
+
+// pickRegistration picks the pending registrations before any other message of the write queue
+func (t *clientTcpConnection) pickRegistration(queue []*ctrl.Message) (*ctrl.Message, int, bool) {
+	t.registrationsLock.Lock()
+	defer t.registrationsLock.Unlock()
+	if len(t.pendingRegistrations) == 0 {
+		return nil, -1, false
+	}
+	for i, msg := range queue {
+		if _, ok := t.pendingRegistrations[msg.UUID()]; ok {
+			delete(t.pendingRegistrations, msg.UUID())
+			return msg, i, true
+		}
+	}
+	// Wait for the registrations to be enqueued
+	return nil, -1, true
+}
+
 func (t *clientTcpConnection) reDialLoop(remoteAddr net.Addr) {
 	// Retry until connection closed
 	for {
@@ -116,15 +315,63 @@ func (t *clientTcpConnection) reDialLoop(remoteAddr net.Addr) {
 			return
 		default:
 			t.logger.Warnf("Connection lost, retrying to reconnect %s", remoteAddr.String())
+			t.notifyState(ConnectionReconnecting)
 
 			// Let's try the dial
-			conn, err := tryDial(t.ctx, t.dialer, remoteAddr.String(), clientReconnectionRetry, clientDialRetryInterval)
+			conn, err := tryDial(t.ctx, t.clock, t.dialer, remoteAddr.String(), clientReconnectionRetry, clientDialRetryInterval)
 			if err != nil {
 				t.logger.Warnf("Cannot re-dial to target %s: %v", remoteAddr.String(), err)
 				return
 			}
+			metrics.RecordReconnect(t.ctx, metrics.ClientRole)
 
+			t.notifyState(ConnectionReady)
 			t.consumeConnection(conn)
 		}
 	}
 }
+
+func (t *clientTcpConnection) CycleConnection(ctx context.Context) error {
+	done := make(chan error, 1)
+
+	t.connLock.Lock()
+	if t.stopped {
+		t.connLock.Unlock()
+		return errors.New("the control client is stopped")
+	}
+	t.cycleWaiters = append(t.cycleWaiters, done)
+	// If no connection is being consumed, the client is already reconnecting
+	if t.closeConn != nil {
+		t.logger.Infof("Cycling the connection to %s", t.RemoteAddr())
+		t.closeConn()
+	}
+	t.connLock.Unlock()
+
+	select {
+	case err := <-done:
+		return err
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// notifyCycleWaiters notifies the goroutines waiting in CycleConnection
+func (t *clientTcpConnection) notifyCycleWaiters(err error, stopped bool) {
+	t.connLock.Lock()
+	defer t.connLock.Unlock()
+	t.notifyCycleWaitersLocked(err, stopped)
+}
+
+func (t *clientTcpConnection) notifyCycleWaitersLocked(err error, stopped bool) {
+	for _, w := range t.cycleWaiters {
+		w <- err
+	}
+	t.cycleWaiters = nil
+	t.stopped = stopped
+}
+
+func (t *clientTcpConnection) notifyState(state ConnectionState) {
+	if t.stateCallback != nil {
+		t.stateCallback(state)
+	}
+}
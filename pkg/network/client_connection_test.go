@@ -5,9 +5,17 @@ import (
 	"errors"
 	"net"
 	"testing"
+	"time"
 
 	"github.com/stretchr/testify/require"
 	"go.uber.org/atomic"
+	"go.uber.org/zap"
+	"k8s.io/utils/clock"
+	clocktesting "k8s.io/utils/clock/testing"
+	"knative.dev/pkg/logging"
+
+	ctrl "knative.dev/control-protocol/pkg"
+	"knative.dev/control-protocol/pkg/message"
 )
 
 func TestClientPollingLoop(t *testing.T) {
@@ -29,7 +37,7 @@ func TestClientPollingLoop(t *testing.T) {
 		conn:      dialedConn,
 	}
 
-	tcpConn := newClientTcpConnection(ctx, dialer)
+	tcpConn := newClientTcpConnection(ctx, dialer, FlowControl{}, clock.RealClock{}, nil)
 	tcpConn.startPolling(initialConn)
 
 	// Now let's make the initial connection fail
@@ -52,6 +60,55 @@ func TestClientPollingLoop(t *testing.T) {
 	require.True(t, dialer.dialTries < 0)
 }
 
+func TestTryDial_WaitsRetryIntervalOnClock(t *testing.T) {
+	fakeClock := clocktesting.NewFakeClock(time.Now())
+	conn := &mockConn{}
+	dialer := &mockDialer{
+		dialTries: 2,
+		conn:      conn,
+	}
+
+	type dialResult struct {
+		conn net.Conn
+		err  error
+	}
+	result := make(chan dialResult)
+	go func() {
+		c, err := tryDial(context.TODO(), fakeClock, dialer, "localhost:9000", 5, time.Hour)
+		result <- dialResult{conn: c, err: err}
+	}()
+
+	// The first dial fails, so tryDial waits for the retry interval
+	require.Eventually(t, fakeClock.HasWaiters, time.Second, time.Millisecond)
+	select {
+	case <-result:
+		t.Fatal("tryDial shouldn't retry before the retry interval")
+	default:
+	}
+
+	fakeClock.Step(time.Hour)
+	r := <-result
+	require.NoError(t, r.err)
+	require.Equal(t, conn, r.conn)
+}
+
+func TestWithDefaultPort(t *testing.T) {
+	tests := map[string]string{
+		"10.0.0.1":          "10.0.0.1:9000",
+		"10.0.0.1:8080":     "10.0.0.1:8080",
+		"localhost":         "localhost:9000",
+		"fd00::1":           "[fd00::1]:9000",
+		"[fd00::1]":         "[fd00::1]:9000",
+		"[fd00::1]:8080":    "[fd00::1]:8080",
+		"my.host.svc:10000": "my.host.svc:10000",
+	}
+	for target, want := range tests {
+		t.Run(target, func(t *testing.T) {
+			require.Equal(t, want, withDefaultPort(target))
+		})
+	}
+}
+
 type mockDialer struct {
 	dialTries int
 	conn      *mockConn
@@ -64,3 +121,155 @@ func (d *mockDialer) DialContext(ctx context.Context, network, addr string) (net
 	}
 	return nil, errors.New("funky error")
 }
+
+func TestClientCycleConnection(t *testing.T) {
+	logger, _ := zap.NewDevelopment()
+	ctx, cancelFn := context.WithCancel(logging.WithLogger(context.TODO(), logger.Sugar()))
+	t.Cleanup(cancelFn)
+
+	memoryNetwork := NewMemoryNetwork()
+
+	ln, err := memoryNetwork.Listen("10.0.0.1:9000")
+	require.NoError(t, err)
+	serverCtx, serverCancelFn := context.WithCancel(ctx)
+	server, err := StartInsecureControlServer(serverCtx, WithListener(ln))
+	require.NoError(t, err)
+
+	var cycler ConnectionCycler
+	states := make(chan ConnectionState, 10)
+	client, err := StartControlClient(ctx, memoryNetwork, "10.0.0.1:9000",
+		WithClientCycler(func(c ConnectionCycler) {
+			cycler = c
+		}),
+		WithClientStateCallback(func(state ConnectionState) {
+			states <- state
+		}),
+	)
+	require.NoError(t, err)
+	require.NotNil(t, cycler)
+	sendReceive(t, client, server)
+
+	require.NoError(t, cycler.CycleConnection(ctx))
+	sendReceive(t, client, server)
+	sendReceive(t, server, client)
+
+	require.Equal(t, ConnectionConnecting, <-states)
+	require.Equal(t, ConnectionReady, <-states)
+	require.Equal(t, ConnectionReconnecting, <-states)
+	require.Equal(t, ConnectionReady, <-states)
+
+	// The client cannot reconnect without the server
+	serverCancelFn()
+	<-server.ClosedCh()
+	require.EqualError(t, cycler.CycleConnection(ctx), "the control client is broken")
+	require.EqualError(t, cycler.CycleConnection(ctx), "the control client is stopped")
+}
+
+func TestClientCycleConnection_WhileReconnecting(t *testing.T) {
+	ctx, cancelFn := context.WithCancel(context.TODO())
+	t.Cleanup(cancelFn)
+
+	memoryNetwork := NewMemoryNetwork()
+	ln, err := memoryNetwork.Listen("10.0.0.1:9000")
+	require.NoError(t, err)
+	_, err = StartInsecureControlServer(ctx, WithListener(ln))
+	require.NoError(t, err)
+
+	var cycler ConnectionCycler
+	_, err = StartControlClient(ctx, memoryNetwork, "10.0.0.1:9000",
+		WithClientCycler(func(c ConnectionCycler) {
+			cycler = c
+		}),
+	)
+	require.NoError(t, err)
+
+	// Cycling again right after the reconnection must never miss the next connection
+	for i := 0; i < 20; i++ {
+		cycleCtx, cycleCancelFn := context.WithTimeout(ctx, 5*time.Second)
+		require.NoError(t, cycler.CycleConnection(cycleCtx))
+		cycleCancelFn()
+	}
+}
+
+// blockingDialer blocks the dials after the first one until release is closed
+type blockingDialer struct {
+	Dialer
+	dials   atomic.Int32
+	release chan struct{}
+}
+
+func (d *blockingDialer) DialContext(ctx context.Context, network, addr string) (net.Conn, error) {
+	if d.dials.Inc() > 1 {
+		<-d.release
+	}
+	return d.Dialer.DialContext(ctx, network, addr)
+}
+
+func TestClientRegistration_BeforeQueuedMessages(t *testing.T) {
+	ctx, cancelFn := context.WithCancel(context.TODO())
+	t.Cleanup(cancelFn)
+
+	memoryNetwork := NewMemoryNetwork()
+	ln, err := memoryNetwork.Listen("10.0.0.1:9000")
+	require.NoError(t, err)
+	t.Cleanup(func() {
+		_ = ln.Close()
+	})
+
+	dialer := &blockingDialer{Dialer: memoryNetwork, release: make(chan struct{})}
+	var tcpConn *clientTcpConnection
+	client, err := StartControlClient(ctx, dialer, "10.0.0.1:9000",
+		WithClientRegistration(message.Registration{Key: "ns/name"}),
+		WithClientCycler(func(c ConnectionCycler) {
+			tcpConn = c.(*clientTcpConnection)
+		}),
+	)
+	require.NoError(t, err)
+
+	conn, err := ln.Accept()
+	require.NoError(t, err)
+	msg, err := connRead(conn)
+	require.NoError(t, err)
+	require.Equal(t, uint8(ctrl.RegistrationOpCode), msg.OpCode())
+
+	// The message is queued while the client is reconnecting
+	require.NoError(t, conn.Close())
+	go func() {
+		_ = client.SendAndWaitForAck(1, rawPayload("Funky!"))
+	}()
+	require.Eventually(t, func() bool {
+		return tcpConn.writeQueue.len() == 1
+	}, 5*time.Second, 10*time.Millisecond)
+	close(dialer.release)
+
+	conn, err = ln.Accept()
+	require.NoError(t, err)
+	t.Cleanup(func() {
+		_ = conn.Close()
+	})
+	msg, err = connRead(conn)
+	require.NoError(t, err)
+	require.Equal(t, uint8(ctrl.RegistrationOpCode), msg.OpCode())
+	msg, err = connRead(conn)
+	require.NoError(t, err)
+	require.Equal(t, uint8(1), msg.OpCode())
+	require.Equal(t, "Funky!", string(msg.Payload()))
+}
+
+func sendReceive(t *testing.T, sender ctrl.Service, receiver ctrl.Service) {
+	received := make(chan struct{})
+	receiver.MessageHandler(ctrl.MessageHandlerFunc(func(ctx context.Context, message ctrl.ServiceMessage) {
+		require.Equal(t, "Funky!", string(message.Payload()))
+		message.Ack()
+		close(received)
+	}))
+
+	require.NoError(t, sender.SendAndWaitForAck(1, rawPayload("Funky!")))
+	<-received
+}
+
+type rawPayload string
+
+func (p rawPayload) MarshalBinary() ([]byte, error) {
+	return []byte(p), nil
+}
@@ -0,0 +1,54 @@
+/*
+Copyright 2026 The Knative Authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package network
+
+// ConnectionState is the state of the connection of a control client
+type ConnectionState int
+
+const (
+	// ConnectionConnecting is the state during the initial dial
+	ConnectionConnecting ConnectionState = iota
+	// ConnectionReady is the state while a connection is established with the other end
+	ConnectionReady
+	// ConnectionReconnecting is the state while re-dialing the other end, after the connection is lost
+	ConnectionReconnecting
+	// ConnectionBroken is the final state after the client gave up re-dialing the other end
+	ConnectionBroken
+	// ConnectionClosed is the final state after the context of the client is done
+	ConnectionClosed
+)
+
+func (s ConnectionState) String() string {
+	switch s {
+	case ConnectionConnecting:
+		return "connecting"
+	case ConnectionReady:
+		return "ready"
+	case ConnectionReconnecting:
+		return "reconnecting"
+	case ConnectionBroken:
+		return "broken"
+	case ConnectionClosed:
+		return "closed"
+	default:
+		return "unknown"
+	}
+}
+
+// ConnectionStateCallback is invoked every time the connection of a control client changes its state.
+// It's invoked synchronously by the goroutine managing the connection, so it must not block.
+type ConnectionStateCallback func(state ConnectionState)
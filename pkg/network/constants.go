@@ -19,9 +19,14 @@ package network
 import "time"
 
 const (
+	// DefaultControlPort is the port used by the control servers and clients when not specified
+	DefaultControlPort = 9000
+
 	clientInitialDialRetry  = 5
 	clientReconnectionRetry = 10
 	clientDialRetryInterval = 200 * time.Millisecond
 
+	queueDepthSamplingInterval = time.Second
+
 	KeepAlive = 30 * time.Second
 )
@@ -0,0 +1,173 @@
+/*
+Copyright 2026 The Knative Authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package network
+
+import (
+	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"net"
+	"time"
+
+	"knative.dev/pkg/logging"
+
+	ctrl "knative.dev/control-protocol/pkg"
+	"knative.dev/control-protocol/pkg/service"
+)
+
+const acceptHandshakeTimeout = 10 * time.Second
+
+// AcceptedConnection is a connection dialed by a remote peer and accepted by a ControlListener.
+// Unlike the connections of the control clients and servers, an accepted connection is not reconnected:
+// when it breaks, the remote peer is expected to dial a new one.
+type AcceptedConnection struct {
+	// Service is the control service of the connection
+	Service ctrl.Service
+	// RemoteAddr is the address of the remote peer
+	RemoteAddr net.Addr
+	// PeerCertificates are the certificates presented by the remote peer, empty when not using tls
+	PeerCertificates []*x509.Certificate
+
+	cancelFn context.CancelFunc
+	closedCh chan struct{}
+}
+
+// Close closes the connection
+func (c *AcceptedConnection) Close() {
+	c.cancelFn()
+}
+
+// ClosedCh returns a channel which is closed after the connection is closed, either by Close or because it broke
+func (c *AcceptedConnection) ClosedCh() <-chan struct{} {
+	return c.closedCh
+}
+
+type ControlListener struct {
+	closedCh <-chan struct{}
+	addr     net.Addr
+}
+
+// ClosedCh returns a channel which is closed after the listener stopped accepting connections
+func (cl *ControlListener) ClosedCh() <-chan struct{} {
+	return cl.closedCh
+}
+
+// Addr is the address where the listener is actually listening
+func (cl *ControlListener) Addr() net.Addr {
+	return cl.addr
+}
+
+// StartControlListener accepts the connections dialed by the remote peers, e.g. data planes which cannot be dialed by the control plane.
+// Unlike the ControlServer, which consumes one connection at a time, the listener serves all the accepted connections concurrently,
+// each one with its own control service. acceptFn is invoked for every accepted connection, before any message is received from it.
+// When tlsConfigLoader is not nil, the tls handshake is completed before invoking acceptFn.
+// The listener and all the accepted connections are closed when ctx is done.
+func StartControlListener(ctx context.Context, tlsConfigLoader func() (*tls.Config, error), acceptFn func(*AcceptedConnection), options ...ControlServerOption) (*ControlListener, error) {
+	opts := ControlServerOptions{
+		port:         DefaultControlPort,
+		listenConfig: &listenConfig,
+	}
+
+	for _, fn := range options {
+		fn(&opts)
+	}
+
+	ln, err := opts.listen(ctx)
+	if err != nil {
+		return nil, err
+	}
+	logging.FromContext(ctx).Infof("Started control listener: %s", ln.Addr().String())
+
+	closedCh := make(chan struct{})
+	go func() {
+		<-ctx.Done()
+		if err := ln.Close(); err != nil {
+			logging.FromContext(ctx).Warnf("Error while closing the control listener: %s", err)
+		}
+	}()
+	go func() {
+		defer close(closedCh)
+		for {
+			conn, err := ln.Accept()
+			if err != nil {
+				if ctx.Err() == nil {
+					logging.FromContext(ctx).Warnf("Error while accepting the connection, closing the control listener: %s", err)
+				}
+				return
+			}
+			go acceptConnection(ctx, conn, tlsConfigLoader, acceptFn, &opts)
+		}
+	}()
+
+	return &ControlListener{
+		closedCh: closedCh,
+		addr:     ln.Addr(),
+	}, nil
+}
+
+func acceptConnection(ctx context.Context, conn net.Conn, tlsConfigLoader func() (*tls.Config, error), acceptFn func(*AcceptedConnection), opts *ControlServerOptions) {
+	logger := logging.FromContext(ctx)
+
+	var peerCertificates []*x509.Certificate
+	if tlsConfigLoader != nil {
+		tlsConf, err := tlsConfigLoader()
+		if err != nil {
+			logger.Warnf("Cannot load tls configuration: %v", err)
+			_ = conn.Close()
+			return
+		}
+		tlsConn := tls.Server(conn, tlsConf)
+		handshakeCtx, cancelFn := context.WithTimeout(ctx, acceptHandshakeTimeout)
+		err = tlsConn.HandshakeContext(handshakeCtx)
+		cancelFn()
+		if err != nil {
+			logger.Warnf("Tls handshake with %s failed: %v", conn.RemoteAddr(), err)
+			_ = conn.Close()
+			return
+		}
+		peerCertificates = tlsConn.ConnectionState().PeerCertificates
+		conn = tlsConn
+	}
+	logger.Debugf("Accepting new control connection from %s", conn.RemoteAddr())
+
+	connCtx, cancelFn := context.WithCancel(ctx)
+	tcpConn := &baseTcpConnection{
+		ctx:                 connCtx,
+		logger:              logger,
+		writeQueue:          newUnboundedMessageQueue(),
+		readQueue:           newUnboundedMessageQueue(),
+		flow:                newFlowController(opts.flowControl),
+		unrecoverableErrors: make(chan error, 10),
+	}
+	accepted := &AcceptedConnection{
+		Service:          service.NewService(connCtx, wrapConnection(tcpConn, opts.connectionWrappers), opts.serviceOptions...),
+		RemoteAddr:       conn.RemoteAddr(),
+		PeerCertificates: peerCertificates,
+		cancelFn:         cancelFn,
+		closedCh:         make(chan struct{}),
+	}
+	acceptFn(accepted)
+
+	go tcpConn.sampleQueueDepth(queueDepthSamplingInterval)
+	tcpConn.consumeConnection(conn)
+
+	// The connection is not reconnected
+	cancelFn()
+	tcpConn.cleanup()
+	close(accepted.closedCh)
+	logger.Debugf("Closed control connection from %s", accepted.RemoteAddr)
+}
@@ -0,0 +1,318 @@
+/*
+Copyright 2026 The Knative Authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package network
+
+import (
+	"encoding/binary"
+	"fmt"
+	"sync"
+
+	"github.com/google/uuid"
+
+	ctrl "knative.dev/control-protocol/pkg"
+)
+
+// FlowControl configures the credit based flow control of the inbound side of a connection.
+//
+// When enabled, every time a new connection is established this end grants to the other end
+// a window of credits, and the other end stops writing messages when it runs out of them.
+// Credits are given back once the received messages are acked, so a slow handler naturally slows its sender.
+// Acks and credit grants are not subject to flow control, neither are the messages written
+// before the initial grant of the other end is received.
+//
+// Both ends of the connection must run a version of the control protocol supporting flow control.
+type FlowControl struct {
+	// Messages is the maximum number of in-flight messages the other end can send. 0 means unlimited.
+	Messages uint32
+	// Bytes is the maximum number of in-flight payload bytes the other end can send. 0 means unlimited.
+	// A single message bigger than the window is still delivered when some credit is available.
+	Bytes uint32
+
+	// Observer, when set, is invoked with a snapshot of the credit state every time it changes.
+	Observer func(CreditState)
+}
+
+func (f FlowControl) enabled() bool {
+	return f.Messages != 0 || f.Bytes != 0
+}
+
+// CreditState is a snapshot of the flow control state of the current connection.
+type CreditState struct {
+	// Negotiated is true when the other end granted credits on the current connection,
+	// hence outbound messages are subject to flow control.
+	Negotiated bool
+	// Blocked is true when outbound messages are waiting for new credits.
+	Blocked bool
+
+	// AvailableMessages is the number of messages that can be sent before running out of credits.
+	// Meaningful only if Negotiated and the other end limits the number of in-flight messages.
+	AvailableMessages int64
+	// AvailableBytes is the number of payload bytes that can be sent before running out of credits.
+	// Meaningful only if Negotiated and the other end limits the number of in-flight bytes.
+	AvailableBytes int64
+
+	// PendingMessages is the number of inbound messages received on the current connection and not yet acked.
+	PendingMessages int64
+	// PendingBytes is the number of payload bytes of the inbound messages received on the current connection and not yet acked.
+	PendingBytes int64
+}
+
+type creditGrant struct {
+	messages uint32
+	bytes    uint32
+}
+
+func (g creditGrant) MarshalBinary() ([]byte, error) {
+	b := make([]byte, 8)
+	binary.BigEndian.PutUint32(b[0:4], g.messages)
+	binary.BigEndian.PutUint32(b[4:8], g.bytes)
+	return b, nil
+}
+
+func (g *creditGrant) UnmarshalBinary(data []byte) error {
+	if len(data) != 8 {
+		return fmt.Errorf("unexpected credit grant length: %d != 8", len(data))
+	}
+	g.messages = binary.BigEndian.Uint32(data[0:4])
+	g.bytes = binary.BigEndian.Uint32(data[4:8])
+	return nil
+}
+
+// flowController tracks the credits of a single connection at time.
+// It must be reset every time a new connection is consumed.
+type flowController struct {
+	config FlowControl
+
+	mutex sync.Mutex
+
+	// Outbound side, granted by the other end
+	negotiated        bool
+	limitMessages     bool
+	limitBytes        bool
+	availableMessages int64
+	availableBytes    int64
+
+	// Inbound side, granted by this end
+	pending          map[uuid.UUID][]uint32
+	pendingMessages  int64
+	pendingBytes     int64
+	releasedMessages uint32
+	releasedBytes    uint32
+	grantToSend      *creditGrant
+}
+
+func newFlowController(config FlowControl) *flowController {
+	return &flowController{
+		config:  config,
+		pending: make(map[uuid.UUID][]uint32),
+	}
+}
+
+func isFlowControlled(msg *ctrl.Message) bool {
+	opcode := ctrl.OpCode(msg.OpCode())
+	return opcode != ctrl.AckOpCode && opcode != ctrl.CreditOpCode
+}
+
+// reset prepares the flow controller for a new connection, scheduling the initial grant if configured.
+func (f *flowController) reset() {
+	f.mutex.Lock()
+	f.negotiated = false
+	f.limitMessages = false
+	f.limitBytes = false
+	f.availableMessages = 0
+	f.availableBytes = 0
+	f.pending = make(map[uuid.UUID][]uint32)
+	f.pendingMessages = 0
+	f.pendingBytes = 0
+	f.releasedMessages = 0
+	f.releasedBytes = 0
+	f.grantToSend = nil
+	if f.config.enabled() {
+		f.grantToSend = &creditGrant{messages: f.config.Messages, bytes: f.config.Bytes}
+	}
+	state := f.stateLocked()
+	f.mutex.Unlock()
+
+	f.notify(state)
+}
+
+// pick chooses the next message to write: a pending credit grant first,
+// then the first message of the queue which is either not subject to flow control or fits in the available credits.
+// Acks can overtake messages waiting for credits, otherwise the two ends might wait for each other.
+func (f *flowController) pick(queue []*ctrl.Message) (*ctrl.Message, int) {
+	f.mutex.Lock()
+	defer f.mutex.Unlock()
+
+	if f.grantToSend != nil {
+		payload, _ := f.grantToSend.MarshalBinary()
+		f.grantToSend = nil
+		msg := ctrl.NewMessage(uuid.Nil, uint8(ctrl.CreditOpCode), payload)
+		return &msg, -1
+	}
+
+	canSend := f.canSendLocked()
+	for i, msg := range queue {
+		if !isFlowControlled(msg) || canSend {
+			return msg, i
+		}
+	}
+	return nil, -1
+}
+
+func (f *flowController) canSendLocked() bool {
+	if !f.negotiated {
+		return true
+	}
+	if f.limitMessages && f.availableMessages <= 0 {
+		return false
+	}
+	if f.limitBytes && f.availableBytes <= 0 {
+		return false
+	}
+	return true
+}
+
+// onWritten must be invoked after a message was successfully written to the connection.
+func (f *flowController) onWritten(msg *ctrl.Message) {
+	if ctrl.OpCode(msg.OpCode()) == ctrl.CreditOpCode {
+		return
+	}
+
+	f.mutex.Lock()
+	if isFlowControlled(msg) {
+		f.availableMessages--
+		f.availableBytes -= int64(msg.Length())
+	} else if msg.Check(ctrl.BatchedAckFlag) {
+		// A batched ack is going out, let's release the credits of all the acked messages
+		payload := msg.Payload()
+		for i := 0; i+16 <= len(payload); i += 16 {
+			var id uuid.UUID
+			copy(id[:], payload[i:i+16])
+			f.releaseLocked(id)
+		}
+	} else {
+		// An ack is going out, let's release the credits of the acked message
+		f.releaseLocked(msg.UUID())
+	}
+	state := f.stateLocked()
+	f.mutex.Unlock()
+
+	f.notify(state)
+}
+
+// onRead must be invoked for every message read from the connection.
+// It returns false if the message is a credit grant, hence it must not be propagated to the upper layers.
+func (f *flowController) onRead(msg *ctrl.Message) (bool, error) {
+	if ctrl.OpCode(msg.OpCode()) == ctrl.CreditOpCode {
+		var grant creditGrant
+		if err := grant.UnmarshalBinary(msg.Payload()); err != nil {
+			return false, err
+		}
+
+		f.mutex.Lock()
+		if !f.negotiated {
+			f.negotiated = true
+			f.limitMessages = grant.messages != 0
+			f.limitBytes = grant.bytes != 0
+		}
+		f.availableMessages += int64(grant.messages)
+		f.availableBytes += int64(grant.bytes)
+		state := f.stateLocked()
+		f.mutex.Unlock()
+
+		f.notify(state)
+		return false, nil
+	}
+
+	if !isFlowControlled(msg) || !f.config.enabled() {
+		return true, nil
+	}
+
+	f.mutex.Lock()
+	f.pending[msg.UUID()] = append(f.pending[msg.UUID()], msg.Length())
+	f.pendingMessages++
+	f.pendingBytes += int64(msg.Length())
+	state := f.stateLocked()
+	f.mutex.Unlock()
+
+	f.notify(state)
+	return true, nil
+}
+
+// releaseLocked releases the credits of the acked message and, once half of the window is released,
+// schedules a new credit grant.
+func (f *flowController) releaseLocked(id uuid.UUID) {
+	lengths, ok := f.pending[id]
+	if !ok {
+		// Not received on this connection
+		return
+	}
+	if len(lengths) == 1 {
+		delete(f.pending, id)
+	} else {
+		f.pending[id] = lengths[1:]
+	}
+	f.pendingMessages--
+	f.pendingBytes -= int64(lengths[0])
+	f.releasedMessages++
+	f.releasedBytes += lengths[0]
+
+	if (f.config.Messages != 0 && f.releasedMessages >= halfWindow(f.config.Messages)) ||
+		(f.config.Bytes != 0 && f.releasedBytes >= halfWindow(f.config.Bytes)) {
+		if f.grantToSend == nil {
+			f.grantToSend = &creditGrant{}
+		}
+		if f.config.Messages != 0 {
+			f.grantToSend.messages += f.releasedMessages
+		}
+		if f.config.Bytes != 0 {
+			f.grantToSend.bytes += f.releasedBytes
+		}
+		f.releasedMessages = 0
+		f.releasedBytes = 0
+	}
+}
+
+func halfWindow(window uint32) uint32 {
+	if window < 2 {
+		return 1
+	}
+	return window / 2
+}
+
+func (f *flowController) state() CreditState {
+	f.mutex.Lock()
+	defer f.mutex.Unlock()
+	return f.stateLocked()
+}
+
+func (f *flowController) stateLocked() CreditState {
+	return CreditState{
+		Negotiated:        f.negotiated,
+		Blocked:           !f.canSendLocked(),
+		AvailableMessages: f.availableMessages,
+		AvailableBytes:    f.availableBytes,
+		PendingMessages:   f.pendingMessages,
+		PendingBytes:      f.pendingBytes,
+	}
+}
+
+func (f *flowController) notify(state CreditState) {
+	if f.config.Observer != nil {
+		f.config.Observer(state)
+	}
+}
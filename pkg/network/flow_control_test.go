@@ -0,0 +1,217 @@
+/*
+Copyright 2026 The Knative Authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package network
+
+import (
+	"testing"
+
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/require"
+
+	ctrl "knative.dev/control-protocol/pkg"
+)
+
+func TestFlowController_InitialGrant(t *testing.T) {
+	fc := newFlowController(FlowControl{Messages: 10, Bytes: 100})
+	fc.reset()
+
+	msg, i := fc.pick(nil)
+	require.NotNil(t, msg)
+	require.Equal(t, -1, i)
+	require.Equal(t, uint8(ctrl.CreditOpCode), msg.OpCode())
+
+	var grant creditGrant
+	require.NoError(t, grant.UnmarshalBinary(msg.Payload()))
+	require.Equal(t, creditGrant{messages: 10, bytes: 100}, grant)
+
+	// Sent only once
+	msg, _ = fc.pick(nil)
+	require.Nil(t, msg)
+}
+
+func TestFlowController_DisabledDoesntGrant(t *testing.T) {
+	fc := newFlowController(FlowControl{})
+	fc.reset()
+
+	msg, _ := fc.pick(nil)
+	require.Nil(t, msg)
+
+	inbound := ctrl.NewMessage(uuid.New(), 1, []byte("abc"))
+	propagate, err := fc.onRead(&inbound)
+	require.NoError(t, err)
+	require.True(t, propagate)
+	require.Equal(t, int64(0), fc.state().PendingMessages)
+}
+
+func TestFlowController_BlocksWhenOutOfCredits(t *testing.T) {
+	fc := newFlowController(FlowControl{})
+	fc.reset()
+
+	data1 := ctrl.NewMessage(uuid.New(), 1, []byte("abc"))
+	data2 := ctrl.NewMessage(uuid.New(), 1, []byte("def"))
+	ack := ctrl.NewMessage(uuid.New(), uint8(ctrl.AckOpCode), nil)
+
+	// Not negotiated, everything goes through
+	msg, i := fc.pick([]*ctrl.Message{&data1})
+	require.Same(t, &data1, msg)
+	require.Equal(t, 0, i)
+
+	// Receive a grant for a single message
+	grant := mustCreditGrantMessage(t, creditGrant{messages: 1})
+	propagate, err := fc.onRead(&grant)
+	require.NoError(t, err)
+	require.False(t, propagate)
+	require.True(t, fc.state().Negotiated)
+	require.Equal(t, int64(1), fc.state().AvailableMessages)
+
+	fc.onWritten(&data1)
+	require.True(t, fc.state().Blocked)
+
+	// The ack overtakes the blocked message
+	msg, i = fc.pick([]*ctrl.Message{&data2, &ack})
+	require.Same(t, &ack, msg)
+	require.Equal(t, 1, i)
+
+	msg, _ = fc.pick([]*ctrl.Message{&data2})
+	require.Nil(t, msg)
+
+	// New credits unblock the message
+	grant = mustCreditGrantMessage(t, creditGrant{messages: 1})
+	_, err = fc.onRead(&grant)
+	require.NoError(t, err)
+	require.False(t, fc.state().Blocked)
+
+	msg, _ = fc.pick([]*ctrl.Message{&data2})
+	require.Same(t, &data2, msg)
+}
+
+func TestFlowController_BytesWindow(t *testing.T) {
+	fc := newFlowController(FlowControl{})
+	fc.reset()
+
+	grant := mustCreditGrantMessage(t, creditGrant{bytes: 4})
+	_, err := fc.onRead(&grant)
+	require.NoError(t, err)
+
+	// A message bigger than the window still goes through
+	big := ctrl.NewMessage(uuid.New(), 1, []byte("abcdefgh"))
+	msg, _ := fc.pick([]*ctrl.Message{&big})
+	require.Same(t, &big, msg)
+	fc.onWritten(&big)
+
+	require.Equal(t, int64(-4), fc.state().AvailableBytes)
+	require.True(t, fc.state().Blocked)
+}
+
+func TestFlowController_AckReleasesCredits(t *testing.T) {
+	fc := newFlowController(FlowControl{Messages: 4})
+	fc.reset()
+	_, _ = fc.pick(nil) // Initial grant
+
+	var inbound []ctrl.Message
+	for i := 0; i < 4; i++ {
+		msg := ctrl.NewMessage(uuid.New(), 1, []byte("abc"))
+		inbound = append(inbound, msg)
+		propagate, err := fc.onRead(&msg)
+		require.NoError(t, err)
+		require.True(t, propagate)
+	}
+	require.Equal(t, int64(4), fc.state().PendingMessages)
+	require.Equal(t, int64(12), fc.state().PendingBytes)
+
+	// First ack doesn't reach half window
+	ack := ctrl.NewMessage(inbound[0].UUID(), uint8(ctrl.AckOpCode), nil)
+	fc.onWritten(&ack)
+	msg, _ := fc.pick(nil)
+	require.Nil(t, msg)
+
+	// Second ack triggers the grant
+	ack = ctrl.NewMessage(inbound[1].UUID(), uint8(ctrl.AckOpCode), nil)
+	fc.onWritten(&ack)
+	msg, _ = fc.pick(nil)
+	require.NotNil(t, msg)
+	require.Equal(t, uint8(ctrl.CreditOpCode), msg.OpCode())
+
+	var grant creditGrant
+	require.NoError(t, grant.UnmarshalBinary(msg.Payload()))
+	require.Equal(t, creditGrant{messages: 2}, grant)
+	require.Equal(t, int64(2), fc.state().PendingMessages)
+
+	// Acks of unknown messages are ignored
+	ack = ctrl.NewMessage(uuid.New(), uint8(ctrl.AckOpCode), nil)
+	fc.onWritten(&ack)
+	require.Equal(t, int64(2), fc.state().PendingMessages)
+}
+
+func TestFlowController_BatchedAckReleasesCredits(t *testing.T) {
+	fc := newFlowController(FlowControl{Messages: 2})
+	fc.reset()
+	_, _ = fc.pick(nil) // Initial grant
+
+	var payload []byte
+	for i := 0; i < 2; i++ {
+		msg := ctrl.NewMessage(uuid.New(), 1, nil)
+		_, err := fc.onRead(&msg)
+		require.NoError(t, err)
+		id := msg.UUID()
+		payload = append(payload, id[:]...)
+	}
+	require.Equal(t, int64(2), fc.state().PendingMessages)
+
+	ack := ctrl.NewMessage(uuid.Nil, uint8(ctrl.AckOpCode), payload, ctrl.WithFlags(uint8(ctrl.BatchedAckFlag)))
+	fc.onWritten(&ack)
+	require.Equal(t, int64(0), fc.state().PendingMessages)
+
+	msg, _ := fc.pick(nil)
+	require.NotNil(t, msg)
+	require.Equal(t, uint8(ctrl.CreditOpCode), msg.OpCode())
+}
+
+func TestFlowController_ResetForgetsPreviousConnection(t *testing.T) {
+	fc := newFlowController(FlowControl{Messages: 4})
+	fc.reset()
+
+	grant := mustCreditGrantMessage(t, creditGrant{messages: 1})
+	_, err := fc.onRead(&grant)
+	require.NoError(t, err)
+
+	inbound := ctrl.NewMessage(uuid.New(), 1, nil)
+	_, err = fc.onRead(&inbound)
+	require.NoError(t, err)
+
+	fc.reset()
+	state := fc.state()
+	require.False(t, state.Negotiated)
+	require.Equal(t, int64(0), state.PendingMessages)
+}
+
+func TestFlowController_MalformedGrant(t *testing.T) {
+	fc := newFlowController(FlowControl{})
+	fc.reset()
+
+	grant := ctrl.NewMessage(uuid.Nil, uint8(ctrl.CreditOpCode), []byte{1, 2})
+	propagate, err := fc.onRead(&grant)
+	require.Error(t, err)
+	require.False(t, propagate)
+	require.False(t, fc.state().Negotiated)
+}
+
+func mustCreditGrantMessage(t *testing.T, grant creditGrant) ctrl.Message {
+	payload, err := grant.MarshalBinary()
+	require.NoError(t, err)
+	return ctrl.NewMessage(uuid.Nil, uint8(ctrl.CreditOpCode), payload)
+}
@@ -0,0 +1,222 @@
+/*
+Copyright 2026 The Knative Authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package network
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"strconv"
+	"sync"
+)
+
+const (
+	memoryNetworkName = "memory"
+
+	// memoryListenerBacklog is the number of dialed connections waiting to be accepted
+	memoryListenerBacklog = 128
+
+	firstEphemeralMemoryPort = 32768
+)
+
+var errConnectionRefused = errors.New("connection refused")
+
+// MemoryNetwork is an in-memory network, to run the network code without sockets in tests.
+// Listeners are created with MemoryNetwork.Listen, and can be passed to StartControlServer using WithListener.
+// MemoryNetwork implements Dialer, so it can be used with StartControlClient, and with the connection pool.
+// Connections are built on net.Pipe.
+type MemoryNetwork struct {
+	mutex         sync.Mutex
+	listeners     map[string]*memoryListener
+	nextEphemeral int
+}
+
+var _ Dialer = (*MemoryNetwork)(nil)
+
+func NewMemoryNetwork() *MemoryNetwork {
+	return &MemoryNetwork{
+		listeners:     make(map[string]*memoryListener),
+		nextEphemeral: firstEphemeralMemoryPort,
+	}
+}
+
+// Listen creates a new listener on the provided address, in the form host:port.
+// If the port is 0, a free port is allocated.
+func (n *MemoryNetwork) Listen(address string) (net.Listener, error) {
+	host, portStr, err := net.SplitHostPort(address)
+	if err != nil {
+		return nil, err
+	}
+	port, err := strconv.Atoi(portStr)
+	if err != nil {
+		return nil, fmt.Errorf("invalid port %q: %w", portStr, err)
+	}
+
+	n.mutex.Lock()
+	defer n.mutex.Unlock()
+
+	if port == 0 {
+		port = n.allocatePortLocked(host)
+	}
+	addr := memoryAddr(net.JoinHostPort(host, strconv.Itoa(port)))
+	if _, ok := n.listeners[addr.String()]; ok {
+		return nil, &net.OpError{Op: "listen", Net: memoryNetworkName, Addr: addr, Err: errors.New("address already in use")}
+	}
+
+	ln := &memoryListener{
+		network: n,
+		addr:    addr,
+		conns:   make(chan net.Conn, memoryListenerBacklog),
+		closed:  make(chan struct{}),
+	}
+	n.listeners[addr.String()] = ln
+	return ln, nil
+}
+
+// DialContext connects to the listener at the provided address. The network argument is ignored.
+func (n *MemoryNetwork) DialContext(ctx context.Context, _ string, address string) (net.Conn, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, &net.OpError{Op: "dial", Net: memoryNetworkName, Err: err}
+	}
+
+	n.mutex.Lock()
+	ln, ok := n.listeners[address]
+	localAddr := memoryAddr(net.JoinHostPort("127.0.0.1", strconv.Itoa(n.allocatePortLocked("127.0.0.1"))))
+	n.mutex.Unlock()
+	if !ok {
+		return nil, &net.OpError{Op: "dial", Net: memoryNetworkName, Addr: memoryAddr(address), Err: errConnectionRefused}
+	}
+
+	clientSide, serverSide := net.Pipe()
+	client := &memoryConn{Conn: clientSide, local: localAddr, remote: ln.addr}
+	server := &memoryConn{Conn: serverSide, local: ln.addr, remote: localAddr}
+	if !ln.enqueue(server) {
+		_ = client.Close()
+		_ = server.Close()
+		return nil, &net.OpError{Op: "dial", Net: memoryNetworkName, Addr: ln.addr, Err: errConnectionRefused}
+	}
+	return client, nil
+}
+
+// allocatePortLocked returns a port not in use by a listener on host
+func (n *MemoryNetwork) allocatePortLocked(host string) int {
+	for {
+		port := n.nextEphemeral
+		n.nextEphemeral++
+		if n.nextEphemeral > 65535 {
+			n.nextEphemeral = firstEphemeralMemoryPort
+		}
+		if _, ok := n.listeners[net.JoinHostPort(host, strconv.Itoa(port))]; !ok {
+			return port
+		}
+	}
+}
+
+func (n *MemoryNetwork) removeListener(ln *memoryListener) {
+	n.mutex.Lock()
+	defer n.mutex.Unlock()
+	if n.listeners[ln.addr.String()] == ln {
+		delete(n.listeners, ln.addr.String())
+	}
+}
+
+type memoryListener struct {
+	network *MemoryNetwork
+	addr    memoryAddr
+
+	// mutex guards the send to conns against close
+	mutex     sync.Mutex
+	conns     chan net.Conn
+	closed    chan struct{}
+	closeOnce sync.Once
+}
+
+var _ net.Listener = (*memoryListener)(nil)
+
+func (l *memoryListener) enqueue(conn net.Conn) bool {
+	l.mutex.Lock()
+	defer l.mutex.Unlock()
+	select {
+	case <-l.closed:
+		return false
+	case l.conns <- conn:
+		return true
+	default:
+		// Backlog full
+		return false
+	}
+}
+
+func (l *memoryListener) Accept() (net.Conn, error) {
+	select {
+	case conn := <-l.conns:
+		return conn, nil
+	case <-l.closed:
+		return nil, &net.OpError{Op: "accept", Net: memoryNetworkName, Addr: l.addr, Err: net.ErrClosed}
+	}
+}
+
+func (l *memoryListener) Close() error {
+	l.closeOnce.Do(func() {
+		l.network.removeListener(l)
+
+		l.mutex.Lock()
+		close(l.closed)
+		l.mutex.Unlock()
+
+		// Refuse the connections never accepted
+		for {
+			select {
+			case conn := <-l.conns:
+				_ = conn.Close()
+			default:
+				return
+			}
+		}
+	})
+	return nil
+}
+
+func (l *memoryListener) Addr() net.Addr {
+	return l.addr
+}
+
+// memoryConn is a net.Pipe with addresses
+type memoryConn struct {
+	net.Conn
+	local  net.Addr
+	remote net.Addr
+}
+
+func (c *memoryConn) LocalAddr() net.Addr {
+	return c.local
+}
+
+func (c *memoryConn) RemoteAddr() net.Addr {
+	return c.remote
+}
+
+type memoryAddr string
+
+func (a memoryAddr) Network() string {
+	return memoryNetworkName
+}
+
+func (a memoryAddr) String() string {
+	return string(a)
+}
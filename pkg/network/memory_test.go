@@ -0,0 +1,176 @@
+/*
+Copyright 2026 The Knative Authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package network_test
+
+import (
+	"context"
+	"errors"
+	"net"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+	"knative.dev/pkg/logging"
+
+	"knative.dev/control-protocol/pkg/certificates"
+	"knative.dev/control-protocol/pkg/network"
+	"knative.dev/control-protocol/pkg/test"
+)
+
+func TestInMemoryServerToClient(t *testing.T) {
+	_, server, _, client := test.MustSetupInMemoryControlPair(t)
+	test.SendReceiveTest(t, server, client)
+}
+
+func TestInMemoryClientToServer(t *testing.T) {
+	_, server, _, client := test.MustSetupInMemoryControlPair(t)
+	test.SendReceiveTest(t, client, server)
+}
+
+func TestMemoryNetwork_ListenAndDial(t *testing.T) {
+	memoryNetwork := network.NewMemoryNetwork()
+
+	ln, err := memoryNetwork.Listen("10.0.0.1:9000")
+	require.NoError(t, err)
+	require.Equal(t, "10.0.0.1:9000", ln.Addr().String())
+
+	_, err = memoryNetwork.Listen("10.0.0.1:9000")
+	require.Error(t, err)
+
+	_, err = memoryNetwork.DialContext(context.TODO(), "tcp", "10.0.0.2:9000")
+	require.Error(t, err)
+
+	client, err := memoryNetwork.DialContext(context.TODO(), "tcp", "10.0.0.1:9000")
+	require.NoError(t, err)
+	server, err := ln.Accept()
+	require.NoError(t, err)
+	require.Equal(t, client.LocalAddr().String(), server.RemoteAddr().String())
+	require.Equal(t, "10.0.0.1:9000", client.RemoteAddr().String())
+
+	go func() {
+		_, _ = client.Write([]byte("Funky!"))
+	}()
+	b := make([]byte, 6)
+	_, err = server.Read(b)
+	require.NoError(t, err)
+	require.Equal(t, "Funky!", string(b))
+
+	require.NoError(t, ln.Close())
+	_, err = ln.Accept()
+	require.True(t, errors.Is(err, net.ErrClosed))
+	_, err = memoryNetwork.DialContext(context.TODO(), "tcp", "10.0.0.1:9000")
+	require.Error(t, err)
+
+	// The address can be reused once the listener is closed
+	ln, err = memoryNetwork.Listen("10.0.0.1:9000")
+	require.NoError(t, err)
+	require.NoError(t, ln.Close())
+}
+
+func TestMemoryNetwork_ClientReconnectsToRestartedServer(t *testing.T) {
+	logger, _ := zap.NewDevelopment()
+	ctx, cancelFn := context.WithCancel(logging.WithLogger(context.TODO(), logger.Sugar()))
+	t.Cleanup(cancelFn)
+
+	memoryNetwork := network.NewMemoryNetwork()
+
+	ln, err := memoryNetwork.Listen("10.0.0.1:9000")
+	require.NoError(t, err)
+	serverCtx, serverCancelFn := context.WithCancel(ctx)
+	server, err := network.StartInsecureControlServer(serverCtx, network.WithListener(ln))
+	require.NoError(t, err)
+	require.Equal(t, 9000, server.ListeningPort())
+
+	client, err := network.StartControlClient(ctx, memoryNetwork, "10.0.0.1:9000")
+	require.NoError(t, err)
+	test.SendReceiveTest(t, client, server)
+
+	serverCancelFn()
+	<-server.ClosedCh()
+
+	ln, err = memoryNetwork.Listen("10.0.0.1:9000")
+	require.NoError(t, err)
+	server, err = network.StartInsecureControlServer(ctx, network.WithListener(ln))
+	require.NoError(t, err)
+	test.SendReceiveTest(t, client, server)
+}
+
+func TestMemoryNetwork_TLS(t *testing.T) {
+	logger, _ := zap.NewDevelopment()
+	ctx, cancelFn := context.WithCancel(logging.WithLogger(context.TODO(), logger.Sugar()))
+	t.Cleanup(cancelFn)
+
+	serverTLSConf, clientTLSDialer := test.MustGenerateTestTLSConf(t, ctx)
+	memoryNetwork := network.NewMemoryNetwork()
+
+	ln, err := memoryNetwork.Listen("10.0.0.1:9000")
+	require.NoError(t, err)
+	server, err := network.StartControlServer(ctx, serverTLSConf, network.WithListener(ln))
+	require.NoError(t, err)
+
+	client, err := network.StartControlClient(ctx, network.NewTLSDialer(memoryNetwork, clientTLSDialer.Config), "10.0.0.1:9000")
+	require.NoError(t, err)
+	test.SendReceiveTest(t, client, server)
+	test.SendReceiveTest(t, server, client)
+}
+
+func TestMemoryNetwork_ControlListener(t *testing.T) {
+	logger, _ := zap.NewDevelopment()
+	ctx, cancelFn := context.WithCancel(logging.WithLogger(context.TODO(), logger.Sugar()))
+	t.Cleanup(cancelFn)
+
+	serverTLSConf, clientTLSDialer := test.MustGenerateTestTLSConf(t, ctx)
+	memoryNetwork := network.NewMemoryNetwork()
+
+	ln, err := memoryNetwork.Listen("10.0.0.1:9000")
+	require.NoError(t, err)
+	listenerCtx, listenerCancelFn := context.WithCancel(ctx)
+	accepted := make(chan *network.AcceptedConnection, 10)
+	listener, err := network.StartControlListener(listenerCtx, serverTLSConf, func(conn *network.AcceptedConnection) {
+		accepted <- conn
+	}, network.WithListener(ln))
+	require.NoError(t, err)
+	require.Equal(t, "10.0.0.1:9000", listener.Addr().String())
+
+	// The connections are served concurrently
+	dialer := network.NewTLSDialer(memoryNetwork, clientTLSDialer.Config)
+	clientA, err := network.StartControlClient(ctx, dialer, "10.0.0.1:9000")
+	require.NoError(t, err)
+	connA := <-accepted
+	clientB, err := network.StartControlClient(ctx, dialer, "10.0.0.1:9000")
+	require.NoError(t, err)
+	connB := <-accepted
+
+	require.NotEqual(t, connA.RemoteAddr, connB.RemoteAddr)
+	require.Len(t, connA.PeerCertificates, 1)
+	require.NoError(t, connA.PeerCertificates[0].VerifyHostname(certificates.DataPlaneRoutingName("")))
+
+	test.SendReceiveTest(t, clientA, connA.Service)
+	test.SendReceiveTest(t, connB.Service, clientB)
+	test.SendReceiveTest(t, connA.Service, clientA)
+
+	// Accepted connections are not reconnected: the client dials a new one
+	connA.Close()
+	<-connA.ClosedCh()
+	connC := <-accepted
+	test.SendReceiveTest(t, clientA, connC.Service)
+
+	listenerCancelFn()
+	<-listener.ClosedCh()
+	<-connB.ClosedCh()
+	<-connC.ClosedCh()
+}
@@ -55,6 +55,41 @@ func (q *unboundedMessageQueue) blockingPoll(ctx context.Context) *ctrl.Message
 	return msg
 }
 
+// blockingPollFunc is like blockingPoll, but the message to return is chosen by pick.
+// pick is invoked with the queue lock held and returns the chosen message and its index in the queue,
+// or -1 if the message doesn't come from the queue. If pick returns nil, this method waits for the next signal.
+func (q *unboundedMessageQueue) blockingPollFunc(ctx context.Context, pick func(queue []*ctrl.Message) (*ctrl.Message, int)) *ctrl.Message {
+	q.cond.L.Lock()
+	defer q.cond.L.Unlock()
+	for {
+		if ctx.Err() != nil { // Context closed, quit
+			return nil
+		}
+		msg, i := pick(q.queue)
+		if msg != nil {
+			if i >= 0 {
+				q.queue = append(q.queue[:i], q.queue[i+1:]...)
+			}
+			return msg
+		}
+		q.cond.Wait()
+	}
+}
+
+// signal wakes up the pollers, so they can re-evaluate their conditions.
+// The lock is acquired to make sure no poller is between the evaluation of its condition and the wait.
+func (q *unboundedMessageQueue) signal() {
+	q.cond.L.Lock()
+	q.cond.L.Unlock()
+	q.cond.Broadcast()
+}
+
+func (q *unboundedMessageQueue) len() int {
+	q.cond.L.Lock()
+	defer q.cond.L.Unlock()
+	return len(q.queue)
+}
+
 func (q *unboundedMessageQueue) unblockPoll() {
 	q.cond.Broadcast()
 }
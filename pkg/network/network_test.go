@@ -20,6 +20,7 @@ import (
 	"context"
 	"errors"
 	"fmt"
+	"net"
 	"sync"
 	"testing"
 	"time"
@@ -32,6 +33,7 @@ import (
 
 	control "knative.dev/control-protocol/pkg"
 	"knative.dev/control-protocol/pkg/network"
+	"knative.dev/control-protocol/pkg/service"
 	"knative.dev/control-protocol/pkg/test"
 )
 
@@ -260,3 +262,97 @@ func TestManyMessages(t *testing.T) {
 
 	logging.FromContext(ctx).Infof("Processed: %d", processed.Load())
 }
+
+func TestFlowControlSlowHandlerSlowsSender(t *testing.T) {
+	logger, _ := zap.NewDevelopment()
+	ctx := logging.WithLogger(context.TODO(), logger.Sugar())
+
+	clientCtx, clientCancelFn := context.WithCancel(ctx)
+	serverCtx, serverCancelFn := context.WithCancel(ctx)
+	t.Cleanup(clientCancelFn)
+
+	controlServer, err := network.StartInsecureControlServer(serverCtx, network.WithPort(0), network.WithFlowControl(network.FlowControl{Messages: 2}))
+	require.NoError(t, err)
+	t.Cleanup(func() {
+		serverCancelFn()
+		<-controlServer.ClosedCh()
+	})
+
+	negotiated := make(chan struct{})
+	var negotiatedOnce sync.Once
+	client, err := network.StartControlClient(clientCtx, &net.Dialer{
+		KeepAlive: network.KeepAlive,
+		Deadline:  time.Time{},
+	}, fmt.Sprintf("127.0.0.1:%d", controlServer.ListeningPort()), network.WithClientFlowControl(network.FlowControl{
+		Observer: func(state network.CreditState) {
+			if state.Negotiated {
+				negotiatedOnce.Do(func() { close(negotiated) })
+			}
+		},
+	}))
+	require.NoError(t, err)
+
+	<-negotiated
+
+	inFlight := atomic.NewInt32(0)
+	maxInFlight := atomic.NewInt32(0)
+	controlServer.MessageHandler(control.MessageHandlerFunc(func(ctx context.Context, message control.ServiceMessage) {
+		n := inFlight.Inc()
+		for {
+			max := maxInFlight.Load()
+			if n <= max || maxInFlight.CAS(max, n) {
+				break
+			}
+		}
+		time.Sleep(20 * time.Millisecond)
+		inFlight.Dec()
+		message.Ack()
+	}))
+
+	var wg sync.WaitGroup
+	wg.Add(10)
+	for i := 0; i < 10; i++ {
+		go func() {
+			defer wg.Done()
+			assert.NoError(t, client.SendAndWaitForAck(1, test.MockPayload("Funky!")))
+		}()
+	}
+	wg.Wait()
+
+	require.LessOrEqual(t, maxInFlight.Load(), int32(2))
+	require.Eventually(t, func() bool {
+		return controlServer.CreditState().PendingMessages == 0
+	}, time.Second, 10*time.Millisecond)
+}
+
+func TestBatchedAcks(t *testing.T) {
+	logger, _ := zap.NewDevelopment()
+	ctx := logging.WithLogger(context.TODO(), logger.Sugar())
+
+	clientCtx, clientCancelFn := context.WithCancel(ctx)
+	serverCtx, serverCancelFn := context.WithCancel(ctx)
+	t.Cleanup(clientCancelFn)
+
+	controlServer, err := network.StartInsecureControlServer(serverCtx, network.WithPort(0), network.WithServiceOptions(service.WithBatchedAcks(10*time.Millisecond, 5)))
+	require.NoError(t, err)
+	t.Cleanup(func() {
+		serverCancelFn()
+		<-controlServer.ClosedCh()
+	})
+
+	client, err := network.StartControlClient(clientCtx, &net.Dialer{
+		KeepAlive: network.KeepAlive,
+		Deadline:  time.Time{},
+	}, fmt.Sprintf("127.0.0.1:%d", controlServer.ListeningPort()))
+	require.NoError(t, err)
+
+	var wg sync.WaitGroup
+	wg.Add(23)
+	for i := 0; i < 23; i++ {
+		go func() {
+			defer wg.Done()
+			assert.NoError(t, client.SendAndWaitForAck(1, test.MockPayload("Funky!")))
+		}()
+	}
+	wg.Wait()
+}
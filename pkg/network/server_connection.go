@@ -28,6 +28,7 @@ import (
 	"knative.dev/pkg/logging"
 
 	ctrl "knative.dev/control-protocol/pkg"
+	"knative.dev/control-protocol/pkg/metrics"
 	"knative.dev/control-protocol/pkg/service"
 )
 
@@ -36,8 +37,12 @@ var listenConfig = net.ListenConfig{
 }
 
 type ControlServerOptions struct {
-	port         int
-	listenConfig *net.ListenConfig
+	port               int
+	listenConfig       *net.ListenConfig
+	listener           net.Listener
+	flowControl        FlowControl
+	serviceOptions     []service.ServiceOption
+	connectionWrappers []ctrl.ConnectionWrapper
 }
 
 type ControlServerOption func(*ControlServerOptions)
@@ -48,10 +53,50 @@ func WithPort(port int) ControlServerOption {
 	}
 }
 
+// WithListener makes the server accept the connections from the provided listener, rather than listening on WithPort.
+// The server takes ownership of the listener, closing it when the context is done.
+// This can be used with MemoryNetwork.Listen to run the server without sockets.
+func WithListener(listener net.Listener) ControlServerOption {
+	return func(options *ControlServerOptions) {
+		options.listener = listener
+	}
+}
+
+// WithFlowControl enables the credit based flow control of the inbound messages.
+// Look at FlowControl for more details.
+func WithFlowControl(flowControl FlowControl) ControlServerOption {
+	return func(options *ControlServerOptions) {
+		options.flowControl = flowControl
+	}
+}
+
+// WithServiceOptions configures the options of the control service
+func WithServiceOptions(opts ...service.ServiceOption) ControlServerOption {
+	return func(options *ControlServerOptions) {
+		options.serviceOptions = append(options.serviceOptions, opts...)
+	}
+}
+
+// WithConnectionWrappers wraps the connection used by the control service, in the provided order
+func WithConnectionWrappers(wrappers ...ctrl.ConnectionWrapper) ControlServerOption {
+	return func(options *ControlServerOptions) {
+		options.connectionWrappers = append(options.connectionWrappers, wrappers...)
+	}
+}
+
+// listen returns the configured listener, or listens on the configured port
+func (opts *ControlServerOptions) listen(ctx context.Context) (net.Listener, error) {
+	if opts.listener != nil {
+		return opts.listener, nil
+	}
+	return opts.listenConfig.Listen(ctx, "tcp", fmt.Sprintf(":%d", opts.port))
+}
+
 type ControlServer struct {
 	ctrl.Service
 	closedCh <-chan struct{}
 	port     int
+	flow     *flowController
 }
 
 // ClosedCh returns a channel which is closed after the server stopped listening
@@ -64,13 +109,18 @@ func (cs *ControlServer) ListeningPort() int {
 	return cs.port
 }
 
+// CreditState returns a snapshot of the flow control state of the current connection
+func (cs *ControlServer) CreditState() CreditState {
+	return cs.flow.state()
+}
+
 func StartInsecureControlServer(ctx context.Context, options ...ControlServerOption) (*ControlServer, error) {
 	return StartControlServer(ctx, nil, options...)
 }
 
 func StartControlServer(ctx context.Context, tlsConfigLoader func() (*tls.Config, error), options ...ControlServerOption) (*ControlServer, error) {
 	opts := ControlServerOptions{
-		port:         9000,
+		port:         DefaultControlPort,
 		listenConfig: &listenConfig,
 	}
 
@@ -78,7 +128,7 @@ func StartControlServer(ctx context.Context, tlsConfigLoader func() (*tls.Config
 		fn(&opts)
 	}
 
-	ln, err := opts.listenConfig.Listen(ctx, "tcp", fmt.Sprintf(":%d", opts.port))
+	ln, err := opts.listen(ctx)
 	if err != nil {
 		return nil, err
 	}
@@ -91,8 +141,8 @@ func StartControlServer(ctx context.Context, tlsConfigLoader func() (*tls.Config
 		return nil, fmt.Errorf("cannot parse the listening port: %w", err)
 	}
 
-	tcpConn := newServerTcpConnection(ctx, ln, tlsConfigLoader)
-	ctrlService := service.NewService(ctx, tcpConn)
+	tcpConn := newServerTcpConnection(ctx, ln, tlsConfigLoader, opts.flowControl)
+	ctrlService := service.NewService(ctx, wrapConnection(tcpConn, opts.connectionWrappers), opts.serviceOptions...)
 
 	closedServerCh := make(chan struct{})
 
@@ -102,6 +152,7 @@ func StartControlServer(ctx context.Context, tlsConfigLoader func() (*tls.Config
 		Service:  ctrlService,
 		closedCh: closedServerCh,
 		port:     port,
+		flow:     tcpConn.flow,
 	}
 
 	return ctrlServer, nil
@@ -114,13 +165,14 @@ type serverTcpConnection struct {
 	tlsConfigLoader func() (*tls.Config, error)
 }
 
-func newServerTcpConnection(ctx context.Context, listener net.Listener, tlsConfigLoader func() (*tls.Config, error)) *serverTcpConnection {
+func newServerTcpConnection(ctx context.Context, listener net.Listener, tlsConfigLoader func() (*tls.Config, error), flowControl FlowControl) *serverTcpConnection {
 	c := &serverTcpConnection{
 		baseTcpConnection: baseTcpConnection{
 			ctx:                 ctx,
 			logger:              logging.FromContext(ctx),
 			writeQueue:          newUnboundedMessageQueue(),
 			readQueue:           newUnboundedMessageQueue(),
+			flow:                newFlowController(flowControl),
 			unrecoverableErrors: make(chan error, 10),
 		},
 		listener:        listener,
@@ -130,6 +182,8 @@ func newServerTcpConnection(ctx context.Context, listener net.Listener, tlsConfi
 }
 
 func (t *serverTcpConnection) startAcceptPolling(closedServerChannel chan struct{}) {
+	go t.sampleQueueDepth(queueDepthSamplingInterval)
+
 	// We have 2 goroutines:
 	// * One pools the t.ctx and closes the listener
 	// * One polls the listener to accept new conns. When done, it closes the connection channels
@@ -153,6 +207,7 @@ func (t *serverTcpConnection) startAcceptPolling(closedServerChannel chan struct
 }
 
 func (t *serverTcpConnection) listenLoop() {
+	accepted := false
 	for {
 		select {
 		case <-t.ctx.Done():
@@ -175,6 +230,10 @@ func (t *serverTcpConnection) listenLoop() {
 				conn = tls.Server(conn, tlsConf)
 			}
 			t.logger.Debugf("Accepting new control connection from %s", conn.RemoteAddr())
+			if accepted {
+				metrics.RecordReconnect(t.ctx, metrics.ServerRole)
+			}
+			accepted = true
 			t.consumeConnection(conn)
 		}
 	}
@@ -4,34 +4,31 @@ import (
 	"crypto/tls"
 	"crypto/x509"
 	"io/ioutil"
+	"path/filepath"
 
 	"knative.dev/control-protocol/pkg/certificates"
 )
 
 const (
-	baseCertsPath = "/etc/control-secret"
-
-	publicCertPath = baseCertsPath + "/" + certificates.SecretCertKey
-	pkPath         = baseCertsPath + "/" + certificates.SecretPKKey
-	caCertPath     = baseCertsPath + "/" + certificates.SecretCaCertKey
+	// DefaultCertsDir is the directory where the control secret is mounted
+	DefaultCertsDir = "/etc/control-secret"
 )
 
 func LoadServerTLSConfigFromFile() (*tls.Config, error) {
-	cert, err := tls.LoadX509KeyPair(publicCertPath, pkPath)
-	if err != nil {
-		return nil, err
-	}
+	return LoadServerTLSConfigFromDir(DefaultCertsDir)
+}
 
-	caCert, err := ioutil.ReadFile(caCertPath)
+// LoadServerTLSConfigFromDir loads the server tls configuration from the certificates stored in dir,
+// using the same file names of the control secret.
+func LoadServerTLSConfigFromDir(dir string) (*tls.Config, error) {
+	cert, caCertPool, err := loadCertificatesFromDir(dir)
 	if err != nil {
 		return nil, err
 	}
 
-	certPool := x509.NewCertPool()
-	certPool.AppendCertsFromPEM(caCert)
 	conf := &tls.Config{
 		Certificates: []tls.Certificate{cert},
-		ClientCAs:    certPool,
+		ClientCAs:    caCertPool,
 		ClientAuth:   tls.RequireAndVerifyClientCert,
 		VerifyConnection: func(cs tls.ConnectionState) error {
 			err := cs.PeerCertificates[0].VerifyHostname(certificates.DataPlaneRoutingName(""))
@@ -43,23 +40,38 @@ func LoadServerTLSConfigFromFile() (*tls.Config, error) {
 }
 
 func LoadClientTLSConfigFromFile() (*tls.Config, error) {
-	cert, err := tls.LoadX509KeyPair(publicCertPath, pkPath)
-	if err != nil {
-		return nil, err
-	}
+	return LoadClientTLSConfigFromDir(DefaultCertsDir, certificates.DataPlaneUserName("myns"))
+}
 
-	caCert, err := ioutil.ReadFile(caCertPath)
+// LoadClientTLSConfigFromDir loads the client tls configuration from the certificates stored in dir,
+// using the same file names of the control secret, expecting serverName in the server certificate.
+func LoadClientTLSConfigFromDir(dir string, serverName string) (*tls.Config, error) {
+	cert, caCertPool, err := loadCertificatesFromDir(dir)
 	if err != nil {
 		return nil, err
 	}
 
-	certPool := x509.NewCertPool()
-	certPool.AppendCertsFromPEM(caCert)
 	conf := &tls.Config{
 		Certificates: []tls.Certificate{cert},
-		RootCAs:      certPool,
-		ServerName:   certificates.DataPlaneUserName("myns"),
+		RootCAs:      caCertPool,
+		ServerName:   serverName,
 	}
 
 	return conf, nil
 }
+
+func loadCertificatesFromDir(dir string) (tls.Certificate, *x509.CertPool, error) {
+	cert, err := tls.LoadX509KeyPair(filepath.Join(dir, certificates.SecretCertKey), filepath.Join(dir, certificates.SecretPKKey))
+	if err != nil {
+		return tls.Certificate{}, nil, err
+	}
+
+	caCert, err := ioutil.ReadFile(filepath.Join(dir, certificates.SecretCaCertKey))
+	if err != nil {
+		return tls.Certificate{}, nil, err
+	}
+
+	certPool := x509.NewCertPool()
+	certPool.AppendCertsFromPEM(caCert)
+	return cert, certPool, nil
+}
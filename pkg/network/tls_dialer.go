@@ -0,0 +1,62 @@
+/*
+Copyright 2026 The Knative Authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package network
+
+import (
+	"context"
+	"crypto/tls"
+	"net"
+)
+
+// NewTLSDialer creates a Dialer establishing tls connections on top of the connections dialed by base.
+// Unlike tls.Dialer, this works with any Dialer, e.g. MemoryNetwork.
+func NewTLSDialer(base Dialer, config *tls.Config) Dialer {
+	return &tlsDialer{
+		base:   base,
+		config: config,
+	}
+}
+
+type tlsDialer struct {
+	base   Dialer
+	config *tls.Config
+}
+
+func (d *tlsDialer) DialContext(ctx context.Context, network string, address string) (net.Conn, error) {
+	rawConn, err := d.base.DialContext(ctx, network, address)
+	if err != nil {
+		return nil, err
+	}
+
+	config := d.config
+	if config.ServerName == "" {
+		// Same default of tls.Dialer
+		host, _, err := net.SplitHostPort(address)
+		if err != nil {
+			host = address
+		}
+		config = config.Clone()
+		config.ServerName = host
+	}
+
+	conn := tls.Client(rawConn, config)
+	if err := conn.HandshakeContext(ctx); err != nil {
+		_ = rawConn.Close()
+		return nil, err
+	}
+	return conn, nil
+}
@@ -0,0 +1,146 @@
+/*
+Copyright 2026 The Knative Authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package reconciler
+
+import (
+	"context"
+	"encoding"
+	"fmt"
+	"sort"
+	"strings"
+	"sync"
+
+	control "knative.dev/control-protocol/pkg"
+)
+
+// Broadcaster is implemented by the pools which can send a message to all the connected hosts of a key.
+// The pools returned by NewControlPlaneConnectionPool and NewLeaderAwareConnectionPool implement it.
+type Broadcaster interface {
+	Broadcast(ctx context.Context, key string, opcode control.OpCode, payload encoding.BinaryMarshaler) *BroadcastResult
+}
+
+// BroadcastResult contains the outcome of a broadcast, per host
+type BroadcastResult struct {
+	// Results contains the error returned sending to every host, nil if the host acked the message
+	Results map[string]error
+}
+
+// Total returns the number of hosts the message was sent to
+func (r *BroadcastResult) Total() int {
+	return len(r.Results)
+}
+
+// SucceededHosts returns the sorted hosts which acked the message
+func (r *BroadcastResult) SucceededHosts() []string {
+	return r.hosts(func(err error) bool { return err == nil })
+}
+
+// FailedHosts returns the sorted hosts which failed to ack the message
+func (r *BroadcastResult) FailedHosts() []string {
+	return r.hosts(func(err error) bool { return err != nil })
+}
+
+// AllSucceeded returns true if every host acked the message. It's true as well if there are no hosts.
+func (r *BroadcastResult) AllSucceeded() bool {
+	for _, err := range r.Results {
+		if err != nil {
+			return false
+		}
+	}
+	return true
+}
+
+// QuorumReached returns true if the strict majority of the hosts acked the message
+func (r *BroadcastResult) QuorumReached() bool {
+	return len(r.SucceededHosts())*2 > r.Total()
+}
+
+// Err returns an error listing the failed hosts, or nil if every host acked the message
+func (r *BroadcastResult) Err() error {
+	failed := r.FailedHosts()
+	if len(failed) == 0 {
+		return nil
+	}
+	errs := make([]string, 0, len(failed))
+	for _, host := range failed {
+		errs = append(errs, fmt.Sprintf("%s: %v", host, r.Results[host]))
+	}
+	return fmt.Errorf("%d/%d hosts failed: %s", len(failed), r.Total(), strings.Join(errs, "; "))
+}
+
+// String returns a summary of the result, like "3/5 succeeded"
+func (r *BroadcastResult) String() string {
+	return fmt.Sprintf("%d/%d succeeded", len(r.SucceededHosts()), r.Total())
+}
+
+func (r *BroadcastResult) hosts(filter func(error) bool) []string {
+	var hosts []string
+	for host, err := range r.Results {
+		if filter(err) {
+			hosts = append(hosts, host)
+		}
+	}
+	sort.Strings(hosts)
+	return hosts
+}
+
+// Broadcast sends the message to all the connected hosts of key concurrently, waiting for all the acks.
+// The deadline of ctx is shared by all the sends: when ctx is done, the hosts which didn't ack fail with the ctx error.
+func (cc *controlPlaneConnectionPoolImpl) Broadcast(ctx context.Context, key string, opcode control.OpCode, payload encoding.BinaryMarshaler) *BroadcastResult {
+	return broadcast(ctx, cc.GetServices(key), opcode, payload)
+}
+
+// broadcast sends the message to all the services concurrently, waiting for all the acks
+func broadcast(ctx context.Context, services map[string]control.Service, opcode control.OpCode, payload encoding.BinaryMarshaler) *BroadcastResult {
+	result := &BroadcastResult{
+		Results: make(map[string]error, len(services)),
+	}
+	var resultLock sync.Mutex
+	var wg sync.WaitGroup
+	for host, svc := range services {
+		wg.Add(1)
+		go func(host string, svc control.Service) {
+			defer wg.Done()
+			err := sendWithDeadline(ctx, svc, opcode, payload)
+
+			resultLock.Lock()
+			result.Results[host] = err
+			resultLock.Unlock()
+		}(host, svc)
+	}
+	wg.Wait()
+
+	return result
+}
+
+// sendWithDeadline sends the message, returning when either the message is acked or ctx is done
+func sendWithDeadline(ctx context.Context, svc control.Service, opcode control.OpCode, payload encoding.BinaryMarshaler) error {
+	if sender, ok := svc.(control.ContextSender); ok {
+		return sender.SendAndWaitForAckWithContext(ctx, opcode, payload)
+	}
+
+	errCh := make(chan error, 1)
+	go func() {
+		errCh <- svc.SendAndWaitForAck(opcode, payload)
+	}()
+	select {
+	case err := <-errCh:
+		return err
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
@@ -0,0 +1,99 @@
+/*
+Copyright 2026 The Knative Authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package reconciler_test
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+
+	control "knative.dev/control-protocol/pkg"
+	"knative.dev/control-protocol/pkg/network"
+	"knative.dev/control-protocol/pkg/reconciler"
+	"knative.dev/control-protocol/pkg/test"
+)
+
+func TestBroadcastResult(t *testing.T) {
+	failure := errors.New("failure")
+	result := &reconciler.BroadcastResult{Results: map[string]error{
+		"10.0.0.3:9000": nil,
+		"10.0.0.1:9000": nil,
+		"10.0.0.2:9000": failure,
+	}}
+
+	require.Equal(t, 3, result.Total())
+	require.Equal(t, []string{"10.0.0.1:9000", "10.0.0.3:9000"}, result.SucceededHosts())
+	require.Equal(t, []string{"10.0.0.2:9000"}, result.FailedHosts())
+	require.False(t, result.AllSucceeded())
+	require.True(t, result.QuorumReached())
+	require.EqualError(t, result.Err(), "1/3 hosts failed: 10.0.0.2:9000: failure")
+	require.Equal(t, "2/3 succeeded", result.String())
+
+	empty := &reconciler.BroadcastResult{}
+	require.True(t, empty.AllSucceeded())
+	require.False(t, empty.QuorumReached())
+	require.NoError(t, empty.Err())
+}
+
+func TestConnectionPool_Broadcast(t *testing.T) {
+	ctx, cancelFn := context.WithCancel(context.TODO())
+	t.Cleanup(cancelFn)
+
+	memoryNetwork := network.NewMemoryNetwork()
+	handlers := map[string]control.MessageHandlerFunc{
+		"10.0.0.1:9000": func(ctx context.Context, message control.ServiceMessage) {
+			message.Ack()
+		},
+		"10.0.0.2:9000": func(ctx context.Context, message control.ServiceMessage) {
+			message.AckWithError(errors.New("cannot apply"))
+		},
+		"10.0.0.3:9000": func(ctx context.Context, message control.ServiceMessage) {
+			// Never acks
+		},
+	}
+	var hosts []string
+	for address, handler := range handlers {
+		ln, err := memoryNetwork.Listen(address)
+		require.NoError(t, err)
+		server, err := network.StartInsecureControlServer(ctx, network.WithListener(ln))
+		require.NoError(t, err)
+		server.MessageHandler(handler)
+		hosts = append(hosts, address)
+	}
+
+	connectionPool := reconciler.NewInsecureControlPlaneConnectionPool(reconciler.WithDialer(memoryNetwork))
+	t.Cleanup(func() {
+		connectionPool.Close(ctx)
+	})
+	_, err := connectionPool.ReconcileConnections(ctx, "hello", hosts, nil, nil)
+	require.NoError(t, err)
+
+	broadcastCtx, broadcastCancelFn := context.WithTimeout(ctx, 500*time.Millisecond)
+	defer broadcastCancelFn()
+	result := connectionPool.(reconciler.Broadcaster).Broadcast(broadcastCtx, "hello", 1, test.SomeMockPayload)
+
+	require.Equal(t, 3, result.Total())
+	require.Equal(t, []string{"10.0.0.1:9000"}, result.SucceededHosts())
+	require.Equal(t, []string{"10.0.0.2:9000", "10.0.0.3:9000"}, result.FailedHosts())
+	require.EqualError(t, result.Results["10.0.0.2:9000"], "cannot apply")
+	require.ErrorIs(t, result.Results["10.0.0.3:9000"], context.DeadlineExceeded)
+	require.False(t, result.QuorumReached())
+	require.Equal(t, "1/3 succeeded", result.String())
+}
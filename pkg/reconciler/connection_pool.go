@@ -20,17 +20,23 @@ import (
 	"context"
 	"fmt"
 	"net"
+	"sort"
+	"strings"
 	"sync"
 	"time"
 
 	"knative.dev/pkg/logging"
 
 	control "knative.dev/control-protocol/pkg"
+	"knative.dev/control-protocol/pkg/metrics"
 	"knative.dev/control-protocol/pkg/network"
 )
 
 const (
 	keepAlive = 30 * time.Second
+
+	defaultMaxParallelDials       = 10
+	defaultConnectionCycleTimeout = 30 * time.Second
 )
 
 type ControlPlaneConnectionPool interface {
@@ -44,21 +50,58 @@ type ControlPlaneConnectionPool interface {
 	DialControlService(ctx context.Context, key string, host string) (string, control.Service, error)
 }
 
-var _ ControlPlaneConnectionPool = (*controlPlaneConnectionPoolImpl)(nil)
+// ConnectionStateObserver is implemented by the pools tracking the state of their connections.
+// The pools returned by NewControlPlaneConnectionPool and NewLeaderAwareConnectionPool implement it.
+type ConnectionStateObserver interface {
+	// GetConnectionState returns the state of the connection to host for key, false if the pool has no such connection
+	GetConnectionState(key string, host string) (network.ConnectionState, bool)
+	// SubscribeConnectionState registers callback for the state changes of all the connections
+	SubscribeConnectionState(callback ConnectionStateChangeCallback) (unsubscribe func())
+}
+
+// ConnectionStateChangeCallback is invoked every time the connection to a host of the pool changes its state.
+// When the state is network.ConnectionBroken or network.ConnectionClosed, the connection was removed from the pool.
+// Callbacks are invoked synchronously, so they must not block.
+type ConnectionStateChangeCallback func(key string, host string, state network.ConnectionState)
+
+var (
+	_ ControlPlaneConnectionPool = (*controlPlaneConnectionPoolImpl)(nil)
+	_ ConnectionStateObserver    = (*controlPlaneConnectionPoolImpl)(nil)
+	_ Broadcaster                = (*controlPlaneConnectionPoolImpl)(nil)
+	_ CredentialsRotator         = (*controlPlaneConnectionPoolImpl)(nil)
+)
 
 type controlPlaneConnectionPoolImpl struct {
 	tlsDialerFactory TLSDialerFactory
 	baseDialOptions  *net.Dialer
+	// dialer, if set, replaces baseDialOptions
+	dialer network.Dialer
+
+	serviceWrapperFactories []func(key string, host string) control.ServiceWrapper
+	controlClientOptions    []network.ControlClientOption
+	maxParallelDials        int
+	connectionCycleTimeout  time.Duration
+	messageKeyRouter        MessageKeyRouter
 
-	serviceWrapperFactories []control.ServiceWrapper
+	// currentDialer is the last dialer built, used by all the control clients when they reconnect
+	currentDialerLock sync.RWMutex
+	currentDialer     network.Dialer
 
 	connsLock sync.Mutex
-	conns     map[string]map[string]clientServiceHolder
+	conns     map[string]map[string]*clientServiceHolder
+	// hostConns contains the connections dialed by the pool, look at hostConnID.
+	// The connections dialed by the data planes are only in conns.
+	hostConns map[hostConnID]*hostConnection
+
+	subscribersLock  sync.RWMutex
+	subscribers      map[uint64]ConnectionStateChangeCallback
+	nextSubscriberId uint64
 }
 
+// clientServiceHolder holds the service used by a key to communicate with a host, through its host connection
 type clientServiceHolder struct {
-	service  control.Service
-	cancelFn context.CancelFunc
+	service control.Service
+	conn    *hostConnection
 }
 
 func NewInsecureControlPlaneConnectionPool(opts ...ControlPlaneConnectionPoolOption) ControlPlaneConnectionPool {
@@ -72,7 +115,11 @@ func NewControlPlaneConnectionPool(tlsDialerFactory TLSDialerFactory, opts ...Co
 			KeepAlive: keepAlive,
 			Deadline:  time.Time{},
 		},
-		conns: make(map[string]map[string]clientServiceHolder),
+		conns:                  make(map[string]map[string]*clientServiceHolder),
+		hostConns:              make(map[hostConnID]*hostConnection),
+		maxParallelDials:       defaultMaxParallelDials,
+		connectionCycleTimeout: defaultConnectionCycleTimeout,
+		subscribers:            make(map[uint64]ConnectionStateChangeCallback),
 	}
 
 	for _, fn := range opts {
@@ -85,7 +132,7 @@ func NewControlPlaneConnectionPool(tlsDialerFactory TLSDialerFactory, opts ...Co
 func (cc *controlPlaneConnectionPoolImpl) GetConnectedHosts(key string) []string {
 	cc.connsLock.Lock()
 	defer cc.connsLock.Unlock()
-	var m map[string]clientServiceHolder
+	var m map[string]*clientServiceHolder
 	var ok bool
 	if m, ok = cc.conns[key]; !ok {
 		return nil
@@ -100,7 +147,7 @@ func (cc *controlPlaneConnectionPoolImpl) GetConnectedHosts(key string) []string
 func (cc *controlPlaneConnectionPoolImpl) GetServices(key string) map[string]control.Service {
 	cc.connsLock.Lock()
 	defer cc.connsLock.Unlock()
-	var m map[string]clientServiceHolder
+	var m map[string]*clientServiceHolder
 	var ok bool
 	if m, ok = cc.conns[key]; !ok {
 		return nil
@@ -126,47 +173,201 @@ func (cc *controlPlaneConnectionPoolImpl) ResolveControlInterface(key string, ho
 
 func (cc *controlPlaneConnectionPoolImpl) RemoveConnection(ctx context.Context, key string, host string) {
 	cc.connsLock.Lock()
-	defer cc.connsLock.Unlock()
+	removed := cc.releaseLocked(ctx, key, host)
+	cc.connsLock.Unlock()
+
+	if removed {
+		cc.notify(key, host, network.ConnectionClosed)
+	}
+}
+
+func (cc *controlPlaneConnectionPoolImpl) RemoveAllConnections(ctx context.Context, key string) {
+	cc.connsLock.Lock()
 	m, ok := cc.conns[key]
 	if !ok {
+		cc.connsLock.Unlock()
 		return
 	}
-	holder, ok := m[host]
-	if !ok {
-		return
+	hosts := make([]string, 0, len(m))
+	for host := range m {
+		hosts = append(hosts, host)
 	}
-	holder.cancelFn()
-	delete(m, host)
-	if len(m) == 0 {
-		delete(cc.conns, key)
+	for _, host := range hosts {
+		cc.releaseLocked(ctx, key, host)
+	}
+	cc.connsLock.Unlock()
+
+	for _, host := range hosts {
+		cc.notify(key, host, network.ConnectionClosed)
 	}
 }
 
-func (cc *controlPlaneConnectionPoolImpl) RemoveAllConnections(ctx context.Context, key string) {
+func (cc *controlPlaneConnectionPoolImpl) Close(ctx context.Context) {
+	cc.connsLock.Lock()
+	conns := cc.conns
+	hcs := make(map[*hostConnection]struct{}, len(cc.hostConns))
+	for _, hc := range cc.hostConns {
+		hcs[hc] = struct{}{}
+	}
+	for _, m := range conns {
+		for _, holder := range m {
+			// e.g. the connections dialed by the data planes
+			hcs[holder.conn] = struct{}{}
+		}
+	}
+	for hc := range hcs {
+		hc.releaseAll()
+		if hc.cancelFn != nil {
+			hc.cancelFn()
+		}
+	}
+	for key, m := range conns {
+		metrics.RecordPoolConnectionsChange(ctx, key, -len(m))
+	}
+	// Let's make sure this object is reusable
+	cc.conns = make(map[string]map[string]*clientServiceHolder)
+	cc.hostConns = make(map[hostConnID]*hostConnection)
+	cc.connsLock.Unlock()
+
+	for key, m := range conns {
+		for host := range m {
+			cc.notify(key, host, network.ConnectionClosed)
+		}
+	}
+}
+
+func (cc *controlPlaneConnectionPoolImpl) GetConnectionState(key string, host string) (network.ConnectionState, bool) {
 	cc.connsLock.Lock()
 	defer cc.connsLock.Unlock()
+	holder, ok := cc.conns[key][host]
+	if !ok {
+		return network.ConnectionClosed, false
+	}
+	return holder.conn.state, true
+}
+
+func (cc *controlPlaneConnectionPoolImpl) SubscribeConnectionState(callback ConnectionStateChangeCallback) func() {
+	cc.subscribersLock.Lock()
+	id := cc.nextSubscriberId
+	cc.nextSubscriberId++
+	cc.subscribers[id] = callback
+	cc.subscribersLock.Unlock()
+
+	return func() {
+		cc.subscribersLock.Lock()
+		delete(cc.subscribers, id)
+		cc.subscribersLock.Unlock()
+	}
+}
+
+// releaseLocked removes the connection of key to host, closing the host connection if not used by other keys.
+// It returns true if the connection was removed.
+func (cc *controlPlaneConnectionPoolImpl) releaseLocked(ctx context.Context, key string, host string) bool {
 	m, ok := cc.conns[key]
 	if !ok {
-		return
+		return false
+	}
+	holder, ok := m[host]
+	if !ok {
+		return false
+	}
+	delete(m, host)
+	if len(m) == 0 {
+		delete(cc.conns, key)
 	}
-	for _, holder := range m {
-		holder.cancelFn()
+	metrics.RecordPoolConnectionsChange(ctx, key, -1)
+
+	hc := holder.conn
+	if hc.release(key) == 0 {
+		if cc.hostConns[hc.id] == hc {
+			delete(cc.hostConns, hc.id)
+		}
+		hc.cancelFn()
 	}
-	delete(cc.conns, key)
+	return true
 }
 
-func (cc *controlPlaneConnectionPoolImpl) Close(ctx context.Context) {
+// onConnectionStateChange tracks the state of the host connection,
+// evicting it from the pool, for all the keys using it, when it reaches a final state.
+func (cc *controlPlaneConnectionPoolImpl) onConnectionStateChange(ctx context.Context, hc *hostConnection, state network.ConnectionState) {
 	cc.connsLock.Lock()
-	defer cc.connsLock.Unlock()
-	for _, m := range cc.conns {
-		for _, holder := range m {
-			holder.cancelFn()
+	hc.state = state
+	var keys []string
+	if isFinalConnectionState(state) {
+		if cc.hostConns[hc.id] == hc {
+			delete(cc.hostConns, hc.id)
 		}
+		keys = hc.releaseAll()
+		for _, key := range keys {
+			if m, ok := cc.conns[key]; ok && m[hc.host] != nil && m[hc.host].conn == hc {
+				delete(m, hc.host)
+				if len(m) == 0 {
+					delete(cc.conns, key)
+				}
+				metrics.RecordPoolConnectionsChange(ctx, key, -1)
+			}
+		}
+		if hc.cancelFn != nil {
+			hc.cancelFn()
+		}
+	} else {
+		keys = hc.referencingKeys()
 	}
-	// Let's make sure this object is reusable
-	cc.conns = make(map[string]map[string]clientServiceHolder)
+	cc.connsLock.Unlock()
+
+	// The changes before the registration of a key are notified by DialControlService
+	for _, key := range keys {
+		if isFinalConnectionState(state) {
+			logging.FromContext(ctx).Warnf("Evicting the control connection to %s for key %s, because it's %s", hc.host, key, state)
+		}
+		cc.notify(key, hc.host, state)
+	}
+}
+
+func (cc *controlPlaneConnectionPoolImpl) notify(key string, host string, state network.ConnectionState) {
+	cc.subscribersLock.RLock()
+	callbacks := make([]ConnectionStateChangeCallback, 0, len(cc.subscribers))
+	for _, cb := range cc.subscribers {
+		callbacks = append(callbacks, cb)
+	}
+	cc.subscribersLock.RUnlock()
+
+	for _, cb := range callbacks {
+		cb(key, host, state)
+	}
+}
+
+func isFinalConnectionState(state network.ConnectionState) bool {
+	return state == network.ConnectionBroken || state == network.ConnectionClosed
+}
+
+// ReconcileError is returned by ReconcileConnections when some of the new hosts cannot be dialed.
+// The pool is updated anyway with the hosts successfully dialed, and the old connections are removed.
+type ReconcileError struct {
+	// Connected contains the new hosts successfully dialed
+	Connected []string
+	// Failed contains the new hosts that cannot be dialed, with the dial error
+	Failed map[string]error
+}
+
+func (e *ReconcileError) Error() string {
+	hosts := make([]string, 0, len(e.Failed))
+	for host := range e.Failed {
+		hosts = append(hosts, host)
+	}
+	sort.Strings(hosts)
+
+	errs := make([]string, 0, len(hosts))
+	for _, host := range hosts {
+		errs = append(errs, fmt.Sprintf("%s: %v", host, e.Failed[host]))
+	}
+	return fmt.Sprintf("cannot connect to %d pod(s): %s", len(hosts), strings.Join(errs, "; "))
 }
 
+// ReconcileConnections dials the new hosts in parallel, at most maxParallelDials at time, and removes the old ones.
+// When some of the new hosts cannot be dialed, the returned services contain the hosts successfully dialed
+// and the error is a *ReconcileError.
+// newServiceCb and oldServiceCb are invoked sequentially by the calling goroutine.
 func (cc *controlPlaneConnectionPoolImpl) ReconcileConnections(ctx context.Context, key string, wantConnections []string, newServiceCb func(string, control.Service), oldServiceCb func(string)) (map[string]control.Service, error) {
 	existingConnections := cc.GetConnectedHosts(key)
 
@@ -176,17 +377,17 @@ func (cc *controlPlaneConnectionPoolImpl) ReconcileConnections(ctx context.Conte
 	logging.FromContext(ctx).Debugf("New connections: %v", newConnections)
 	logging.FromContext(ctx).Debugf("Old connections: %v", oldConnections)
 
-	for _, newConn := range newConnections {
-		logging.FromContext(ctx).Debugf("Creating a new control connection: %s", newConn)
+	newServices, dialErrs := cc.dialAll(ctx, key, newConnections)
 
-		// Dial the service
-		_, ctrl, err := cc.DialControlService(ctx, key, newConn)
-		if err != nil {
-			return nil, fmt.Errorf("cannot connect to the pod: %w", err)
+	var connected []string
+	for i, newConn := range newConnections {
+		if dialErrs[i] != nil {
+			logging.FromContext(ctx).Warnf("Cannot connect to the pod %s: %v", newConn, dialErrs[i])
+			continue
 		}
-
+		connected = append(connected, newConn)
 		if newServiceCb != nil {
-			newServiceCb(newConn, ctrl)
+			newServiceCb(newConn, newServices[i])
 		}
 	}
 
@@ -200,49 +401,258 @@ func (cc *controlPlaneConnectionPoolImpl) ReconcileConnections(ctx context.Conte
 
 	logging.FromContext(ctx).Debugf("Now connected to: %v", cc.GetConnectedHosts(key))
 
+	if len(connected) != len(newConnections) {
+		reconcileErr := &ReconcileError{
+			Connected: connected,
+			Failed:    make(map[string]error, len(newConnections)-len(connected)),
+		}
+		for i, newConn := range newConnections {
+			if dialErrs[i] != nil {
+				reconcileErr.Failed[newConn] = dialErrs[i]
+			}
+		}
+		return cc.GetServices(key), reconcileErr
+	}
+
 	return cc.GetServices(key), nil
 }
 
+// dialAll dials the provided hosts, at most maxParallelDials at time.
+// The returned slices have the same order of hosts.
+func (cc *controlPlaneConnectionPoolImpl) dialAll(ctx context.Context, key string, hosts []string) ([]control.Service, []error) {
+	services := make([]control.Service, len(hosts))
+	errs := make([]error, len(hosts))
+
+	parallelism := cc.maxParallelDials
+	if parallelism < 1 {
+		parallelism = 1
+	}
+	sem := make(chan struct{}, parallelism)
+
+	var wg sync.WaitGroup
+	for i, host := range hosts {
+		wg.Add(1)
+		sem <- struct{}{}
+		go func(i int, host string) {
+			defer func() {
+				<-sem
+				wg.Done()
+			}()
+			logging.FromContext(ctx).Debugf("Creating a new control connection: %s", host)
+			_, services[i], errs[i] = cc.DialControlService(ctx, key, host)
+		}(i, host)
+	}
+	wg.Wait()
+
+	return services, errs
+}
+
+// DialControlService returns the service to communicate with host for key.
+// When the pool has a MessageKeyRouter, the connection to a host is shared by all the keys: it's dialed by the first key,
+// and closed when the last key removes it, or when the pool is closed. Otherwise, every key dials its own connection.
+// ctx bounds only the dial: the connection outlives it, using just its logger.
 func (cc *controlPlaneConnectionPoolImpl) DialControlService(ctx context.Context, key string, host string) (string, control.Service, error) {
+	cc.connsLock.Lock()
+	if holder, ok := cc.conns[key][host]; ok {
+		cc.connsLock.Unlock()
+		return host, holder.service, nil
+	}
+	id := cc.hostConnID(key, host)
+	hc, ok := cc.hostConns[id]
+	if !ok {
+		hc = newHostConnection(id, host, cc.messageKeyRouter)
+		cc.hostConns[id] = hc
+		cc.connsLock.Unlock()
+		cc.dialHost(ctx, hc)
+	} else {
+		cc.connsLock.Unlock()
+		select {
+		case <-hc.dialed:
+		case <-ctx.Done():
+			return "", nil, ctx.Err()
+		}
+	}
+
+	cc.connsLock.Lock()
+	if hc.dialErr != nil {
+		cc.connsLock.Unlock()
+		if hc.dialCanceled && ctx.Err() == nil {
+			// The key dialing the connection gave up, so let's dial again with our ctx
+			return cc.DialControlService(ctx, key, host)
+		}
+		return "", nil, hc.dialErr
+	}
+	if cc.hostConns[id] != hc {
+		// The connection broke before we could register it
+		state := hc.state
+		cc.connsLock.Unlock()
+		return "", nil, fmt.Errorf("the connection to %s is %s", host, state)
+	}
+	if holder, ok := cc.conns[key][host]; ok {
+		// Dialed concurrently for the same key
+		cc.connsLock.Unlock()
+		return host, holder.service, nil
+	}
+
+	newSvc := cc.wrapService(key, host, hc.acquire(key))
+
+	var m map[string]*clientServiceHolder
+	if m, ok = cc.conns[key]; !ok {
+		m = make(map[string]*clientServiceHolder)
+		cc.conns[key] = m
+	}
+	m[host] = &clientServiceHolder{service: newSvc, conn: hc}
+	state := hc.state
+	metrics.RecordPoolConnectionsChange(ctx, key, 1)
+	cc.connsLock.Unlock()
+
+	cc.notify(key, host, state)
+
+	return host, newSvc, nil
+}
+
+// hostConnID identifies a connection dialed by the pool. key is empty when the connection is shared by all the keys.
+type hostConnID struct {
+	key  string
+	host string
+}
+
+// hostConnID returns the id of the connection to host used by key.
+// The connections are shared by the keys only when the pool has a MessageKeyRouter,
+// otherwise the inbound messages of a connection shared by multiple keys couldn't be routed.
+func (cc *controlPlaneConnectionPoolImpl) hostConnID(key string, host string) hostConnID {
+	if cc.messageKeyRouter != nil {
+		return hostConnID{host: host}
+	}
+	return hostConnID{key: key, host: host}
+}
+
+// wrapService applies the wrappers of the pool to the service of key for host
+func (cc *controlPlaneConnectionPoolImpl) wrapService(key string, host string, svc control.Service) control.Service {
+	for _, factory := range cc.serviceWrapperFactories {
+		svc = factory(key, host)(svc)
+	}
+	return svc
+}
+
+// dialHost starts the control client of the host connection, closing hc.dialed when done
+func (cc *controlPlaneConnectionPoolImpl) dialHost(ctx context.Context, hc *hostConnection) {
+	svc, cancelFn, err := cc.startControlClient(ctx, hc)
+
+	cc.connsLock.Lock()
+	defer cc.connsLock.Unlock()
+	if err != nil {
+		hc.dialErr = err
+		hc.dialCanceled = ctx.Err() != nil
+		if cc.hostConns[hc.id] == hc {
+			delete(cc.hostConns, hc.id)
+		}
+	} else {
+		hc.service = svc
+		hc.cancelFn = cancelFn
+		if isFinalConnectionState(hc.state) {
+			cancelFn()
+		}
+	}
+	close(hc.dialed)
+}
+
+func (cc *controlPlaneConnectionPoolImpl) startControlClient(ctx context.Context, hc *hostConnection) (control.Service, context.CancelFunc, error) {
+	if ctx.Err() != nil {
+		return nil, nil, ctx.Err()
+	}
+	// Every dial reads again the tls credentials
+	if err := cc.rebuildDialer(); err != nil {
+		return nil, nil, err
+	}
+
+	// The connection is shared by the keys, so it's owned by the pool rather than bound to the ctx of the first dial:
+	// it's closed by cancelFn, when released by the last key or when the pool is closed.
+	connCtx := logging.WithLogger(context.Background(), logging.FromContext(ctx))
+
+	clientOptions := make([]network.ControlClientOption, 0, len(cc.controlClientOptions)+2)
+	clientOptions = append(clientOptions, cc.controlClientOptions...)
+	clientOptions = append(clientOptions,
+		network.WithClientStateCallback(func(state network.ConnectionState) {
+			cc.onConnectionStateChange(connCtx, hc, state)
+		}),
+		network.WithClientCycler(func(cycler network.ConnectionCycler) {
+			hc.cycler = cycler
+		}),
+	)
+
+	// Need to start new conn
+	clientCtx, cancelFn := context.WithCancel(connCtx)
+	// Stop dialing if the caller gives up
+	stopWatching, watched := make(chan struct{}), make(chan struct{})
+	go func() {
+		defer close(watched)
+		select {
+		case <-ctx.Done():
+			cancelFn()
+		case <-stopWatching:
+		}
+	}()
+	svc, err := network.StartControlClient(clientCtx, poolDialer{cc}, hc.host, clientOptions...)
+	close(stopWatching)
+	<-watched
+	if err == nil && ctx.Err() != nil {
+		err = ctx.Err()
+	}
+	if err != nil {
+		cancelFn()
+		return nil, nil, err
+	}
+	svc.MessageHandler(hc)
+	svc.ErrorHandler(hc)
+	return svc, cancelFn, nil
+}
+
+// buildDialer builds the dialer, generating the tls dialer with tlsDialerFactory if set
+func (cc *controlPlaneConnectionPoolImpl) buildDialer() (network.Dialer, error) {
 	var dialer network.Dialer
 	dialer = cc.baseDialOptions
+	if cc.dialer != nil {
+		dialer = cc.dialer
+	}
 	// Check if tlsDialerFactory is set up, otherwise connect without tls
 	if cc.tlsDialerFactory != nil {
 		// Create TLS dialer
-		var err error
-		dialer, err = cc.tlsDialerFactory.GenerateTLSDialer(cc.baseDialOptions)
+		tlsDialer, err := cc.tlsDialerFactory.GenerateTLSDialer(cc.baseDialOptions)
 		if err != nil {
-			return "", nil, err
+			return nil, err
+		}
+		if cc.dialer != nil {
+			dialer = network.NewTLSDialer(cc.dialer, tlsDialer.Config)
+		} else {
+			dialer = tlsDialer
 		}
 	}
+	return dialer, nil
+}
 
-	// Need to start new conn
-	ctx, cancelFn := context.WithCancel(ctx)
-	newSvc, err := network.StartControlClient(ctx, dialer, host)
+// rebuildDialer replaces the dialer used by the control clients with a new one
+func (cc *controlPlaneConnectionPoolImpl) rebuildDialer() error {
+	dialer, err := cc.buildDialer()
 	if err != nil {
-		cancelFn()
-		return "", nil, err
-	}
-
-	// Apply wrappers
-	for _, wrap := range cc.serviceWrapperFactories {
-		newSvc = wrap(newSvc)
+		return err
 	}
+	cc.currentDialerLock.Lock()
+	cc.currentDialer = dialer
+	cc.currentDialerLock.Unlock()
+	return nil
+}
 
-	cc.connsLock.Lock()
-	var m map[string]clientServiceHolder
-	var ok bool
-	if m, ok = cc.conns[key]; !ok {
-		m = make(map[string]clientServiceHolder)
-		cc.conns[key] = m
-	}
-	m[host] = clientServiceHolder{
-		service:  newSvc,
-		cancelFn: cancelFn,
-	}
-	cc.connsLock.Unlock()
+// poolDialer dials with the current dialer of the pool, so the control clients reconnect with the last credentials
+type poolDialer struct {
+	pool *controlPlaneConnectionPoolImpl
+}
 
-	return host, newSvc, nil
+func (d poolDialer) DialContext(ctx context.Context, network string, address string) (net.Conn, error) {
+	d.pool.currentDialerLock.RLock()
+	dialer := d.pool.currentDialer
+	d.pool.currentDialerLock.RUnlock()
+	return dialer.DialContext(ctx, network, address)
 }
 
 func setDifference(a, b []string) (diff []string) {
@@ -16,12 +16,63 @@ limitations under the License.
 
 package reconciler
 
-import control "knative.dev/control-protocol/pkg"
+import (
+	"time"
+
+	control "knative.dev/control-protocol/pkg"
+	"knative.dev/control-protocol/pkg/network"
+)
 
 type ControlPlaneConnectionPoolOption func(*controlPlaneConnectionPoolImpl)
 
 func WithServiceWrapper(wrapper control.ServiceWrapper) ControlPlaneConnectionPoolOption {
+	return WithHostServiceWrapper(func(string, string) control.ServiceWrapper {
+		return wrapper
+	})
+}
+
+// WithHostServiceWrapper is like WithServiceWrapper, but factory builds the wrapper of every service of the pool
+// with its key and host, e.g. to name the circuit breaker of the host with service.WithCircuitBreakerName.
+func WithHostServiceWrapper(factory func(key string, host string) control.ServiceWrapper) ControlPlaneConnectionPoolOption {
+	return func(pool *controlPlaneConnectionPoolImpl) {
+		pool.serviceWrapperFactories = append(pool.serviceWrapperFactories, factory)
+	}
+}
+
+// WithControlClientOptions configures the options used to start every control client of the pool
+func WithControlClientOptions(opts ...network.ControlClientOption) ControlPlaneConnectionPoolOption {
+	return func(pool *controlPlaneConnectionPoolImpl) {
+		pool.controlClientOptions = append(pool.controlClientOptions, opts...)
+	}
+}
+
+// WithDialer makes the pool dial the connections with the provided dialer, e.g. network.MemoryNetwork, rather than with a net.Dialer.
+// When the pool is created with a TLSDialerFactory, the tls configuration of the generated dialer is applied on top of the provided dialer.
+func WithDialer(dialer network.Dialer) ControlPlaneConnectionPoolOption {
+	return func(pool *controlPlaneConnectionPoolImpl) {
+		pool.dialer = dialer
+	}
+}
+
+// WithMaxParallelDials sets how many hosts ReconcileConnections dials in parallel. Defaults to 10.
+func WithMaxParallelDials(maxParallelDials int) ControlPlaneConnectionPoolOption {
+	return func(pool *controlPlaneConnectionPoolImpl) {
+		pool.maxParallelDials = maxParallelDials
+	}
+}
+
+// WithConnectionCycleTimeout sets how long RotateCredentials waits for every connection to reconnect. Defaults to 30 seconds.
+func WithConnectionCycleTimeout(timeout time.Duration) ControlPlaneConnectionPoolOption {
+	return func(pool *controlPlaneConnectionPoolImpl) {
+		pool.connectionCycleTimeout = timeout
+	}
+}
+
+// WithMessageKeyRouter makes the keys connected to the same host share a single connection,
+// routing its inbound messages to their key with router. Without a router, every key dials its own connection to the host.
+// When the router cannot determine the key, the messages received from a connection shared by multiple keys are acked with an error.
+func WithMessageKeyRouter(router MessageKeyRouter) ControlPlaneConnectionPoolOption {
 	return func(pool *controlPlaneConnectionPoolImpl) {
-		pool.serviceWrapperFactories = append(pool.serviceWrapperFactories, wrapper)
+		pool.messageKeyRouter = router
 	}
 }
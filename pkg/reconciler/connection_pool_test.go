@@ -18,15 +18,20 @@ package reconciler_test
 
 import (
 	"context"
+	"errors"
 	"fmt"
+	"sync"
 	"testing"
+	"time"
 
 	"github.com/stretchr/testify/require"
 	"go.uber.org/atomic"
 	"go.uber.org/zap"
+	"golang.org/x/time/rate"
 	"knative.dev/pkg/logging"
 
 	control "knative.dev/control-protocol/pkg"
+	"knative.dev/control-protocol/pkg/network"
 	"knative.dev/control-protocol/pkg/reconciler"
 	"knative.dev/control-protocol/pkg/service"
 	"knative.dev/control-protocol/pkg/test"
@@ -72,6 +77,150 @@ func TestReconcileConnections(t *testing.T) {
 	}
 }
 
+func TestReconcileConnections_PartialFailure(t *testing.T) {
+	logger, _ := zap.NewDevelopment()
+	ctx := logging.WithLogger(context.TODO(), logger.Sugar())
+
+	for name, setupFn := range test.ConnectionPoolTestCases() {
+		t.Run(name, func(t *testing.T) {
+			server, connectionPool := setupFn(t, ctx, reconciler.WithMaxParallelDials(2))
+			address := fmt.Sprintf("127.0.0.1:%d", server.ListeningPort())
+			unreachableAddress := "127.0.0.1:1"
+
+			var newServices []string
+			conns, err := connectionPool.ReconcileConnections(context.TODO(), "hello", []string{unreachableAddress, address}, func(host string, _ control.Service) {
+				newServices = append(newServices, host)
+			}, nil)
+
+			var reconcileErr *reconciler.ReconcileError
+			require.ErrorAs(t, err, &reconcileErr)
+			require.Equal(t, []string{address}, reconcileErr.Connected)
+			require.Len(t, reconcileErr.Failed, 1)
+			require.Contains(t, reconcileErr.Failed, unreachableAddress)
+			require.Equal(t, []string{address}, newServices)
+			require.Contains(t, conns, address)
+			require.NotContains(t, conns, unreachableAddress)
+
+			test.SendReceiveTest(t, server, conns[address])
+
+			// The old connection is removed even if the new one fails
+			var oldServices []string
+			conns, err = connectionPool.ReconcileConnections(context.TODO(), "hello", []string{unreachableAddress}, nil, func(host string) {
+				oldServices = append(oldServices, host)
+			})
+			require.ErrorAs(t, err, &reconcileErr)
+			require.Empty(t, reconcileErr.Connected)
+			require.Contains(t, reconcileErr.Failed, unreachableAddress)
+			require.Equal(t, []string{address}, oldServices)
+			require.Empty(t, conns)
+			require.Empty(t, connectionPool.GetConnectedHosts("hello"))
+		})
+	}
+}
+
+type connectionStatesRecorder struct {
+	mutex  sync.Mutex
+	states map[string][]network.ConnectionState
+}
+
+func (r *connectionStatesRecorder) callback(key string, host string, state network.ConnectionState) {
+	if state == network.ConnectionConnecting {
+		// The connection might become ready either before or after being registered in the pool
+		return
+	}
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+	if r.states == nil {
+		r.states = make(map[string][]network.ConnectionState)
+	}
+	r.states[key+"/"+host] = append(r.states[key+"/"+host], state)
+}
+
+func (r *connectionStatesRecorder) get(key string, host string) []network.ConnectionState {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+	return append([]network.ConnectionState(nil), r.states[key+"/"+host]...)
+}
+
+func TestConnectionPool_ConnectionState(t *testing.T) {
+	logger, _ := zap.NewDevelopment()
+	ctx := logging.WithLogger(context.TODO(), logger.Sugar())
+
+	for name, setupFn := range test.ConnectionPoolTestCases() {
+		t.Run(name, func(t *testing.T) {
+			server, connectionPool := setupFn(t, ctx)
+			address := fmt.Sprintf("127.0.0.1:%d", server.ListeningPort())
+
+			observer := connectionPool.(reconciler.ConnectionStateObserver)
+			recorder := &connectionStatesRecorder{}
+			unsubscribe := observer.SubscribeConnectionState(recorder.callback)
+
+			_, err := connectionPool.ReconcileConnections(context.TODO(), "hello", []string{address}, nil, nil)
+			require.NoError(t, err)
+
+			require.Eventually(t, func() bool {
+				state, ok := observer.GetConnectionState("hello", address)
+				return ok && state == network.ConnectionReady
+			}, 5*time.Second, 10*time.Millisecond)
+			require.Equal(t, []network.ConnectionState{network.ConnectionReady}, recorder.get("hello", address))
+
+			connectionPool.RemoveConnection(ctx, "hello", address)
+			_, ok := observer.GetConnectionState("hello", address)
+			require.False(t, ok)
+			require.Equal(t, []network.ConnectionState{network.ConnectionReady, network.ConnectionClosed}, recorder.get("hello", address))
+
+			unsubscribe()
+			_, err = connectionPool.ReconcileConnections(context.TODO(), "hello", []string{address}, nil, nil)
+			require.NoError(t, err)
+			require.Len(t, recorder.get("hello", address), 2)
+		})
+	}
+}
+
+func TestConnectionPool_EvictsBrokenConnections(t *testing.T) {
+	logger, _ := zap.NewDevelopment()
+	ctx := logging.WithLogger(context.TODO(), logger.Sugar())
+
+	memoryNetwork := network.NewMemoryNetwork()
+	ln, err := memoryNetwork.Listen("127.0.0.1:0")
+	require.NoError(t, err)
+
+	serverCtx, serverCancelFn := context.WithCancel(ctx)
+	server, err := network.StartInsecureControlServer(serverCtx, network.WithListener(ln))
+	require.NoError(t, err)
+	address := fmt.Sprintf("127.0.0.1:%d", server.ListeningPort())
+
+	connectionPool := reconciler.NewInsecureControlPlaneConnectionPool(reconciler.WithDialer(memoryNetwork))
+	t.Cleanup(func() {
+		connectionPool.Close(ctx)
+	})
+
+	observer := connectionPool.(reconciler.ConnectionStateObserver)
+	recorder := &connectionStatesRecorder{}
+	observer.SubscribeConnectionState(recorder.callback)
+
+	conns, err := connectionPool.ReconcileConnections(ctx, "hello", []string{address}, nil, nil)
+	require.NoError(t, err)
+	require.Contains(t, conns, address)
+
+	// Stop the server, so the client gives up re-dialing it
+	serverCancelFn()
+	<-server.ClosedCh()
+
+	require.Eventually(t, func() bool {
+		_, ok := observer.GetConnectionState("hello", address)
+		return !ok
+	}, 10*time.Second, 10*time.Millisecond)
+	require.Empty(t, connectionPool.GetServices("hello"))
+	// The client may reconnect once, before the stopping server closes its listener
+	states := recorder.get("hello", address)
+	require.Equal(t, network.ConnectionReady, states[0])
+	require.Equal(t, []network.ConnectionState{
+		network.ConnectionReconnecting,
+		network.ConnectionBroken,
+	}, states[len(states)-2:])
+}
+
 func TestReconcileConnections_ResolveAndRemove(t *testing.T) {
 	logger, _ := zap.NewDevelopment()
 	ctx := logging.WithLogger(context.TODO(), logger.Sugar())
@@ -128,3 +277,98 @@ func TestConnectionPool_IntegrationWithCachingWrapper(t *testing.T) {
 		})
 	}
 }
+
+func TestConnectionPool_IntegrationWithRateLimitingWrapper(t *testing.T) {
+	logger, _ := zap.NewDevelopment()
+	ctx := logging.WithLogger(context.TODO(), logger.Sugar())
+
+	for name, setupFn := range test.ConnectionPoolTestCases() {
+		t.Run(name, func(t *testing.T) {
+			dataPlane, connectionPool := setupFn(t, ctx, reconciler.WithServiceWrapper(service.WithRateLimitingService(
+				ctx,
+				service.WithOpCodeRateLimit(1, rate.Every(time.Hour), 1),
+				service.RateLimitFailFast(),
+			)))
+			address := fmt.Sprintf("127.0.0.1:%d", dataPlane.ListeningPort())
+
+			conns, err := connectionPool.ReconcileConnections(context.TODO(), "hello", []string{address}, nil, nil)
+			require.NoError(t, err)
+			require.Contains(t, conns, address)
+
+			controlPlane := conns[address]
+
+			require.NoError(t, controlPlane.SendAndWaitForAck(1, test.MockPayload("Funky!")))
+
+			var rateLimitedErr *service.RateLimitedError
+			require.ErrorAs(t, controlPlane.SendAndWaitForAck(1, test.MockPayload("Funky!")), &rateLimitedErr)
+		})
+	}
+}
+
+func TestConnectionPool_IntegrationWithRetryWrapper(t *testing.T) {
+	logger, _ := zap.NewDevelopment()
+	ctx := logging.WithLogger(context.TODO(), logger.Sugar())
+
+	for name, setupFn := range test.ConnectionPoolTestCases() {
+		t.Run(name, func(t *testing.T) {
+			dataPlane, connectionPool := setupFn(t, ctx, reconciler.WithServiceWrapper(service.WithRetryService(
+				ctx,
+				service.WithRetryBackoff(time.Millisecond, 10*time.Millisecond, 2),
+				service.WithRetryableErrors(func(err error) bool { return true }),
+			)))
+			address := fmt.Sprintf("127.0.0.1:%d", dataPlane.ListeningPort())
+
+			attempts := atomic.NewInt32(0)
+			dataPlane.MessageHandler(control.MessageHandlerFunc(func(ctx context.Context, message control.ServiceMessage) {
+				if attempts.Inc() == 1 {
+					message.AckWithError(errors.New("transient"))
+					return
+				}
+				message.Ack()
+			}))
+
+			conns, err := connectionPool.ReconcileConnections(context.TODO(), "hello", []string{address}, nil, nil)
+			require.NoError(t, err)
+			require.Contains(t, conns, address)
+
+			require.NoError(t, conns[address].SendAndWaitForAck(1, test.MockPayload("Funky!")))
+			require.Equal(t, int32(2), attempts.Load())
+		})
+	}
+}
+
+func TestConnectionPool_IntegrationWithCircuitBreakerWrapper(t *testing.T) {
+	logger, _ := zap.NewDevelopment()
+	ctx := logging.WithLogger(context.TODO(), logger.Sugar())
+
+	for name, setupFn := range test.ConnectionPoolTestCases() {
+		t.Run(name, func(t *testing.T) {
+			opened := make(chan string, 1)
+			dataPlane, connectionPool := setupFn(t, ctx, reconciler.WithHostServiceWrapper(func(key string, host string) control.ServiceWrapper {
+				return service.WithCircuitBreakerService(ctx,
+					service.WithCircuitBreakerName(key+" "+host),
+					service.WithFailureThreshold(1),
+					service.WithCoolDown(time.Hour),
+					service.WithStateChangeCallback(func(name string, svc control.Service, from service.CircuitState, to service.CircuitState) {
+						if to == service.CircuitOpen {
+							opened <- name
+						}
+					}),
+				)
+			}))
+			address := fmt.Sprintf("127.0.0.1:%d", dataPlane.ListeningPort())
+			dataPlane.MessageHandler(control.MessageHandlerFunc(func(ctx context.Context, message control.ServiceMessage) {
+				message.AckWithError(errors.New("wedged"))
+			}))
+
+			conns, err := connectionPool.ReconcileConnections(context.TODO(), "hello", []string{address}, nil, nil)
+			require.NoError(t, err)
+			require.Contains(t, conns, address)
+
+			// The callback can tell which host failed
+			require.ErrorContains(t, conns[address].SendAndWaitForAck(1, test.MockPayload("Funky!")), "wedged")
+			require.Equal(t, "hello "+address, <-opened)
+			require.ErrorIs(t, conns[address].SendAndWaitForAck(1, test.MockPayload("Funky!")), service.ErrCircuitOpen)
+		})
+	}
+}
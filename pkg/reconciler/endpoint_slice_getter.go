@@ -0,0 +1,227 @@
+/*
+Copyright 2026 The Knative Authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package reconciler
+
+import (
+	"net"
+	"sort"
+	"strconv"
+
+	discoveryv1 "k8s.io/api/discovery/v1"
+	"k8s.io/apimachinery/pkg/labels"
+	v1 "k8s.io/client-go/listers/discovery/v1"
+
+	"knative.dev/control-protocol/pkg/network"
+)
+
+// ServiceEndpoint is the control endpoint of a service backend, discovered from an EndpointSlice
+type ServiceEndpoint struct {
+	// PodName is the name of the pod backing the endpoint, if any
+	PodName string
+	// IP is the first address of the endpoint
+	IP string
+	// Host is the address to dial, in the form host:port, with IPv6 addresses bracketed
+	Host string
+	// Ready, Serving and Terminating are the conditions of the endpoint
+	Ready       bool
+	Serving     bool
+	Terminating bool
+}
+
+type serviceEndpointOptions struct {
+	portName        string
+	port            int
+	addressType     discoveryv1.AddressType
+	includeServing  bool
+	includeNotReady bool
+}
+
+type ServiceEndpointOption func(*serviceEndpointOptions)
+
+// WithServicePortName resolves the port of the endpoints from the EndpointSlice port with the provided name.
+// The EndpointSlices without a port with that name are skipped.
+func WithServicePortName(name string) ServiceEndpointOption {
+	return func(options *serviceEndpointOptions) {
+		options.portName = name
+	}
+}
+
+// WithServiceEndpointPort sets the port of the endpoints, when WithServicePortName is not provided.
+// Defaults to network.DefaultControlPort.
+func WithServiceEndpointPort(port int) ServiceEndpointOption {
+	return func(options *serviceEndpointOptions) {
+		options.port = port
+	}
+}
+
+// WithAddressType returns only the endpoints of the EndpointSlices with the provided address type.
+// By default, both the IPv4 and IPv6 EndpointSlices are used, and the endpoints of a dual-stack service are returned once,
+// with their IPv4 address.
+func WithAddressType(addressType discoveryv1.AddressType) ServiceEndpointOption {
+	return func(options *serviceEndpointOptions) {
+		options.addressType = addressType
+	}
+}
+
+// WithServingEndpoints returns also the endpoints not ready but still serving, e.g. while terminating
+func WithServingEndpoints() ServiceEndpointOption {
+	return func(options *serviceEndpointOptions) {
+		options.includeServing = true
+	}
+}
+
+// WithNotReadyServiceEndpoints returns all the endpoints, regardless of their conditions
+func WithNotReadyServiceEndpoints() ServiceEndpointOption {
+	return func(options *serviceEndpointOptions) {
+		options.includeNotReady = true
+	}
+}
+
+// EndpointSliceGetter discovers the control endpoints of a service, e.g. a headless service, from its EndpointSlices.
+type EndpointSliceGetter struct {
+	Lister v1.EndpointSliceLister
+}
+
+// GetServiceEndpoints returns the control endpoints of the service in namespace.
+// By default only the ready endpoints are returned. The endpoints are deduplicated by their target, e.g. the pod,
+// so a dual-stack pod is returned once, with the address of the first family, IPv4 before IPv6.
+// The endpoints without a target are deduplicated by Host.
+func (g EndpointSliceGetter) GetServiceEndpoints(namespace string, serviceName string, opts ...ServiceEndpointOption) ([]ServiceEndpoint, error) {
+	options := serviceEndpointOptions{
+		port: network.DefaultControlPort,
+	}
+	for _, fn := range opts {
+		fn(&options)
+	}
+
+	slices, err := g.Lister.EndpointSlices(namespace).List(labels.SelectorFromSet(labels.Set{
+		discoveryv1.LabelServiceName: serviceName,
+	}))
+	if err != nil {
+		return nil, err
+	}
+
+	// Visit the IPv4 slices first, so the dual-stack endpoints are deterministically returned with their IPv4 address
+	sort.Slice(slices, func(i, j int) bool {
+		if slices[i].AddressType != slices[j].AddressType {
+			return slices[i].AddressType < slices[j].AddressType
+		}
+		return slices[i].Name < slices[j].Name
+	})
+
+	seen := make(map[string]struct{})
+	var endpoints []ServiceEndpoint
+	for _, slice := range slices {
+		if slice.AddressType == discoveryv1.AddressTypeFQDN {
+			continue
+		}
+		if options.addressType != "" && slice.AddressType != options.addressType {
+			continue
+		}
+		port, ok := endpointSlicePort(slice, &options)
+		if !ok {
+			continue
+		}
+
+		for _, e := range slice.Endpoints {
+			endpoint, ok := serviceEndpoint(e, port, &options)
+			if !ok {
+				continue
+			}
+			key := endpointKey(e, endpoint)
+			if _, ok := seen[key]; ok {
+				continue
+			}
+			seen[key] = struct{}{}
+			endpoints = append(endpoints, endpoint)
+		}
+	}
+	return endpoints, nil
+}
+
+// GetServiceHosts returns the hosts of the control endpoints of the service, to be used with ControlPlaneConnectionPool.ReconcileConnections
+func (g EndpointSliceGetter) GetServiceHosts(namespace string, serviceName string, opts ...ServiceEndpointOption) ([]string, error) {
+	endpoints, err := g.GetServiceEndpoints(namespace, serviceName, opts...)
+	if err != nil {
+		return nil, err
+	}
+	hosts := make([]string, 0, len(endpoints))
+	for _, e := range endpoints {
+		hosts = append(hosts, e.Host)
+	}
+	return hosts, nil
+}
+
+// endpointKey identifies an endpoint across the EndpointSlices of the different address types
+func endpointKey(e discoveryv1.Endpoint, endpoint ServiceEndpoint) string {
+	if e.TargetRef == nil {
+		return endpoint.Host
+	}
+	if e.TargetRef.UID != "" {
+		return string(e.TargetRef.UID)
+	}
+	return e.TargetRef.Kind + "/" + e.TargetRef.Namespace + "/" + e.TargetRef.Name
+}
+
+func endpointSlicePort(slice *discoveryv1.EndpointSlice, options *serviceEndpointOptions) (int, bool) {
+	if options.portName == "" {
+		return options.port, true
+	}
+	for _, p := range slice.Ports {
+		if p.Name != nil && *p.Name == options.portName && p.Port != nil {
+			return int(*p.Port), true
+		}
+	}
+	return 0, false
+}
+
+func serviceEndpoint(e discoveryv1.Endpoint, port int, options *serviceEndpointOptions) (ServiceEndpoint, bool) {
+	if len(e.Addresses) == 0 {
+		return ServiceEndpoint{}, false
+	}
+
+	// As documented by the EndpointConditions, a nil ready is interpreted as ready,
+	// a nil serving has the value of ready, and a nil terminating as not terminating
+	ready := e.Conditions.Ready == nil || *e.Conditions.Ready
+	serving := ready
+	if e.Conditions.Serving != nil {
+		serving = *e.Conditions.Serving
+	}
+	terminating := e.Conditions.Terminating != nil && *e.Conditions.Terminating
+
+	switch {
+	case options.includeNotReady:
+	case options.includeServing && serving:
+	case ready:
+	default:
+		return ServiceEndpoint{}, false
+	}
+
+	var podName string
+	if e.TargetRef != nil && e.TargetRef.Kind == "Pod" {
+		podName = e.TargetRef.Name
+	}
+	ip := e.Addresses[0]
+	return ServiceEndpoint{
+		PodName:     podName,
+		IP:          ip,
+		Host:        net.JoinHostPort(ip, strconv.Itoa(port)),
+		Ready:       ready,
+		Serving:     serving,
+		Terminating: terminating,
+	}, true
+}
@@ -0,0 +1,169 @@
+/*
+Copyright 2026 The Knative Authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package reconciler_test
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+	corev1 "k8s.io/api/core/v1"
+	discoveryv1 "k8s.io/api/discovery/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	listers "k8s.io/client-go/listers/discovery/v1"
+	"k8s.io/client-go/tools/cache"
+
+	"knative.dev/control-protocol/pkg/reconciler"
+)
+
+func TestEndpointSliceGetter_GetServiceEndpoints(t *testing.T) {
+	namespace := "abc"
+	serviceName := "dispatcher"
+	yes, no := true, false
+	controlPort, controlPortName := int32(10000), "control"
+
+	slice := func(name string, addressType discoveryv1.AddressType, endpoints ...discoveryv1.Endpoint) *discoveryv1.EndpointSlice {
+		return &discoveryv1.EndpointSlice{
+			ObjectMeta: metav1.ObjectMeta{
+				Name:      name,
+				Namespace: namespace,
+				Labels:    map[string]string{discoveryv1.LabelServiceName: serviceName},
+			},
+			AddressType: addressType,
+			Endpoints:   endpoints,
+			Ports: []discoveryv1.EndpointPort{{
+				Name: &controlPortName,
+				Port: &controlPort,
+			}},
+		}
+	}
+	endpoint := func(ip string, pod string, conditions discoveryv1.EndpointConditions) discoveryv1.Endpoint {
+		return discoveryv1.Endpoint{
+			Addresses:  []string{ip},
+			Conditions: conditions,
+			TargetRef:  &corev1.ObjectReference{Kind: "Pod", Name: pod, Namespace: namespace},
+		}
+	}
+	ready := discoveryv1.EndpointConditions{Ready: &yes, Serving: &yes, Terminating: &no}
+	terminatingServing := discoveryv1.EndpointConditions{Ready: &no, Serving: &yes, Terminating: &yes}
+	notReady := discoveryv1.EndpointConditions{Ready: &no, Serving: &no, Terminating: &no}
+
+	tests := []struct {
+		name   string
+		slices []*discoveryv1.EndpointSlice
+		opts   []reconciler.ServiceEndpointOption
+		want   []reconciler.ServiceEndpoint
+	}{{
+		name: "no slices",
+	}, {
+		name: "ready endpoints with default port",
+		slices: []*discoveryv1.EndpointSlice{
+			slice("dispatcher-a", discoveryv1.AddressTypeIPv4,
+				endpoint("10.0.0.1", "pod-a", ready),
+				endpoint("10.0.0.2", "pod-b", terminatingServing),
+				endpoint("10.0.0.3", "pod-c", notReady),
+				endpoint("10.0.0.4", "pod-d", discoveryv1.EndpointConditions{}),
+			),
+		},
+		want: []reconciler.ServiceEndpoint{
+			{PodName: "pod-a", IP: "10.0.0.1", Host: "10.0.0.1:9000", Ready: true, Serving: true},
+			{PodName: "pod-d", IP: "10.0.0.4", Host: "10.0.0.4:9000", Ready: true, Serving: true},
+		},
+	}, {
+		name: "serving endpoints",
+		slices: []*discoveryv1.EndpointSlice{
+			slice("dispatcher-a", discoveryv1.AddressTypeIPv4,
+				endpoint("10.0.0.1", "pod-a", ready),
+				endpoint("10.0.0.2", "pod-b", terminatingServing),
+				endpoint("10.0.0.3", "pod-c", notReady),
+			),
+		},
+		opts: []reconciler.ServiceEndpointOption{reconciler.WithServingEndpoints()},
+		want: []reconciler.ServiceEndpoint{
+			{PodName: "pod-a", IP: "10.0.0.1", Host: "10.0.0.1:9000", Ready: true, Serving: true},
+			{PodName: "pod-b", IP: "10.0.0.2", Host: "10.0.0.2:9000", Serving: true, Terminating: true},
+		},
+	}, {
+		name: "not ready endpoints",
+		slices: []*discoveryv1.EndpointSlice{
+			slice("dispatcher-a", discoveryv1.AddressTypeIPv4, endpoint("10.0.0.3", "pod-c", notReady)),
+		},
+		opts: []reconciler.ServiceEndpointOption{reconciler.WithNotReadyServiceEndpoints()},
+		want: []reconciler.ServiceEndpoint{
+			{PodName: "pod-c", IP: "10.0.0.3", Host: "10.0.0.3:9000"},
+		},
+	}, {
+		name: "named port and deduplication across slices",
+		slices: []*discoveryv1.EndpointSlice{
+			slice("dispatcher-a", discoveryv1.AddressTypeIPv4, endpoint("10.0.0.1", "pod-a", ready)),
+			slice("dispatcher-b", discoveryv1.AddressTypeIPv4, endpoint("10.0.0.1", "pod-a", ready)),
+		},
+		opts: []reconciler.ServiceEndpointOption{reconciler.WithServicePortName(controlPortName)},
+		want: []reconciler.ServiceEndpoint{
+			{PodName: "pod-a", IP: "10.0.0.1", Host: "10.0.0.1:10000", Ready: true, Serving: true},
+		},
+	}, {
+		name: "missing named port",
+		slices: []*discoveryv1.EndpointSlice{
+			slice("dispatcher-a", discoveryv1.AddressTypeIPv4, endpoint("10.0.0.1", "pod-a", ready)),
+		},
+		opts: []reconciler.ServiceEndpointOption{reconciler.WithServicePortName("other")},
+	}, {
+		name: "dual stack",
+		slices: []*discoveryv1.EndpointSlice{
+			slice("dispatcher-a", discoveryv1.AddressTypeIPv4, endpoint("10.0.0.1", "pod-a", ready)),
+			slice("dispatcher-b", discoveryv1.AddressTypeIPv6, endpoint("fd00::1", "pod-a", ready)),
+			slice("dispatcher-c", discoveryv1.AddressTypeFQDN, endpoint("pod-a.dispatcher", "pod-a", ready)),
+		},
+		opts: []reconciler.ServiceEndpointOption{reconciler.WithAddressType(discoveryv1.AddressTypeIPv6)},
+		want: []reconciler.ServiceEndpoint{
+			{PodName: "pod-a", IP: "fd00::1", Host: "[fd00::1]:9000", Ready: true, Serving: true},
+		},
+	}, {
+		name: "dual stack without address type",
+		slices: []*discoveryv1.EndpointSlice{
+			slice("dispatcher-a", discoveryv1.AddressTypeIPv6, endpoint("fd00::1", "pod-a", ready), endpoint("fd00::2", "pod-b", ready)),
+			slice("dispatcher-b", discoveryv1.AddressTypeIPv4, endpoint("10.0.0.1", "pod-a", ready)),
+		},
+		want: []reconciler.ServiceEndpoint{
+			{PodName: "pod-a", IP: "10.0.0.1", Host: "10.0.0.1:9000", Ready: true, Serving: true},
+			{PodName: "pod-b", IP: "fd00::2", Host: "[fd00::2]:9000", Ready: true, Serving: true},
+		},
+	}}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			indexer := cache.NewIndexer(cache.MetaNamespaceKeyFunc, cache.Indexers{cache.NamespaceIndex: cache.MetaNamespaceIndexFunc})
+			for _, s := range tt.slices {
+				require.NoError(t, indexer.Add(s))
+			}
+			// Slices of other services are ignored
+			other := slice("other", discoveryv1.AddressTypeIPv4, endpoint("10.0.0.9", "pod-other", ready))
+			other.Labels[discoveryv1.LabelServiceName] = "other"
+			require.NoError(t, indexer.Add(other))
+
+			getter := reconciler.EndpointSliceGetter{
+				Lister: listers.NewEndpointSliceLister(indexer),
+			}
+			got, err := getter.GetServiceEndpoints(namespace, serviceName, tt.opts...)
+			require.NoError(t, err)
+			require.ElementsMatch(t, tt.want, got)
+
+			hosts, err := getter.GetServiceHosts(namespace, serviceName, tt.opts...)
+			require.NoError(t, err)
+			require.Len(t, hosts, len(tt.want))
+		})
+	}
+}
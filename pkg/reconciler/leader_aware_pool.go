@@ -0,0 +1,263 @@
+/*
+Copyright 2026 The Knative Authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package reconciler
+
+import (
+	"context"
+	"encoding"
+	"errors"
+	"fmt"
+	"sync"
+
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/client-go/tools/cache"
+	"knative.dev/pkg/logging"
+	pkgreconciler "knative.dev/pkg/reconciler"
+
+	control "knative.dev/control-protocol/pkg"
+	"knative.dev/control-protocol/pkg/network"
+)
+
+// ErrNotLeader is returned when dialing or reconciling the connections for a key whose bucket is not led by this replica
+var ErrNotLeader = errors.New("not the leader of the key")
+
+type leaderAwareConnectionPoolOptions struct {
+	keyParser func(key string) (types.NamespacedName, error)
+}
+
+type LeaderAwareConnectionPoolOption func(*leaderAwareConnectionPoolOptions)
+
+// WithPoolKeyParser sets how the keys of the pool are mapped to the keys of the buckets.
+// Defaults to cache.SplitMetaNamespaceKey, which parses keys in the form namespace/name,
+// rejecting the keys without a namespace: provide a parser to use other keys.
+func WithPoolKeyParser(parser func(key string) (types.NamespacedName, error)) LeaderAwareConnectionPoolOption {
+	return func(options *leaderAwareConnectionPoolOptions) {
+		options.keyParser = parser
+	}
+}
+
+// LeaderAwareConnectionPool is a ControlPlaneConnectionPool opening the connections for a key
+// only while this replica is the leader of the bucket of the key, as elected by knative.dev/pkg leader election.
+// This way, in a controller with N replicas, every data plane pod is connected only by the leader of its key,
+// rather than by all the replicas.
+//
+// The pool must be promoted and demoted together with the reconciler, e.g. invoking Promote and Demote
+// from the reconciler pkgreconciler.LeaderAwareFuncs. After a promotion, the connections are opened only when
+// ReconcileConnections is invoked again for the keys of the bucket, e.g. by the reconciler enqueued by the promotion.
+// When demoted, the connections of the keys not led anymore are removed.
+type LeaderAwareConnectionPool struct {
+	ControlPlaneConnectionPool
+
+	options leaderAwareConnectionPoolOptions
+	leader  pkgreconciler.LeaderAwareFuncs
+
+	// keys contains the keys connected through this pool, which must be checked on demotion
+	keysLock sync.Mutex
+	keys     map[string]struct{}
+}
+
+var (
+	_ ControlPlaneConnectionPool = (*LeaderAwareConnectionPool)(nil)
+	_ ConnectionStateObserver    = (*LeaderAwareConnectionPool)(nil)
+	_ Broadcaster                = (*LeaderAwareConnectionPool)(nil)
+	_ CredentialsRotator         = (*LeaderAwareConnectionPool)(nil)
+	_ pkgreconciler.LeaderAware  = (*LeaderAwareConnectionPool)(nil)
+)
+
+// NewLeaderAwareConnectionPool wraps pool, opening its connections only for the keys led by this replica.
+func NewLeaderAwareConnectionPool(pool ControlPlaneConnectionPool, opts ...LeaderAwareConnectionPoolOption) *LeaderAwareConnectionPool {
+	options := leaderAwareConnectionPoolOptions{
+		keyParser: parseNamespacedName,
+	}
+	for _, fn := range opts {
+		fn(&options)
+	}
+
+	p := &LeaderAwareConnectionPool{
+		ControlPlaneConnectionPool: pool,
+		options:                    options,
+		keys:                       make(map[string]struct{}),
+	}
+	p.leader.DemoteFunc = p.onDemote
+	return p
+}
+
+// Promote implements pkgreconciler.LeaderAware
+func (p *LeaderAwareConnectionPool) Promote(b pkgreconciler.Bucket, enq func(pkgreconciler.Bucket, types.NamespacedName)) error {
+	return p.leader.Promote(b, enq)
+}
+
+// Demote implements pkgreconciler.LeaderAware
+func (p *LeaderAwareConnectionPool) Demote(b pkgreconciler.Bucket) {
+	p.leader.Demote(b)
+}
+
+// IsLeaderFor returns true if this replica is the leader of the bucket of the key
+func (p *LeaderAwareConnectionPool) IsLeaderFor(key string) bool {
+	nn, err := p.options.keyParser(key)
+	if err != nil {
+		return false
+	}
+	return p.leader.IsLeaderFor(nn)
+}
+
+// ReconcileConnections reconciles the connections for key like ControlPlaneConnectionPool.ReconcileConnections,
+// only if this replica is the leader of key. Otherwise, the connections for key are removed and ErrNotLeader is returned.
+func (p *LeaderAwareConnectionPool) ReconcileConnections(ctx context.Context, key string, wantConnections []string, newServiceCb func(string, control.Service), oldServiceCb func(string)) (map[string]control.Service, error) {
+	if _, err := p.options.keyParser(key); err != nil {
+		return nil, fmt.Errorf("invalid pool key %q: %w", key, err)
+	}
+	if !p.IsLeaderFor(key) {
+		logging.FromContext(ctx).Debugf("Not the leader of %s, skipping the connections", key)
+		p.dropKey(ctx, key, oldServiceCb)
+		return nil, fmt.Errorf("cannot reconcile the connections for key %s: %w", key, ErrNotLeader)
+	}
+
+	// The key is tracked before dialing, so that a concurrent demotion removes its connections
+	p.trackKey(key)
+	services, err := p.ControlPlaneConnectionPool.ReconcileConnections(ctx, key, wantConnections, newServiceCb, oldServiceCb)
+
+	if !p.IsLeaderFor(key) {
+		// Demoted while dialing
+		logging.FromContext(ctx).Debugf("Not the leader of %s anymore, removing the connections", key)
+		p.dropKey(ctx, key, oldServiceCb)
+		return nil, fmt.Errorf("cannot reconcile the connections for key %s: %w", key, ErrNotLeader)
+	}
+	return services, err
+}
+
+// DialControlService dials the control service like ControlPlaneConnectionPool.DialControlService,
+// only if this replica is the leader of key. Otherwise, it returns ErrNotLeader.
+func (p *LeaderAwareConnectionPool) DialControlService(ctx context.Context, key string, host string) (string, control.Service, error) {
+	if _, err := p.options.keyParser(key); err != nil {
+		return "", nil, fmt.Errorf("invalid pool key %q: %w", key, err)
+	}
+	if !p.IsLeaderFor(key) {
+		return "", nil, fmt.Errorf("cannot dial %s for key %s: %w", host, key, ErrNotLeader)
+	}
+
+	p.trackKey(key)
+	h, svc, err := p.ControlPlaneConnectionPool.DialControlService(ctx, key, host)
+	if err == nil && !p.IsLeaderFor(key) {
+		p.ControlPlaneConnectionPool.RemoveConnection(ctx, key, host)
+		return "", nil, fmt.Errorf("cannot dial %s for key %s: %w", host, key, ErrNotLeader)
+	}
+	return h, svc, err
+}
+
+// RemoveAllConnections implements ControlPlaneConnectionPool
+func (p *LeaderAwareConnectionPool) RemoveAllConnections(ctx context.Context, key string) {
+	p.keysLock.Lock()
+	delete(p.keys, key)
+	p.keysLock.Unlock()
+
+	p.ControlPlaneConnectionPool.RemoveAllConnections(ctx, key)
+}
+
+// Close implements ControlPlaneConnectionPool
+func (p *LeaderAwareConnectionPool) Close(ctx context.Context) {
+	p.keysLock.Lock()
+	p.keys = make(map[string]struct{})
+	p.keysLock.Unlock()
+
+	p.ControlPlaneConnectionPool.Close(ctx)
+}
+
+// GetConnectionState implements ConnectionStateObserver, if the wrapped pool implements it.
+// Otherwise, no connection is found.
+func (p *LeaderAwareConnectionPool) GetConnectionState(key string, host string) (network.ConnectionState, bool) {
+	observer, ok := p.ControlPlaneConnectionPool.(ConnectionStateObserver)
+	if !ok {
+		return network.ConnectionClosed, false
+	}
+	return observer.GetConnectionState(key, host)
+}
+
+// SubscribeConnectionState implements ConnectionStateObserver, if the wrapped pool implements it.
+// Otherwise, callback is never invoked.
+func (p *LeaderAwareConnectionPool) SubscribeConnectionState(callback ConnectionStateChangeCallback) (unsubscribe func()) {
+	observer, ok := p.ControlPlaneConnectionPool.(ConnectionStateObserver)
+	if !ok {
+		return func() {}
+	}
+	return observer.SubscribeConnectionState(callback)
+}
+
+// Broadcast implements Broadcaster, sending the message to the services returned by GetServices
+// if the wrapped pool doesn't implement it.
+func (p *LeaderAwareConnectionPool) Broadcast(ctx context.Context, key string, opcode control.OpCode, payload encoding.BinaryMarshaler) *BroadcastResult {
+	if broadcaster, ok := p.ControlPlaneConnectionPool.(Broadcaster); ok {
+		return broadcaster.Broadcast(ctx, key, opcode, payload)
+	}
+	return broadcast(ctx, p.GetServices(key), opcode, payload)
+}
+
+// RotateCredentials implements CredentialsRotator, if the wrapped pool implements it.
+// Otherwise, it returns an error.
+func (p *LeaderAwareConnectionPool) RotateCredentials(ctx context.Context) error {
+	rotator, ok := p.ControlPlaneConnectionPool.(CredentialsRotator)
+	if !ok {
+		return fmt.Errorf("the pool %T doesn't support the credentials rotation", p.ControlPlaneConnectionPool)
+	}
+	return rotator.RotateCredentials(ctx)
+}
+
+func (p *LeaderAwareConnectionPool) onDemote(b pkgreconciler.Bucket) {
+	p.keysLock.Lock()
+	var notLed []string
+	for key := range p.keys {
+		if !p.IsLeaderFor(key) {
+			notLed = append(notLed, key)
+		}
+	}
+	p.keysLock.Unlock()
+
+	// Demote doesn't provide a context
+	ctx := context.Background()
+	for _, key := range notLed {
+		logging.FromContext(ctx).Infof("Demoted from bucket %s, removing the connections for key %s", b.Name(), key)
+		p.RemoveAllConnections(ctx, key)
+	}
+}
+
+func (p *LeaderAwareConnectionPool) trackKey(key string) {
+	p.keysLock.Lock()
+	p.keys[key] = struct{}{}
+	p.keysLock.Unlock()
+}
+
+// dropKey removes all the connections for key, invoking oldServiceCb for every removed host
+func (p *LeaderAwareConnectionPool) dropKey(ctx context.Context, key string, oldServiceCb func(string)) {
+	if oldServiceCb != nil {
+		for _, host := range p.GetConnectedHosts(key) {
+			oldServiceCb(host)
+		}
+	}
+	p.RemoveAllConnections(ctx, key)
+}
+
+func parseNamespacedName(key string) (types.NamespacedName, error) {
+	namespace, name, err := cache.SplitMetaNamespaceKey(key)
+	if err != nil {
+		return types.NamespacedName{}, err
+	}
+	if namespace == "" || name == "" {
+		// Otherwise all the keys without a namespace would share the buckets of the cluster-scoped resources
+		return types.NamespacedName{}, fmt.Errorf("the key %q is not in the form namespace/name", key)
+	}
+	return types.NamespacedName{Namespace: namespace, Name: name}, nil
+}
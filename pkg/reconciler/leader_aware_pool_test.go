@@ -0,0 +1,174 @@
+/*
+Copyright 2026 The Knative Authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package reconciler_test
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+	"k8s.io/apimachinery/pkg/types"
+	pkgreconciler "knative.dev/pkg/reconciler"
+
+	control "knative.dev/control-protocol/pkg"
+	"knative.dev/control-protocol/pkg/network"
+	"knative.dev/control-protocol/pkg/reconciler"
+	"knative.dev/control-protocol/pkg/test"
+)
+
+// namespaceBucket contains all the keys of a namespace
+type namespaceBucket string
+
+func (b namespaceBucket) Name() string {
+	return string(b)
+}
+
+func (b namespaceBucket) Has(key types.NamespacedName) bool {
+	return key.Namespace == string(b)
+}
+
+func TestLeaderAwareConnectionPool(t *testing.T) {
+	ctx, cancelFn := context.WithCancel(context.TODO())
+	t.Cleanup(cancelFn)
+
+	memoryNetwork := network.NewMemoryNetwork()
+	for _, address := range []string{"10.0.0.1:9000", "10.0.0.2:9000"} {
+		ln, err := memoryNetwork.Listen(address)
+		require.NoError(t, err)
+		_, err = network.StartInsecureControlServer(ctx, network.WithListener(ln))
+		require.NoError(t, err)
+	}
+
+	connectionPool := reconciler.NewLeaderAwareConnectionPool(
+		reconciler.NewInsecureControlPlaneConnectionPool(reconciler.WithDialer(memoryNetwork)),
+	)
+	t.Cleanup(func() {
+		connectionPool.Close(ctx)
+	})
+
+	// Not the leader yet
+	services, err := connectionPool.ReconcileConnections(ctx, "ns-a/broker", []string{"10.0.0.1:9000"}, nil, nil)
+	require.ErrorIs(t, err, reconciler.ErrNotLeader)
+	require.Empty(t, services)
+	require.Empty(t, connectionPool.GetConnectedHosts("ns-a/broker"))
+	_, _, err = connectionPool.DialControlService(ctx, "ns-a/broker", "10.0.0.1:9000")
+	require.ErrorIs(t, err, reconciler.ErrNotLeader)
+
+	_, err = connectionPool.ReconcileConnections(ctx, "invalid/pool/key", []string{"10.0.0.1:9000"}, nil, nil)
+	require.Error(t, err)
+	_, err = connectionPool.ReconcileConnections(ctx, "broker", []string{"10.0.0.1:9000"}, nil, nil)
+	require.ErrorContains(t, err, "not in the form namespace/name")
+	_, _, err = connectionPool.DialControlService(ctx, "broker", "10.0.0.1:9000")
+	require.ErrorContains(t, err, "not in the form namespace/name")
+
+	// Leading both namespaces
+	require.NoError(t, connectionPool.Promote(namespaceBucket("ns-a"), nil))
+	require.NoError(t, connectionPool.Promote(namespaceBucket("ns-b"), nil))
+	require.True(t, connectionPool.IsLeaderFor("ns-a/broker"))
+
+	services, err = connectionPool.ReconcileConnections(ctx, "ns-a/broker", []string{"10.0.0.1:9000"}, nil, nil)
+	require.NoError(t, err)
+	require.Len(t, services, 1)
+	_, _, err = connectionPool.DialControlService(ctx, "ns-b/broker", "10.0.0.2:9000")
+	require.NoError(t, err)
+
+	// Demoted from ns-a
+	connectionPool.Demote(namespaceBucket("ns-a"))
+	require.False(t, connectionPool.IsLeaderFor("ns-a/broker"))
+	require.Empty(t, connectionPool.GetConnectedHosts("ns-a/broker"))
+	require.Equal(t, []string{"10.0.0.2:9000"}, connectionPool.GetConnectedHosts("ns-b/broker"))
+
+	services, err = connectionPool.ReconcileConnections(ctx, "ns-a/broker", []string{"10.0.0.1:9000"}, nil, nil)
+	require.ErrorIs(t, err, reconciler.ErrNotLeader)
+	require.Empty(t, services)
+
+	// Promoted again, the connections are opened at the next reconcile
+	require.NoError(t, connectionPool.Promote(pkgreconciler.UniversalBucket(), nil))
+
+	// Still leading ns-b through the universal bucket
+	connectionPool.Demote(namespaceBucket("ns-b"))
+	require.Equal(t, []string{"10.0.0.2:9000"}, connectionPool.GetConnectedHosts("ns-b/broker"))
+
+	services, err = connectionPool.ReconcileConnections(ctx, "ns-a/broker", []string{"10.0.0.1:9000"}, nil, nil)
+	require.NoError(t, err)
+	require.Len(t, services, 1)
+}
+
+func TestLeaderAwareConnectionPool_WithPoolKeyParser(t *testing.T) {
+	ctx, cancelFn := context.WithCancel(context.TODO())
+	t.Cleanup(cancelFn)
+
+	memoryNetwork := network.NewMemoryNetwork()
+	ln, err := memoryNetwork.Listen("10.0.0.1:9000")
+	require.NoError(t, err)
+	_, err = network.StartInsecureControlServer(ctx, network.WithListener(ln))
+	require.NoError(t, err)
+
+	connectionPool := reconciler.NewLeaderAwareConnectionPool(
+		reconciler.NewInsecureControlPlaneConnectionPool(reconciler.WithDialer(memoryNetwork)),
+		reconciler.WithPoolKeyParser(func(key string) (types.NamespacedName, error) {
+			return types.NamespacedName{Name: key}, nil
+		}),
+	)
+	t.Cleanup(func() {
+		connectionPool.Close(ctx)
+	})
+	require.NoError(t, connectionPool.Promote(pkgreconciler.UniversalBucket(), nil))
+
+	services, err := connectionPool.ReconcileConnections(ctx, "broker", []string{"10.0.0.1:9000"}, nil, nil)
+	require.NoError(t, err)
+	require.Len(t, services, 1)
+}
+
+// plainPool implements only ControlPlaneConnectionPool, hiding the optional interfaces of the wrapped pool
+type plainPool struct {
+	reconciler.ControlPlaneConnectionPool
+}
+
+func TestLeaderAwareConnectionPool_OptionalInterfaces(t *testing.T) {
+	ctx, cancelFn := context.WithCancel(context.TODO())
+	t.Cleanup(cancelFn)
+
+	memoryNetwork := network.NewMemoryNetwork()
+	ln, err := memoryNetwork.Listen("10.0.0.1:9000")
+	require.NoError(t, err)
+	server, err := network.StartInsecureControlServer(ctx, network.WithListener(ln))
+	require.NoError(t, err)
+	server.MessageHandler(control.MessageHandlerFunc(func(ctx context.Context, message control.ServiceMessage) {
+		message.Ack()
+	}))
+
+	connectionPool := reconciler.NewLeaderAwareConnectionPool(plainPool{
+		reconciler.NewInsecureControlPlaneConnectionPool(reconciler.WithDialer(memoryNetwork)),
+	})
+	t.Cleanup(func() {
+		connectionPool.Close(ctx)
+	})
+	require.NoError(t, connectionPool.Promote(pkgreconciler.UniversalBucket(), nil))
+	_, err = connectionPool.ReconcileConnections(ctx, "ns-a/broker", []string{"10.0.0.1:9000"}, nil, nil)
+	require.NoError(t, err)
+
+	// Without the optional interfaces, the wrapped pool can still broadcast through its services
+	result := connectionPool.Broadcast(ctx, "ns-a/broker", 1, test.SomeMockPayload)
+	require.True(t, result.AllSucceeded())
+	require.Equal(t, 1, result.Total())
+
+	_, ok := connectionPool.GetConnectionState("ns-a/broker", "10.0.0.1:9000")
+	require.False(t, ok)
+	connectionPool.SubscribeConnectionState(func(string, string, network.ConnectionState) {})()
+	require.ErrorContains(t, connectionPool.RotateCredentials(ctx), "doesn't support the credentials rotation")
+}
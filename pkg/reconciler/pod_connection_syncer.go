@@ -0,0 +1,230 @@
+/*
+Copyright 2026 The Knative Authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package reconciler
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/labels"
+	"k8s.io/client-go/tools/cache"
+	"k8s.io/utils/clock"
+	podinformer "knative.dev/pkg/client/injection/kube/informers/core/v1/pod"
+	"knative.dev/pkg/controller"
+	"knative.dev/pkg/logging"
+
+	control "knative.dev/control-protocol/pkg"
+	"knative.dev/control-protocol/pkg/network"
+)
+
+const (
+	defaultPodSyncRetryInterval = 5 * time.Second
+)
+
+type podConnectionSyncerOptions struct {
+	newServiceCb     func(string, control.Service)
+	oldServiceCb     func(string)
+	endpointsOptions []PodEndpointOption
+	retryInterval    time.Duration
+	clock            clock.WithDelayedExecution
+}
+
+type PodConnectionSyncerOption func(*podConnectionSyncerOptions)
+
+// WithNewServiceCallback sets the callback invoked for every new connection, look at ReconcileConnections
+func WithNewServiceCallback(newServiceCb func(string, control.Service)) PodConnectionSyncerOption {
+	return func(options *podConnectionSyncerOptions) {
+		options.newServiceCb = newServiceCb
+	}
+}
+
+// WithOldServiceCallback sets the callback invoked for every removed connection, look at ReconcileConnections
+func WithOldServiceCallback(oldServiceCb func(string)) PodConnectionSyncerOption {
+	return func(options *podConnectionSyncerOptions) {
+		options.oldServiceCb = oldServiceCb
+	}
+}
+
+// WithUnreadyPods makes the syncer connect also to the pods not ready yet, as long as they have an ip
+func WithUnreadyPods() PodConnectionSyncerOption {
+	return WithPodEndpointOptions(WithUnreadyPodEndpoints())
+}
+
+// WithPodEndpointOptions configures how the endpoints of the pods are discovered, look at PodIpGetter.GetPodEndpoints
+func WithPodEndpointOptions(opts ...PodEndpointOption) PodConnectionSyncerOption {
+	return func(options *podConnectionSyncerOptions) {
+		options.endpointsOptions = append(options.endpointsOptions, opts...)
+	}
+}
+
+// WithSyncRetryInterval sets how long the syncer waits before retrying a failed sync. Defaults to 5 seconds.
+func WithSyncRetryInterval(retryInterval time.Duration) PodConnectionSyncerOption {
+	return func(options *podConnectionSyncerOptions) {
+		options.retryInterval = retryInterval
+	}
+}
+
+// WithSyncClock sets the clock used to wait before retrying a failed sync.
+// Tests can provide a fake clock, to advance the time deterministically.
+func WithSyncClock(clock clock.WithDelayedExecution) PodConnectionSyncerOption {
+	return func(options *podConnectionSyncerOptions) {
+		options.clock = clock
+	}
+}
+
+// PodConnectionSyncer keeps the connections of a pool key in sync with the pods matching a selector.
+type PodConnectionSyncer struct {
+	pool      ControlPlaneConnectionPool
+	key       string
+	namespace string
+	selector  labels.Selector
+	ipGetter  PodIpGetter
+	options   podConnectionSyncerOptions
+
+	trigger chan struct{}
+}
+
+// StartPodConnectionSyncer watches the pods in namespace matching selector with the injected pod informer,
+// and reconciles the connections of the pool for key every time they change.
+// The pods are connected through the endpoints returned by PodIpGetter.GetPodEndpoints,
+// so by default only the ready pods are connected, unless WithUnreadyPods is provided.
+// When pool implements ConnectionStateObserver, the connections evicted by the pool because broken are redialed.
+// The failed syncs are retried.
+// The syncer stops when ctx is done, removing the connections for key.
+func StartPodConnectionSyncer(ctx context.Context, pool ControlPlaneConnectionPool, key string, namespace string, selector labels.Selector, opts ...PodConnectionSyncerOption) (*PodConnectionSyncer, error) {
+	options := podConnectionSyncerOptions{
+		retryInterval: defaultPodSyncRetryInterval,
+		clock:         clock.RealClock{},
+	}
+	for _, fn := range opts {
+		fn(&options)
+	}
+
+	informer := podinformer.Get(ctx)
+	s := &PodConnectionSyncer{
+		pool:      pool,
+		key:       key,
+		namespace: namespace,
+		selector:  selector,
+		ipGetter:  PodIpGetter{Lister: informer.Lister()},
+		options:   options,
+		trigger:   make(chan struct{}, 1),
+	}
+
+	registration, err := informer.Informer().AddEventHandler(cache.FilteringResourceEventHandler{
+		FilterFunc: s.filter,
+		Handler: controller.HandleAll(func(interface{}) {
+			s.Resync()
+		}),
+	})
+	if err != nil {
+		return nil, err
+	}
+	unsubscribe := func() {}
+	if observer, ok := pool.(ConnectionStateObserver); ok {
+		unsubscribe = observer.SubscribeConnectionState(func(k string, host string, state network.ConnectionState) {
+			if k == key && state == network.ConnectionBroken {
+				s.Resync()
+			}
+		})
+	}
+
+	go func() {
+		s.run(ctx)
+		unsubscribe()
+		// The connections are owned by the pool, which outlives ctx
+		pool.RemoveAllConnections(context.Background(), key)
+		if err := informer.Informer().RemoveEventHandler(registration); err != nil {
+			logging.FromContext(ctx).Warnf("Cannot remove the pod event handler for key %s: %v", key, err)
+		}
+	}()
+
+	s.Resync()
+	return s, nil
+}
+
+// Resync schedules the reconciliation of the connections
+func (s *PodConnectionSyncer) Resync() {
+	select {
+	case s.trigger <- struct{}{}:
+	default:
+		// A sync is already scheduled
+	}
+}
+
+func (s *PodConnectionSyncer) run(ctx context.Context) {
+	var retry clock.Timer
+	stopRetry := func() {
+		if retry != nil {
+			retry.Stop()
+			retry = nil
+		}
+	}
+	defer stopRetry()
+
+	for {
+		var retryCh <-chan time.Time
+		if retry != nil {
+			retryCh = retry.C()
+		}
+		select {
+		case <-ctx.Done():
+			return
+		case <-s.trigger:
+		case <-retryCh:
+		}
+
+		stopRetry()
+		if err := s.sync(ctx); err != nil {
+			if errors.Is(err, ErrNotLeader) {
+				// Retrying, so the connections are opened after the promotion
+				logging.FromContext(ctx).Debugf("Not the leader of key %s, retrying in %v", s.key, s.options.retryInterval)
+			} else {
+				logging.FromContext(ctx).Warnf("Cannot sync the connections for key %s, retrying in %v: %v", s.key, s.options.retryInterval, err)
+			}
+			retry = s.options.clock.NewTimer(s.options.retryInterval)
+		}
+	}
+}
+
+func (s *PodConnectionSyncer) sync(ctx context.Context) error {
+	endpoints, err := s.ipGetter.GetPodEndpoints(s.namespace, s.selector, s.options.endpointsOptions...)
+	if err != nil {
+		return err
+	}
+
+	hosts := make([]string, 0, len(endpoints))
+	for _, e := range endpoints {
+		hosts = append(hosts, e.Host)
+	}
+
+	_, err = s.pool.ReconcileConnections(ctx, s.key, hosts, s.options.newServiceCb, s.options.oldServiceCb)
+	return err
+}
+
+func (s *PodConnectionSyncer) filter(obj interface{}) bool {
+	if tombstone, ok := obj.(cache.DeletedFinalStateUnknown); ok {
+		obj = tombstone.Obj
+	}
+	pod, ok := obj.(*corev1.Pod)
+	if !ok {
+		return false
+	}
+	return pod.Namespace == s.namespace && s.selector.Matches(labels.Set(pod.Labels))
+}
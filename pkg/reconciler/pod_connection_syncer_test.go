@@ -0,0 +1,186 @@
+/*
+Copyright 2026 The Knative Authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package reconciler_test
+
+import (
+	"context"
+	"errors"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/labels"
+	"k8s.io/client-go/rest"
+	clocktesting "k8s.io/utils/clock/testing"
+	fakekubeclient "knative.dev/pkg/client/injection/kube/client/fake"
+	_ "knative.dev/pkg/client/injection/kube/informers/core/v1/pod/fake"
+	"knative.dev/pkg/controller"
+	"knative.dev/pkg/injection"
+
+	control "knative.dev/control-protocol/pkg"
+	"knative.dev/control-protocol/pkg/network"
+	"knative.dev/control-protocol/pkg/reconciler"
+)
+
+func readyPod(namespace string, name string, ip string, podLabels map[string]string) *corev1.Pod {
+	return &corev1.Pod{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      name,
+			Namespace: namespace,
+			Labels:    podLabels,
+		},
+		Status: corev1.PodStatus{
+			Phase: corev1.PodRunning,
+			PodIP: ip,
+			Conditions: []corev1.PodCondition{{
+				Type:   corev1.PodReady,
+				Status: corev1.ConditionTrue,
+			}},
+		},
+	}
+}
+
+func TestPodConnectionSyncer(t *testing.T) {
+	namespace := "abc"
+	podLabels := map[string]string{"app": "dispatcher"}
+
+	ctx, cancelFn := context.WithCancel(context.TODO())
+	t.Cleanup(cancelFn)
+	ctx, informers := injection.Fake.SetupInformers(ctx, &rest.Config{})
+	require.NoError(t, controller.StartInformers(ctx.Done(), informers...))
+
+	memoryNetwork := network.NewMemoryNetwork()
+	for _, address := range []string{"10.0.0.1:9000", "10.0.0.2:9000"} {
+		ln, err := memoryNetwork.Listen(address)
+		require.NoError(t, err)
+		_, err = network.StartInsecureControlServer(ctx, network.WithListener(ln))
+		require.NoError(t, err)
+	}
+
+	connectionPool := reconciler.NewInsecureControlPlaneConnectionPool(reconciler.WithDialer(memoryNetwork))
+	t.Cleanup(func() {
+		connectionPool.Close(ctx)
+	})
+
+	_, err := reconciler.StartPodConnectionSyncer(ctx, connectionPool, "hello", namespace, labels.SelectorFromSet(podLabels))
+	require.NoError(t, err)
+
+	requireConnectedHosts := func(hosts ...string) {
+		require.Eventually(t, func() bool {
+			connected := connectionPool.GetConnectedHosts("hello")
+			if len(connected) != len(hosts) {
+				return false
+			}
+			for _, h := range hosts {
+				if _, svc := connectionPool.ResolveControlInterface("hello", h); svc == nil {
+					return false
+				}
+			}
+			return true
+		}, 5*time.Second, 10*time.Millisecond)
+	}
+
+	pods := fakekubeclient.Get(ctx).CoreV1().Pods(namespace)
+
+	podA := readyPod(namespace, "pod-a", "10.0.0.1", podLabels)
+	_, err = pods.Create(ctx, podA, metav1.CreateOptions{})
+	require.NoError(t, err)
+
+	// Not matching the selector
+	_, err = pods.Create(ctx, readyPod(namespace, "pod-other", "10.0.0.2", map[string]string{"app": "other"}), metav1.CreateOptions{})
+	require.NoError(t, err)
+
+	// Not ready
+	podB := readyPod(namespace, "pod-b", "10.0.0.2", podLabels)
+	podB.Status.Conditions[0].Status = corev1.ConditionFalse
+	podB, err = pods.Create(ctx, podB, metav1.CreateOptions{})
+	require.NoError(t, err)
+
+	requireConnectedHosts("10.0.0.1:9000")
+
+	podB = podB.DeepCopy()
+	podB.Status.Conditions[0].Status = corev1.ConditionTrue
+	_, err = pods.UpdateStatus(ctx, podB, metav1.UpdateOptions{})
+	require.NoError(t, err)
+
+	requireConnectedHosts("10.0.0.1:9000", "10.0.0.2:9000")
+
+	require.NoError(t, pods.Delete(ctx, podA.Name, metav1.DeleteOptions{}))
+
+	requireConnectedHosts("10.0.0.2:9000")
+}
+
+// failingReconcilePool fails the reconciliations while failing is set
+type failingReconcilePool struct {
+	reconciler.ControlPlaneConnectionPool
+	failing int32
+}
+
+func (p *failingReconcilePool) ReconcileConnections(ctx context.Context, key string, wantConnections []string, newServiceCb func(string, control.Service), oldServiceCb func(string)) (map[string]control.Service, error) {
+	if atomic.LoadInt32(&p.failing) == 1 {
+		return nil, errors.New("failed on purpose")
+	}
+	return p.ControlPlaneConnectionPool.ReconcileConnections(ctx, key, wantConnections, newServiceCb, oldServiceCb)
+}
+
+func TestPodConnectionSyncer_RetryFailedSync(t *testing.T) {
+	namespace := "abc"
+	podLabels := map[string]string{"app": "dispatcher"}
+
+	ctx, cancelFn := context.WithCancel(context.TODO())
+	t.Cleanup(cancelFn)
+	ctx, informers := injection.Fake.SetupInformers(ctx, &rest.Config{})
+	require.NoError(t, controller.StartInformers(ctx.Done(), informers...))
+
+	memoryNetwork := network.NewMemoryNetwork()
+	ln, err := memoryNetwork.Listen("10.0.0.1:9000")
+	require.NoError(t, err)
+	_, err = network.StartInsecureControlServer(ctx, network.WithListener(ln))
+	require.NoError(t, err)
+
+	connectionPool := &failingReconcilePool{
+		ControlPlaneConnectionPool: reconciler.NewInsecureControlPlaneConnectionPool(reconciler.WithDialer(memoryNetwork)),
+	}
+	t.Cleanup(func() {
+		connectionPool.Close(ctx)
+	})
+
+	fakeClock := clocktesting.NewFakeClock(time.Now())
+	_, err = reconciler.StartPodConnectionSyncer(ctx, connectionPool, "hello", namespace, labels.SelectorFromSet(podLabels),
+		reconciler.WithSyncRetryInterval(time.Minute),
+		reconciler.WithSyncClock(fakeClock),
+	)
+	require.NoError(t, err)
+
+	atomic.StoreInt32(&connectionPool.failing, 1)
+	_, err = fakekubeclient.Get(ctx).CoreV1().Pods(namespace).Create(ctx, readyPod(namespace, "pod-a", "10.0.0.1", podLabels), metav1.CreateOptions{})
+	require.NoError(t, err)
+
+	// The sync fails, and the retry waits for the clock
+	require.Eventually(t, fakeClock.HasWaiters, 5*time.Second, 10*time.Millisecond)
+	require.Empty(t, connectionPool.GetConnectedHosts("hello"))
+
+	atomic.StoreInt32(&connectionPool.failing, 0)
+	fakeClock.Step(time.Minute)
+	require.Eventually(t, func() bool {
+		return len(connectionPool.GetConnectedHosts("hello")) == 1
+	}, 5*time.Second, 10*time.Millisecond)
+	require.False(t, fakeClock.HasWaiters())
+}
@@ -17,8 +17,15 @@ limitations under the License.
 package reconciler
 
 import (
+	"net"
+	"strconv"
+
+	corev1 "k8s.io/api/core/v1"
 	"k8s.io/apimachinery/pkg/labels"
 	v1 "k8s.io/client-go/listers/core/v1"
+	utilsnet "k8s.io/utils/net"
+
+	"knative.dev/control-protocol/pkg/network"
 )
 
 type PodIpGetter struct {
@@ -40,3 +47,170 @@ func (ipGetter PodIpGetter) GetAllPodsIp(namespace string, selector labels.Selec
 	}
 	return ips, nil
 }
+
+// PodEndpoint is the control endpoint of a pod
+type PodEndpoint struct {
+	// PodName is the name of the pod
+	PodName string
+	// IP is the pod ip, in the ip family requested with WithIPFamily
+	IP string
+	// Host is the address to dial, in the form host:port, with IPv6 addresses bracketed
+	Host string
+	// Ready is true if the pod is running and ready
+	Ready bool
+}
+
+type podEndpointOptions struct {
+	portName     string
+	port         int
+	ipFamily     corev1.IPFamily
+	requireReady bool
+	phases       []corev1.PodPhase
+}
+
+type PodEndpointOption func(*podEndpointOptions)
+
+// WithNamedPort resolves the port of the endpoints from the container port with the provided name.
+// The pods without a container port with that name are skipped.
+func WithNamedPort(name string) PodEndpointOption {
+	return func(options *podEndpointOptions) {
+		options.portName = name
+	}
+}
+
+// WithEndpointPort sets the port of the endpoints, when WithNamedPort is not provided. Defaults to network.DefaultControlPort.
+func WithEndpointPort(port int) PodEndpointOption {
+	return func(options *podEndpointOptions) {
+		options.port = port
+	}
+}
+
+// WithIPFamily picks the pod ip of the provided family from the pod ips, skipping the pods without an ip of that family.
+// By default, the primary pod ip is used.
+func WithIPFamily(family corev1.IPFamily) PodEndpointOption {
+	return func(options *podEndpointOptions) {
+		options.ipFamily = family
+	}
+}
+
+// WithUnreadyPodEndpoints returns also the endpoints of the pods not ready
+func WithUnreadyPodEndpoints() PodEndpointOption {
+	return func(options *podEndpointOptions) {
+		options.requireReady = false
+	}
+}
+
+// WithPodPhases returns only the endpoints of the pods in one of the provided phases
+func WithPodPhases(phases ...corev1.PodPhase) PodEndpointOption {
+	return func(options *podEndpointOptions) {
+		options.phases = phases
+	}
+}
+
+// GetPodEndpoints returns the control endpoints of the pods in namespace matching selector.
+// Pods being deleted or without an ip are always skipped, and by default only the ready pods are returned.
+func (ipGetter PodIpGetter) GetPodEndpoints(namespace string, selector labels.Selector, opts ...PodEndpointOption) ([]PodEndpoint, error) {
+	options := podEndpointOptions{
+		port:         network.DefaultControlPort,
+		requireReady: true,
+	}
+	for _, fn := range opts {
+		fn(&options)
+	}
+
+	pods, err := ipGetter.Lister.Pods(namespace).List(selector)
+	if err != nil {
+		return nil, err
+	}
+
+	endpoints := make([]PodEndpoint, 0, len(pods))
+	for _, p := range pods {
+		endpoint, ok := podEndpoint(p, &options)
+		if ok {
+			endpoints = append(endpoints, endpoint)
+		}
+	}
+	return endpoints, nil
+}
+
+func podEndpoint(pod *corev1.Pod, options *podEndpointOptions) (PodEndpoint, bool) {
+	if pod.DeletionTimestamp != nil {
+		return PodEndpoint{}, false
+	}
+	if len(options.phases) != 0 && !containsPhase(options.phases, pod.Status.Phase) {
+		return PodEndpoint{}, false
+	}
+	ready := isPodReady(pod)
+	if options.requireReady && !ready {
+		return PodEndpoint{}, false
+	}
+
+	ip := podIP(pod, options.ipFamily)
+	if ip == "" {
+		return PodEndpoint{}, false
+	}
+
+	port := options.port
+	if options.portName != "" {
+		var ok bool
+		if port, ok = namedContainerPort(pod, options.portName); !ok {
+			return PodEndpoint{}, false
+		}
+	}
+
+	return PodEndpoint{
+		PodName: pod.Name,
+		IP:      ip,
+		Host:    net.JoinHostPort(ip, strconv.Itoa(port)),
+		Ready:   ready,
+	}, true
+}
+
+// podIP returns the pod ip of the provided family, or the primary pod ip if family is empty
+func podIP(pod *corev1.Pod, family corev1.IPFamily) string {
+	if family == "" {
+		return pod.Status.PodIP
+	}
+	ips := pod.Status.PodIPs
+	if len(ips) == 0 && pod.Status.PodIP != "" {
+		ips = []corev1.PodIP{{IP: pod.Status.PodIP}}
+	}
+	for _, ip := range ips {
+		if utilsnet.IsIPv6String(ip.IP) == (family == corev1.IPv6Protocol) {
+			return ip.IP
+		}
+	}
+	return ""
+}
+
+func namedContainerPort(pod *corev1.Pod, name string) (int, bool) {
+	for _, c := range pod.Spec.Containers {
+		for _, p := range c.Ports {
+			if p.Name == name {
+				return int(p.ContainerPort), true
+			}
+		}
+	}
+	return 0, false
+}
+
+func containsPhase(phases []corev1.PodPhase, phase corev1.PodPhase) bool {
+	for _, p := range phases {
+		if p == phase {
+			return true
+		}
+	}
+	return false
+}
+
+func isPodReady(pod *corev1.Pod) bool {
+	if pod.Status.Phase != corev1.PodRunning {
+		return false
+	}
+	for _, c := range pod.Status.Conditions {
+		if c.Type == corev1.PodReady {
+			return c.Status == corev1.ConditionTrue
+		}
+	}
+	return false
+}
@@ -93,3 +93,130 @@ func TestPodIpGetter_GetAllPodsIp(t *testing.T) {
 		})
 	}
 }
+
+func TestPodIpGetter_GetPodEndpoints(t *testing.T) {
+	namespace := "abc"
+
+	pod := func(name string, ip string, mutators ...func(*corev1.Pod)) *corev1.Pod {
+		p := &corev1.Pod{
+			ObjectMeta: metav1.ObjectMeta{
+				Name:      name,
+				Namespace: namespace,
+			},
+			Spec: corev1.PodSpec{
+				Containers: []corev1.Container{{
+					Name: "dispatcher",
+					Ports: []corev1.ContainerPort{{
+						Name:          "control",
+						ContainerPort: 10000,
+					}},
+				}},
+			},
+			Status: corev1.PodStatus{
+				Phase: corev1.PodRunning,
+				PodIP: ip,
+				Conditions: []corev1.PodCondition{{
+					Type:   corev1.PodReady,
+					Status: corev1.ConditionTrue,
+				}},
+			},
+		}
+		for _, fn := range mutators {
+			fn(p)
+		}
+		return p
+	}
+	notReady := func(p *corev1.Pod) {
+		p.Status.Conditions[0].Status = corev1.ConditionFalse
+	}
+	dualStack := func(p *corev1.Pod) {
+		p.Status.PodIPs = []corev1.PodIP{{IP: p.Status.PodIP}, {IP: "fd00::1"}}
+	}
+
+	tests := []struct {
+		name string
+		pods []*corev1.Pod
+		opts []reconciler.PodEndpointOption
+		want []reconciler.PodEndpoint
+	}{{
+		name: "no pods",
+		want: []reconciler.PodEndpoint{},
+	}, {
+		name: "ready pod with default port",
+		pods: []*corev1.Pod{pod("pod-a", "10.0.0.1")},
+		want: []reconciler.PodEndpoint{{PodName: "pod-a", IP: "10.0.0.1", Host: "10.0.0.1:9000", Ready: true}},
+	}, {
+		name: "skip not ready, deleted and without ip pods",
+		pods: []*corev1.Pod{
+			pod("pod-a", "10.0.0.1"),
+			pod("pod-b", "10.0.0.2", notReady),
+			pod("pod-c", "10.0.0.3", func(p *corev1.Pod) {
+				now := metav1.Now()
+				p.DeletionTimestamp = &now
+			}),
+			pod("pod-d", ""),
+		},
+		want: []reconciler.PodEndpoint{{PodName: "pod-a", IP: "10.0.0.1", Host: "10.0.0.1:9000", Ready: true}},
+	}, {
+		name: "include unready pods",
+		pods: []*corev1.Pod{pod("pod-a", "10.0.0.1"), pod("pod-b", "10.0.0.2", notReady)},
+		opts: []reconciler.PodEndpointOption{reconciler.WithUnreadyPodEndpoints()},
+		want: []reconciler.PodEndpoint{
+			{PodName: "pod-a", IP: "10.0.0.1", Host: "10.0.0.1:9000", Ready: true},
+			{PodName: "pod-b", IP: "10.0.0.2", Host: "10.0.0.2:9000", Ready: false},
+		},
+	}, {
+		name: "filter by phase",
+		pods: []*corev1.Pod{
+			pod("pod-a", "10.0.0.1", notReady),
+			pod("pod-b", "10.0.0.2", notReady, func(p *corev1.Pod) {
+				p.Status.Phase = corev1.PodPending
+			}),
+		},
+		opts: []reconciler.PodEndpointOption{reconciler.WithUnreadyPodEndpoints(), reconciler.WithPodPhases(corev1.PodPending)},
+		want: []reconciler.PodEndpoint{{PodName: "pod-b", IP: "10.0.0.2", Host: "10.0.0.2:9000", Ready: false}},
+	}, {
+		name: "named port",
+		pods: []*corev1.Pod{pod("pod-a", "10.0.0.1"), pod("pod-b", "10.0.0.2", func(p *corev1.Pod) {
+			p.Spec.Containers[0].Ports = nil
+		})},
+		opts: []reconciler.PodEndpointOption{reconciler.WithNamedPort("control")},
+		want: []reconciler.PodEndpoint{{PodName: "pod-a", IP: "10.0.0.1", Host: "10.0.0.1:10000", Ready: true}},
+	}, {
+		name: "ipv6 primary ip",
+		pods: []*corev1.Pod{pod("pod-a", "fd00::2")},
+		opts: []reconciler.PodEndpointOption{reconciler.WithEndpointPort(8080)},
+		want: []reconciler.PodEndpoint{{PodName: "pod-a", IP: "fd00::2", Host: "[fd00::2]:8080", Ready: true}},
+	}, {
+		name: "dual stack, pick ipv6",
+		pods: []*corev1.Pod{pod("pod-a", "10.0.0.1", dualStack), pod("pod-b", "10.0.0.2")},
+		opts: []reconciler.PodEndpointOption{reconciler.WithIPFamily(corev1.IPv6Protocol)},
+		want: []reconciler.PodEndpoint{{PodName: "pod-a", IP: "fd00::1", Host: "[fd00::1]:9000", Ready: true}},
+	}, {
+		name: "dual stack, pick ipv4",
+		pods: []*corev1.Pod{pod("pod-a", "10.0.0.1", dualStack), pod("pod-b", "10.0.0.2")},
+		opts: []reconciler.PodEndpointOption{reconciler.WithIPFamily(corev1.IPv4Protocol)},
+		want: []reconciler.PodEndpoint{
+			{PodName: "pod-a", IP: "10.0.0.1", Host: "10.0.0.1:9000", Ready: true},
+			{PodName: "pod-b", IP: "10.0.0.2", Host: "10.0.0.2:9000", Ready: true},
+		},
+	}}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tt := tt
+			ctx := context.TODO()
+			ctx, _ = injection.Fake.SetupInformers(ctx, &rest.Config{})
+
+			for _, p := range tt.pods {
+				require.NoError(t, podinformer.Get(ctx).Informer().GetIndexer().Add(p))
+			}
+
+			ipGetter := reconciler.PodIpGetter{
+				Lister: podinformer.Get(ctx).Lister(),
+			}
+			got, err := ipGetter.GetPodEndpoints(namespace, labels.Everything(), tt.opts...)
+			require.NoError(t, err)
+			require.ElementsMatch(t, tt.want, got)
+		})
+	}
+}
@@ -0,0 +1,318 @@
+/*
+Copyright 2026 The Knative Authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package reconciler
+
+import (
+	"context"
+	"crypto/tls"
+	"errors"
+	"fmt"
+	"net"
+	"sync"
+
+	"k8s.io/client-go/tools/cache"
+	"knative.dev/pkg/logging"
+
+	control "knative.dev/control-protocol/pkg"
+	"knative.dev/control-protocol/pkg/certificates"
+	"knative.dev/control-protocol/pkg/message"
+	"knative.dev/control-protocol/pkg/metrics"
+	"knative.dev/control-protocol/pkg/network"
+	ctrlservice "knative.dev/control-protocol/pkg/service"
+)
+
+// ReverseConnectionAuthorizer returns an error if the data plane which dialed conn is not allowed to register it for registration.Key
+type ReverseConnectionAuthorizer func(registration message.Registration, conn *network.AcceptedConnection) error
+
+// DataPlaneUserAuthorizer allows a data plane to register a connection only for the keys in the namespace of its certificate,
+// that is the certificate must contain the certificates.DataPlaneUserName SAN of the namespace of the key.
+// The keys must be in the form namespace/name.
+func DataPlaneUserAuthorizer(registration message.Registration, conn *network.AcceptedConnection) error {
+	namespace, _, err := cache.SplitMetaNamespaceKey(registration.Key)
+	if err != nil {
+		return err
+	}
+	if len(conn.PeerCertificates) == 0 {
+		return errors.New("no peer certificate")
+	}
+	return conn.PeerCertificates[0].VerifyHostname(certificates.DataPlaneUserName(namespace))
+}
+
+type reverseConnectionListenerOptions struct {
+	authorizer    ReverseConnectionAuthorizer
+	serverOptions []network.ControlServerOption
+	unpinnedHosts bool
+}
+
+type ReverseConnectionListenerOption func(*reverseConnectionListenerOptions)
+
+// WithReverseConnectionAuthorizer sets how the registrations are authorized.
+// Defaults to DataPlaneUserAuthorizer when using tls, otherwise all the registrations are allowed.
+func WithReverseConnectionAuthorizer(authorizer ReverseConnectionAuthorizer) ReverseConnectionListenerOption {
+	return func(options *reverseConnectionListenerOptions) {
+		options.authorizer = authorizer
+	}
+}
+
+// WithUnpinnedRegistrationHosts allows the data planes to register as any host, rather than only as hosts
+// with the IP of the remote address of their connection, e.g. when the data planes dial through a NAT.
+// Since a data plane can then claim the host of another data plane of the same keys, the authorizer should identify the data planes.
+func WithUnpinnedRegistrationHosts() ReverseConnectionListenerOption {
+	return func(options *reverseConnectionListenerOptions) {
+		options.unpinnedHosts = true
+	}
+}
+
+// WithReverseConnectionServerOptions configures the listener, e.g. the port or the options of the control services
+func WithReverseConnectionServerOptions(opts ...network.ControlServerOption) ReverseConnectionListenerOption {
+	return func(options *reverseConnectionListenerOptions) {
+		options.serverOptions = append(options.serverOptions, opts...)
+	}
+}
+
+// reverseConnectionRegistry is implemented by the pools which can register the connections dialed by the data planes
+type reverseConnectionRegistry interface {
+	// registerReverseConnection registers conn for key as host.
+	// hc is the host connection returned by the previous registration of conn, nil for the first one.
+	registerReverseConnection(ctx context.Context, key string, host string, conn *network.AcceptedConnection, hc *hostConnection) (*hostConnection, error)
+}
+
+var (
+	_ reverseConnectionRegistry = (*controlPlaneConnectionPoolImpl)(nil)
+	_ reverseConnectionRegistry = (*LeaderAwareConnectionPool)(nil)
+)
+
+// StartReverseConnectionListener accepts the connections dialed by the data planes, for the data planes the control plane cannot dial,
+// e.g. running outside the cluster network or behind a NAT.
+//
+// After dialing, a data plane sends a message.Registration with control.RegistrationOpCode for every key it serves,
+// look at network.WithClientRegistration. Every authorized registration places the connection into pool for the key,
+// as the host of the registration or, if empty, as the remote address of the connection.
+// A connection can be registered for more keys only when pool has a MessageKeyRouter, look at WithMessageKeyRouter,
+// otherwise the registrations for the other keys are rejected: a data plane serving more keys must dial a connection per key.
+// Unless WithUnpinnedRegistrationHosts is provided, the host of the registration must have the IP of the remote address.
+// A registration cannot replace a connection dialed by the pool, and the connections dialed by the data planes
+// are never shared with the other keys dialing the same host.
+// The services of the registered connections are returned by pool.GetServices like the dialed ones,
+// and are evicted when the connection breaks, until the data plane dials again.
+// Since these services are not dialed by the pool, ReconcileConnections must not be used for the keys of the data planes dialing the control plane.
+// The messages received before the registration are acked with an error.
+//
+// When tlsConfigLoader is not nil, the data planes must present a certificate, look at ListerCertificateGetter.LoadListenerTLSConfig.
+// The listener stops when ctx is done.
+func StartReverseConnectionListener(ctx context.Context, pool ControlPlaneConnectionPool, tlsConfigLoader func() (*tls.Config, error), opts ...ReverseConnectionListenerOption) (*network.ControlListener, error) {
+	registry, ok := pool.(reverseConnectionRegistry)
+	if !ok {
+		return nil, fmt.Errorf("the pool %T doesn't support the connections dialed by the data planes", pool)
+	}
+
+	options := reverseConnectionListenerOptions{}
+	if tlsConfigLoader != nil {
+		options.authorizer = DataPlaneUserAuthorizer
+	}
+	for _, fn := range opts {
+		fn(&options)
+	}
+
+	return network.StartControlListener(ctx, tlsConfigLoader, func(conn *network.AcceptedConnection) {
+		rc := &reverseConnection{
+			ctx:           ctx,
+			conn:          conn,
+			registry:      registry,
+			authorizer:    options.authorizer,
+			unpinnedHosts: options.unpinnedHosts,
+		}
+		conn.Service.MessageHandler(rc)
+		conn.Service.ErrorHandler(rc)
+	}, options.serverOptions...)
+}
+
+var errNotRegistered = errors.New("the connection is not registered")
+
+// reverseConnection handles the registrations of a connection dialed by a data plane
+type reverseConnection struct {
+	ctx           context.Context
+	conn          *network.AcceptedConnection
+	registry      reverseConnectionRegistry
+	authorizer    ReverseConnectionAuthorizer
+	unpinnedHosts bool
+
+	// lock serializes the registrations, since the messages are handled concurrently
+	lock sync.Mutex
+	host string
+	hc   *hostConnection
+}
+
+func (rc *reverseConnection) HandleServiceMessage(ctx context.Context, msg control.ServiceMessage) {
+	if control.OpCode(msg.Headers().OpCode()) == control.RegistrationOpCode {
+		msg.AckWithError(rc.register(msg.Payload()))
+		return
+	}
+
+	rc.lock.Lock()
+	hc := rc.hc
+	rc.lock.Unlock()
+	if hc == nil {
+		msg.AckWithError(errNotRegistered)
+		return
+	}
+	hc.HandleServiceMessage(ctx, msg)
+}
+
+func (rc *reverseConnection) HandleServiceError(ctx context.Context, err error) {
+	rc.lock.Lock()
+	hc := rc.hc
+	rc.lock.Unlock()
+	if hc == nil {
+		ctrlservice.LoggerErrorHandler.HandleServiceError(ctx, err)
+		return
+	}
+	hc.HandleServiceError(ctx, err)
+}
+
+func (rc *reverseConnection) register(payload []byte) error {
+	var registration message.Registration
+	if err := registration.UnmarshalBinary(payload); err != nil {
+		return err
+	}
+	host := registration.Host
+	if host == "" {
+		host = rc.conn.RemoteAddr.String()
+	} else if !rc.unpinnedHosts {
+		if err := checkPinnedHost(host, rc.conn.RemoteAddr); err != nil {
+			logging.FromContext(rc.ctx).Warnf("Rejected the registration of the control connection from %s for key %s: %v", rc.conn.RemoteAddr, registration.Key, err)
+			return err
+		}
+	}
+
+	rc.lock.Lock()
+	defer rc.lock.Unlock()
+	if rc.host != "" && rc.host != host {
+		return fmt.Errorf("the connection is already registered as %s", rc.host)
+	}
+	if rc.authorizer != nil {
+		if err := rc.authorizer(registration, rc.conn); err != nil {
+			logging.FromContext(rc.ctx).Warnf("Rejected the registration of the control connection from %s for key %s: %v", rc.conn.RemoteAddr, registration.Key, err)
+			return fmt.Errorf("cannot register for key %s: %w", registration.Key, err)
+		}
+	}
+
+	hc, err := rc.registry.registerReverseConnection(rc.ctx, registration.Key, host, rc.conn, rc.hc)
+	if err != nil {
+		return err
+	}
+	logging.FromContext(rc.ctx).Infof("Registered the control connection from %s for key %s as %s", rc.conn.RemoteAddr, registration.Key, host)
+	rc.host = host
+	rc.hc = hc
+	return nil
+}
+
+// checkPinnedHost returns an error if host doesn't have the IP of remoteAddr
+func checkPinnedHost(host string, remoteAddr net.Addr) error {
+	hostIP, _, err := net.SplitHostPort(host)
+	if err != nil {
+		return fmt.Errorf("invalid host %s: %w", host, err)
+	}
+	remoteIP, _, err := net.SplitHostPort(remoteAddr.String())
+	if err != nil {
+		return fmt.Errorf("invalid remote address %s: %w", remoteAddr, err)
+	}
+	if !net.ParseIP(hostIP).Equal(net.ParseIP(remoteIP)) {
+		return fmt.Errorf("cannot register as %s from %s", host, remoteAddr)
+	}
+	return nil
+}
+
+// registerReverseConnection places conn into the pool for key, replacing the previous connection to host if dialed by a data plane as well.
+// The host connection is evicted when conn closes.
+func (cc *controlPlaneConnectionPoolImpl) registerReverseConnection(ctx context.Context, key string, host string, conn *network.AcceptedConnection, hc *hostConnection) (*hostConnection, error) {
+	cc.connsLock.Lock()
+	if hc == nil {
+		// Never in hostConns, so the id is unused
+		hc = newHostConnection(hostConnID{host: host}, host, cc.messageKeyRouter)
+		hc.service = conn.Service
+		hc.cancelFn = conn.Close
+		hc.state = network.ConnectionReady
+		hc.accepted = true
+		// Nothing to dial, and no cycler: the data plane dials again when the connection breaks
+		close(hc.dialed)
+		go func() {
+			<-conn.ClosedCh()
+			cc.onConnectionStateChange(ctx, hc, network.ConnectionBroken)
+		}()
+	} else if isFinalConnectionState(hc.state) {
+		cc.connsLock.Unlock()
+		return nil, fmt.Errorf("the connection from %s is %s", host, hc.state)
+	} else if cc.messageKeyRouter == nil {
+		// Without a router, the inbound messages of a connection registered for multiple keys couldn't be routed.
+		// Since it's the only one, the key referencing the connection is the first one.
+		if keys := hc.referencingKeys(); len(keys) != 0 && keys[0] != key {
+			cc.connsLock.Unlock()
+			return nil, fmt.Errorf("the connection is already registered for key %s, registering more keys requires a MessageKeyRouter", keys[0])
+		}
+	}
+
+	if holder, ok := cc.conns[key][host]; ok {
+		if holder.conn == hc {
+			// Already registered
+			cc.connsLock.Unlock()
+			return hc, nil
+		}
+		if !holder.conn.accepted {
+			cc.connsLock.Unlock()
+			return nil, fmt.Errorf("the pool already dialed %s for key %s", host, key)
+		}
+		// e.g. the data plane dialed again before the previous connection broke
+		cc.releaseLocked(ctx, key, host)
+	}
+	// Not in hostConns: the connection must not be shared with the keys dialing host
+
+	newSvc := cc.wrapService(key, host, hc.acquire(key))
+
+	m, ok := cc.conns[key]
+	if !ok {
+		m = make(map[string]*clientServiceHolder)
+		cc.conns[key] = m
+	}
+	m[host] = &clientServiceHolder{service: newSvc, conn: hc}
+	metrics.RecordPoolConnectionsChange(ctx, key, 1)
+	cc.connsLock.Unlock()
+
+	cc.notify(key, host, network.ConnectionReady)
+
+	return hc, nil
+}
+
+// registerReverseConnection registers the connection like the wrapped pool, only if this replica is the leader of key.
+// Otherwise, it returns ErrNotLeader.
+func (p *LeaderAwareConnectionPool) registerReverseConnection(ctx context.Context, key string, host string, conn *network.AcceptedConnection, hc *hostConnection) (*hostConnection, error) {
+	registry, ok := p.ControlPlaneConnectionPool.(reverseConnectionRegistry)
+	if !ok {
+		return nil, fmt.Errorf("the pool %T doesn't support the connections dialed by the data planes", p.ControlPlaneConnectionPool)
+	}
+	if !p.IsLeaderFor(key) {
+		return nil, fmt.Errorf("cannot register %s for key %s: %w", host, key, ErrNotLeader)
+	}
+
+	p.trackKey(key)
+	hc, err := registry.registerReverseConnection(ctx, key, host, conn, hc)
+	if err == nil && !p.IsLeaderFor(key) {
+		p.ControlPlaneConnectionPool.RemoveConnection(ctx, key, host)
+		return nil, fmt.Errorf("cannot register %s for key %s: %w", host, key, ErrNotLeader)
+	}
+	return hc, err
+}
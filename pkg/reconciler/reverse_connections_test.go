@@ -0,0 +1,259 @@
+/*
+Copyright 2026 The Knative Authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package reconciler_test
+
+import (
+	"context"
+	"crypto/tls"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/rest"
+	fakekubeclient "knative.dev/pkg/client/injection/kube/client/fake"
+	secretinformer "knative.dev/pkg/client/injection/kube/informers/core/v1/secret/fake"
+	"knative.dev/pkg/controller"
+	"knative.dev/pkg/injection"
+	pkgreconciler "knative.dev/pkg/reconciler"
+
+	control "knative.dev/control-protocol/pkg"
+	"knative.dev/control-protocol/pkg/certificates"
+	"knative.dev/control-protocol/pkg/internal/reserved"
+	"knative.dev/control-protocol/pkg/message"
+	"knative.dev/control-protocol/pkg/network"
+	"knative.dev/control-protocol/pkg/reconciler"
+	"knative.dev/control-protocol/pkg/test"
+)
+
+// clientTLSConfig returns the tls configuration of a data plane in namespace dialing the control plane
+func (ca testCA) clientTLSConfig(t *testing.T, namespace string) *tls.Config {
+	serverConf := ca.serverTLSConfig(t, namespace)
+	return &tls.Config{
+		Certificates: serverConf.Certificates,
+		RootCAs:      serverConf.ClientCAs,
+		ServerName:   certificates.DataPlaneRoutingName(""),
+	}
+}
+
+func TestReverseConnectionListener(t *testing.T) {
+	namespace := "knative-eventing"
+	name := "control-secret"
+
+	ctx, cancelFn := context.WithCancel(context.TODO())
+	t.Cleanup(cancelFn)
+	ctx, informers := injection.Fake.SetupInformers(ctx, &rest.Config{})
+	informer := secretinformer.Get(ctx)
+	require.NoError(t, controller.StartInformers(ctx.Done(), informers...))
+
+	ca := mustCreateTestCA(t)
+	_, err := fakekubeclient.Get(ctx).CoreV1().Secrets(namespace).Create(ctx, &corev1.Secret{
+		ObjectMeta: metav1.ObjectMeta{Name: name, Namespace: namespace},
+		Data:       ca.secretData(t),
+	}, metav1.CreateOptions{})
+	require.NoError(t, err)
+	require.Eventually(t, func() bool {
+		_, err := informer.Lister().Secrets(namespace).Get(name)
+		return err == nil
+	}, 5*time.Second, 10*time.Millisecond)
+
+	// The connection is registered for more keys, so the pool needs a router
+	connectionPool := reconciler.NewInsecureControlPlaneConnectionPool(
+		reconciler.WithMessageKeyRouter(func(host string, message control.ServiceMessage) (string, bool) {
+			return "", false
+		}),
+	)
+	t.Cleanup(func() {
+		connectionPool.Close(ctx)
+	})
+	states := make(chan network.ConnectionState, 10)
+	connectionPool.(reconciler.ConnectionStateObserver).SubscribeConnectionState(func(key string, host string, state network.ConnectionState) {
+		if key == "ns-a/broker" {
+			states <- state
+		}
+	})
+
+	memoryNetwork := network.NewMemoryNetwork()
+	ln, err := memoryNetwork.Listen("10.0.0.100:9443")
+	require.NoError(t, err)
+	_, err = reconciler.StartReverseConnectionListener(ctx, connectionPool,
+		reconciler.NewCertificateGetter(informer.Lister(), namespace, name).LoadListenerTLSConfig,
+		reconciler.WithReverseConnectionServerOptions(network.WithListener(ln)),
+	)
+	require.NoError(t, err)
+
+	// The data plane dials the control plane and registers for its key
+	dataPlaneCtx, dataPlaneCancelFn := context.WithCancel(ctx)
+	dataPlane, err := network.StartControlClient(dataPlaneCtx, network.NewTLSDialer(memoryNetwork, ca.clientTLSConfig(t, "ns-a")), "10.0.0.100:9443",
+		network.WithClientRegistration(message.Registration{Key: "ns-a/broker", Host: "127.0.0.1:9000"}),
+	)
+	require.NoError(t, err)
+	require.Equal(t, network.ConnectionReady, <-states)
+
+	services := connectionPool.GetServices("ns-a/broker")
+	require.Len(t, services, 1)
+	svc := services["127.0.0.1:9000"]
+	require.NotNil(t, svc)
+	test.SendReceiveTest(t, svc, dataPlane)
+	test.SendReceiveTest(t, dataPlane, svc)
+
+	// The certificate of the data plane is valid only for its namespace
+	require.ErrorContains(t, register(dataPlane, message.Registration{Key: "ns-b/broker", Host: "127.0.0.1:9000"}), "cannot register for key ns-b/broker")
+	require.Empty(t, connectionPool.GetServices("ns-b/broker"))
+
+	// The connection can be registered for more keys, always as the same host
+	require.ErrorContains(t, register(dataPlane, message.Registration{Key: "ns-a/other"}), "already registered as 127.0.0.1:9000")
+	require.NoError(t, register(dataPlane, message.Registration{Key: "ns-a/other", Host: "127.0.0.1:9000"}))
+	require.Equal(t, []string{"127.0.0.1:9000"}, connectionPool.GetConnectedHosts("ns-a/other"))
+
+	// The credentials rotation doesn't cycle the connections dialed by the data planes
+	require.NoError(t, connectionPool.(reconciler.CredentialsRotator).RotateCredentials(ctx))
+
+	// When the pool removes the connection, the data plane dials again and registers again
+	connectionPool.RemoveConnection(ctx, "ns-a/other", "127.0.0.1:9000")
+	connectionPool.RemoveConnection(ctx, "ns-a/broker", "127.0.0.1:9000")
+	require.Equal(t, network.ConnectionClosed, <-states)
+	require.Equal(t, network.ConnectionReady, <-states)
+	_, svc = connectionPool.ResolveControlInterface("ns-a/broker", "127.0.0.1:9000")
+	require.NotNil(t, svc)
+	test.SendReceiveTest(t, svc, dataPlane)
+
+	// When the data plane goes away, the connection is evicted
+	dataPlaneCancelFn()
+	require.Equal(t, network.ConnectionBroken, <-states)
+	require.Empty(t, connectionPool.GetServices("ns-a/broker"))
+}
+
+func TestReverseConnectionListener_NotRegistered(t *testing.T) {
+	ctx, cancelFn := context.WithCancel(context.TODO())
+	t.Cleanup(cancelFn)
+
+	connectionPool := reconciler.NewLeaderAwareConnectionPool(reconciler.NewInsecureControlPlaneConnectionPool())
+	t.Cleanup(func() {
+		connectionPool.Close(ctx)
+	})
+
+	memoryNetwork := network.NewMemoryNetwork()
+	ln, err := memoryNetwork.Listen("10.0.0.100:9443")
+	require.NoError(t, err)
+	_, err = reconciler.StartReverseConnectionListener(ctx, connectionPool, nil,
+		reconciler.WithReverseConnectionServerOptions(network.WithListener(ln)),
+	)
+	require.NoError(t, err)
+
+	dataPlane, err := network.StartControlClient(ctx, memoryNetwork, "10.0.0.100:9443")
+	require.NoError(t, err)
+
+	require.ErrorContains(t, dataPlane.SendAndWaitForAck(1, test.SomeMockPayload), "the connection is not registered")
+	require.ErrorContains(t, register(dataPlane, message.Registration{Key: "ns-a/broker"}), reconciler.ErrNotLeader.Error())
+	require.Empty(t, connectionPool.GetServices("ns-a/broker"))
+
+	require.NoError(t, connectionPool.Promote(pkgreconciler.UniversalBucket(), nil))
+	require.NoError(t, register(dataPlane, message.Registration{Key: "ns-a/broker"}))
+	require.Len(t, connectionPool.GetServices("ns-a/broker"), 1)
+	require.NoError(t, dataPlane.SendAndWaitForAck(1, test.SomeMockPayload))
+
+	// Without a router, the connection cannot be registered for more keys
+	require.ErrorContains(t, register(dataPlane, message.Registration{Key: "ns-a/other"}), "already registered for key ns-a/broker")
+	require.Empty(t, connectionPool.GetServices("ns-a/other"))
+	require.NoError(t, register(dataPlane, message.Registration{Key: "ns-a/broker"}))
+}
+
+func TestReverseConnectionListener_HostHijack(t *testing.T) {
+	ctx, cancelFn := context.WithCancel(context.TODO())
+	t.Cleanup(cancelFn)
+
+	memoryNetwork := network.NewMemoryNetwork()
+	dataPlaneLn, err := memoryNetwork.Listen("127.0.0.1:9000")
+	require.NoError(t, err)
+	dataPlaneServer, err := network.StartInsecureControlServer(ctx, network.WithListener(dataPlaneLn))
+	require.NoError(t, err)
+
+	// With a router, the connections dialed by the pool are shared by the keys
+	connectionPool := reconciler.NewInsecureControlPlaneConnectionPool(
+		reconciler.WithDialer(memoryNetwork),
+		reconciler.WithMessageKeyRouter(func(host string, message control.ServiceMessage) (string, bool) {
+			return "", false
+		}),
+	)
+	t.Cleanup(func() {
+		connectionPool.Close(ctx)
+	})
+	_, dialedSvc, err := connectionPool.DialControlService(ctx, "ns-a/dialed", "127.0.0.1:9000")
+	require.NoError(t, err)
+
+	ln, err := memoryNetwork.Listen("10.0.0.100:9443")
+	require.NoError(t, err)
+	_, err = reconciler.StartReverseConnectionListener(ctx, connectionPool, nil,
+		reconciler.WithReverseConnectionServerOptions(network.WithListener(ln)),
+	)
+	require.NoError(t, err)
+
+	dataPlane, err := network.StartControlClient(ctx, memoryNetwork, "10.0.0.100:9443")
+	require.NoError(t, err)
+
+	// The host must have the IP of the remote address
+	require.ErrorContains(t, register(dataPlane, message.Registration{Key: "ns-a/broker", Host: "10.0.0.1:9000"}), "cannot register as 10.0.0.1:9000")
+	require.Empty(t, connectionPool.GetServices("ns-a/broker"))
+
+	// The connections dialed by the pool cannot be replaced
+	require.ErrorContains(t, register(dataPlane, message.Registration{Key: "ns-a/dialed", Host: "127.0.0.1:9000"}), "already dialed")
+	_, svc := connectionPool.ResolveControlInterface("ns-a/dialed", "127.0.0.1:9000")
+	require.Equal(t, dialedSvc, svc)
+
+	// The connection dialed by the data plane isn't shared with the keys dialing the same host
+	require.NoError(t, register(dataPlane, message.Registration{Key: "ns-a/broker", Host: "127.0.0.1:9000"}))
+	_, svc, err = connectionPool.DialControlService(ctx, "ns-a/other", "127.0.0.1:9000")
+	require.NoError(t, err)
+	test.SendReceiveTest(t, svc, dataPlaneServer)
+	_, svc = connectionPool.ResolveControlInterface("ns-a/broker", "127.0.0.1:9000")
+	test.SendReceiveTest(t, svc, dataPlane)
+}
+
+func TestReverseConnectionListener_UnpinnedHosts(t *testing.T) {
+	ctx, cancelFn := context.WithCancel(context.TODO())
+	t.Cleanup(cancelFn)
+
+	connectionPool := reconciler.NewInsecureControlPlaneConnectionPool()
+	t.Cleanup(func() {
+		connectionPool.Close(ctx)
+	})
+
+	memoryNetwork := network.NewMemoryNetwork()
+	ln, err := memoryNetwork.Listen("10.0.0.100:9443")
+	require.NoError(t, err)
+	_, err = reconciler.StartReverseConnectionListener(ctx, connectionPool, nil,
+		reconciler.WithReverseConnectionServerOptions(network.WithListener(ln)),
+		reconciler.WithUnpinnedRegistrationHosts(),
+	)
+	require.NoError(t, err)
+
+	dataPlane, err := network.StartControlClient(ctx, memoryNetwork, "10.0.0.100:9443")
+	require.NoError(t, err)
+
+	require.NoError(t, register(dataPlane, message.Registration{Key: "ns-a/broker", Host: "10.0.0.1:9000"}))
+	_, svc := connectionPool.ResolveControlInterface("ns-a/broker", "10.0.0.1:9000")
+	require.NotNil(t, svc)
+	test.SendReceiveTest(t, svc, dataPlane)
+}
+
+// register sends the registration like network.WithClientRegistration, returning the ack error
+func register(dataPlane control.Service, registration message.Registration) error {
+	ctx := reserved.WithReservedOpCode(context.Background(), control.RegistrationOpCode)
+	return dataPlane.(control.ContextSender).SendAndWaitForAckWithContext(ctx, control.RegistrationOpCode, registration)
+}
@@ -0,0 +1,231 @@
+/*
+Copyright 2026 The Knative Authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package reconciler
+
+import (
+	"context"
+	"encoding"
+	"errors"
+	"sort"
+	"sync"
+
+	"github.com/google/uuid"
+	"knative.dev/pkg/logging"
+
+	control "knative.dev/control-protocol/pkg"
+	"knative.dev/control-protocol/pkg/network"
+	ctrlservice "knative.dev/control-protocol/pkg/service"
+)
+
+// MessageKeyRouter returns the pool key an inbound message is addressed to, e.g. parsing the payload.
+// It returns false if the key cannot be determined.
+type MessageKeyRouter func(host string, message control.ServiceMessage) (key string, ok bool)
+
+// hostConnection is the control connection to a host, shared by all the keys connected to the host
+// when the pool has a MessageKeyRouter. The connection is closed when the last key releases it.
+type hostConnection struct {
+	id     hostConnID
+	host   string
+	router MessageKeyRouter
+
+	// dialed is closed when the dial completes, successfully or with dialErr.
+	// The following fields are guarded by the pool connsLock.
+	dialed  chan struct{}
+	dialErr error
+	// dialCanceled is true if the dial failed because the ctx of the key dialing the connection was done
+	dialCanceled bool
+	service      control.Service
+	cancelFn     context.CancelFunc
+	cycler       network.ConnectionCycler
+	state        network.ConnectionState
+	// accepted is true if the connection was dialed by the data plane, look at StartReverseConnectionListener.
+	// The accepted connections are not shared with the keys dialing the host.
+	accepted bool
+
+	// keys contains the services of the keys referencing this connection
+	keysLock sync.RWMutex
+	keys     map[string]*keyService
+}
+
+func newHostConnection(id hostConnID, host string, router MessageKeyRouter) *hostConnection {
+	return &hostConnection{
+		id:     id,
+		host:   host,
+		router: router,
+		dialed: make(chan struct{}),
+		state:  network.ConnectionConnecting,
+		keys:   make(map[string]*keyService),
+	}
+}
+
+// acquire returns the service of key, adding key to the references of this connection
+func (h *hostConnection) acquire(key string) *keyService {
+	h.keysLock.Lock()
+	defer h.keysLock.Unlock()
+	svc, ok := h.keys[key]
+	if !ok {
+		svc = newKeyService(key, h.service)
+		h.keys[key] = svc
+	}
+	return svc
+}
+
+// release removes key from the references of this connection, returning the number of references left
+func (h *hostConnection) release(key string) int {
+	h.keysLock.Lock()
+	defer h.keysLock.Unlock()
+	delete(h.keys, key)
+	return len(h.keys)
+}
+
+// releaseAll removes all the references of this connection, returning the released keys
+func (h *hostConnection) releaseAll() []string {
+	h.keysLock.Lock()
+	defer h.keysLock.Unlock()
+	keys := make([]string, 0, len(h.keys))
+	for key := range h.keys {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	h.keys = make(map[string]*keyService)
+	return keys
+}
+
+// referencingKeys returns the sorted keys referencing this connection
+func (h *hostConnection) referencingKeys() []string {
+	h.keysLock.RLock()
+	defer h.keysLock.RUnlock()
+	keys := make([]string, 0, len(h.keys))
+	for key := range h.keys {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+// HandleServiceMessage routes the inbound message to the handler of its key.
+// When the connection is used by a single key, the message is routed to that key,
+// otherwise the MessageKeyRouter of the pool must determine the key.
+func (h *hostConnection) HandleServiceMessage(ctx context.Context, message control.ServiceMessage) {
+	h.keysLock.RLock()
+	var target *keyService
+	if h.router != nil {
+		if key, ok := h.router(h.host, message); ok {
+			target = h.keys[key]
+		}
+	}
+	if target == nil && len(h.keys) == 1 {
+		for _, svc := range h.keys {
+			target = svc
+		}
+	}
+	keysCount := len(h.keys)
+	h.keysLock.RUnlock()
+
+	if target == nil {
+		logging.FromContext(ctx).Warnf("Cannot route the control message '%s' from %s to one of the %d keys sharing the connection", message.Headers().UUID(), h.host, keysCount)
+		message.AckWithError(errCannotRouteMessage)
+		return
+	}
+	target.handleServiceMessage(ctx, message)
+}
+
+var errCannotRouteMessage = errors.New("cannot route the message to a key")
+
+// HandleServiceError propagates the connection error to all the keys
+func (h *hostConnection) HandleServiceError(ctx context.Context, err error) {
+	h.keysLock.RLock()
+	services := make([]*keyService, 0, len(h.keys))
+	for _, svc := range h.keys {
+		services = append(services, svc)
+	}
+	h.keysLock.RUnlock()
+
+	for _, svc := range services {
+		svc.handleServiceError(ctx, err)
+	}
+}
+
+// keyService is the view of a shared host connection used by a single key.
+// It sends through the shared connection and receives only the messages routed to its key.
+type keyService struct {
+	key     string
+	service control.Service
+
+	handlerLock  sync.RWMutex
+	handler      control.MessageHandler
+	errorHandler control.ErrorHandler
+}
+
+var (
+	_ control.Service       = (*keyService)(nil)
+	_ control.UUIDSender    = (*keyService)(nil)
+	_ control.ContextSender = (*keyService)(nil)
+)
+
+func newKeyService(key string, service control.Service) *keyService {
+	return &keyService{
+		key:          key,
+		service:      service,
+		handler:      ctrlservice.NoopMessageHandler,
+		errorHandler: ctrlservice.LoggerErrorHandler,
+	}
+}
+
+func (k *keyService) SendAndWaitForAck(opcode control.OpCode, payload encoding.BinaryMarshaler) error {
+	return k.service.SendAndWaitForAck(opcode, payload)
+}
+
+func (k *keyService) SendWithUUIDAndWaitForAck(id uuid.UUID, opcode control.OpCode, payload encoding.BinaryMarshaler) error {
+	if sender, ok := k.service.(control.UUIDSender); ok {
+		return sender.SendWithUUIDAndWaitForAck(id, opcode, payload)
+	}
+	return k.SendAndWaitForAckWithContext(control.ContextWithMessageUUID(context.Background(), id), opcode, payload)
+}
+
+func (k *keyService) SendAndWaitForAckWithContext(ctx context.Context, opcode control.OpCode, payload encoding.BinaryMarshaler) error {
+	if sender, ok := k.service.(control.ContextSender); ok {
+		return sender.SendAndWaitForAckWithContext(ctx, opcode, payload)
+	}
+	return k.service.SendAndWaitForAck(opcode, payload)
+}
+
+func (k *keyService) MessageHandler(handler control.MessageHandler) {
+	k.handlerLock.Lock()
+	k.handler = handler
+	k.handlerLock.Unlock()
+}
+
+func (k *keyService) ErrorHandler(handler control.ErrorHandler) {
+	k.handlerLock.Lock()
+	k.errorHandler = handler
+	k.handlerLock.Unlock()
+}
+
+func (k *keyService) handleServiceMessage(ctx context.Context, message control.ServiceMessage) {
+	k.handlerLock.RLock()
+	handler := k.handler
+	k.handlerLock.RUnlock()
+	handler.HandleServiceMessage(ctx, message)
+}
+
+func (k *keyService) handleServiceError(ctx context.Context, err error) {
+	k.handlerLock.RLock()
+	handler := k.errorHandler
+	k.handlerLock.RUnlock()
+	handler.HandleServiceError(ctx, err)
+}
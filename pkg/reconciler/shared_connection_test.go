@@ -0,0 +1,274 @@
+/*
+Copyright 2026 The Knative Authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package reconciler_test
+
+import (
+	"context"
+	"net"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+	"go.uber.org/atomic"
+
+	control "knative.dev/control-protocol/pkg"
+	"knative.dev/control-protocol/pkg/network"
+	"knative.dev/control-protocol/pkg/reconciler"
+	"knative.dev/control-protocol/pkg/test"
+)
+
+type countingListener struct {
+	net.Listener
+	accepted atomic.Int32
+}
+
+func (l *countingListener) Accept() (net.Conn, error) {
+	conn, err := l.Listener.Accept()
+	if err == nil {
+		l.accepted.Inc()
+	}
+	return conn, err
+}
+
+func TestConnectionPool_SharedConnection(t *testing.T) {
+	ctx, cancelFn := context.WithCancel(context.TODO())
+	t.Cleanup(cancelFn)
+
+	memoryNetwork := network.NewMemoryNetwork()
+	ln, err := memoryNetwork.Listen("10.0.0.1:9000")
+	require.NoError(t, err)
+	countingLn := &countingListener{Listener: ln}
+	server, err := network.StartInsecureControlServer(ctx, network.WithListener(countingLn))
+	require.NoError(t, err)
+
+	// The payload of the messages sent by the server is the key
+	connectionPool := reconciler.NewInsecureControlPlaneConnectionPool(
+		reconciler.WithDialer(memoryNetwork),
+		reconciler.WithMessageKeyRouter(func(host string, message control.ServiceMessage) (string, bool) {
+			return string(message.Payload()), true
+		}),
+	)
+	t.Cleanup(func() {
+		connectionPool.Close(ctx)
+	})
+
+	received := map[string]chan string{
+		"ns-a/broker": make(chan string, 10),
+		"ns-b/broker": make(chan string, 10),
+	}
+	for key, ch := range received {
+		ch := ch
+		_, err := connectionPool.ReconcileConnections(ctx, key, []string{"10.0.0.1:9000"}, func(host string, svc control.Service) {
+			svc.MessageHandler(control.MessageHandlerFunc(func(ctx context.Context, message control.ServiceMessage) {
+				ch <- string(message.Payload())
+				message.Ack()
+			}))
+		}, nil)
+		require.NoError(t, err)
+	}
+
+	// A single connection is shared by both keys
+	require.Eventually(t, func() bool {
+		return countingLn.accepted.Load() == 1
+	}, 5*time.Second, 10*time.Millisecond)
+	_, svcA := connectionPool.ResolveControlInterface("ns-a/broker", "10.0.0.1:9000")
+	_, svcB := connectionPool.ResolveControlInterface("ns-b/broker", "10.0.0.1:9000")
+	require.NotEqual(t, svcA, svcB)
+
+	// The inbound messages are routed to their key
+	require.NoError(t, server.SendAndWaitForAck(1, test.MockPayload("ns-a/broker")))
+	require.NoError(t, server.SendAndWaitForAck(1, test.MockPayload("ns-b/broker")))
+	require.Equal(t, "ns-a/broker", <-received["ns-a/broker"])
+	require.Equal(t, "ns-b/broker", <-received["ns-b/broker"])
+	require.ErrorContains(t, server.SendAndWaitForAck(1, test.MockPayload("ns-c/broker")), "cannot route the message to a key")
+
+	// Both keys can send
+	server.MessageHandler(control.MessageHandlerFunc(func(ctx context.Context, message control.ServiceMessage) {
+		message.Ack()
+	}))
+	require.NoError(t, svcA.SendAndWaitForAck(1, test.SomeMockPayload))
+	require.NoError(t, svcB.SendAndWaitForAck(1, test.SomeMockPayload))
+
+	// The connection stays open until the last key removes it
+	connectionPool.RemoveConnection(ctx, "ns-a/broker", "10.0.0.1:9000")
+	_, ok := connectionPool.(reconciler.ConnectionStateObserver).GetConnectionState("ns-a/broker", "10.0.0.1:9000")
+	require.False(t, ok)
+	state, ok := connectionPool.(reconciler.ConnectionStateObserver).GetConnectionState("ns-b/broker", "10.0.0.1:9000")
+	require.True(t, ok)
+	require.Equal(t, network.ConnectionReady, state)
+	require.NoError(t, svcB.SendAndWaitForAck(1, test.SomeMockPayload))
+
+	// With a single key, all the messages are routed to it
+	require.NoError(t, server.SendAndWaitForAck(1, test.MockPayload("ns-c/broker")))
+	require.Equal(t, "ns-c/broker", <-received["ns-b/broker"])
+	require.Equal(t, int32(1), countingLn.accepted.Load())
+
+	// Once closed, the next dial opens a new connection
+	connectionPool.RemoveAllConnections(ctx, "ns-b/broker")
+	_, _, err = connectionPool.DialControlService(ctx, "ns-a/broker", "10.0.0.1:9000")
+	require.NoError(t, err)
+	require.Eventually(t, func() bool {
+		return countingLn.accepted.Load() == 2
+	}, 5*time.Second, 10*time.Millisecond)
+}
+
+func TestConnectionPool_SharedConnectionOutlivesFirstDialContext(t *testing.T) {
+	ctx, cancelFn := context.WithCancel(context.TODO())
+	t.Cleanup(cancelFn)
+
+	memoryNetwork := network.NewMemoryNetwork()
+	ln, err := memoryNetwork.Listen("10.0.0.1:9000")
+	require.NoError(t, err)
+	server, err := network.StartInsecureControlServer(ctx, network.WithListener(ln))
+	require.NoError(t, err)
+	server.MessageHandler(control.MessageHandlerFunc(func(ctx context.Context, message control.ServiceMessage) {
+		message.Ack()
+	}))
+
+	connectionPool := reconciler.NewInsecureControlPlaneConnectionPool(
+		reconciler.WithDialer(memoryNetwork),
+		reconciler.WithMessageKeyRouter(func(host string, message control.ServiceMessage) (string, bool) {
+			return "", false
+		}),
+	)
+	t.Cleanup(func() {
+		connectionPool.Close(ctx)
+	})
+
+	// The first key dials with a ctx which is done right after
+	firstCtx, firstCancelFn := context.WithCancel(ctx)
+	_, _, err = connectionPool.DialControlService(firstCtx, "ns-a/broker", "10.0.0.1:9000")
+	require.NoError(t, err)
+	_, svcB, err := connectionPool.DialControlService(ctx, "ns-b/broker", "10.0.0.1:9000")
+	require.NoError(t, err)
+	firstCancelFn()
+
+	require.NoError(t, svcB.SendAndWaitForAck(1, test.SomeMockPayload))
+	state, ok := connectionPool.(reconciler.ConnectionStateObserver).GetConnectionState("ns-b/broker", "10.0.0.1:9000")
+	require.True(t, ok)
+	require.Equal(t, network.ConnectionReady, state)
+
+	// A done ctx doesn't dial
+	_, _, err = connectionPool.DialControlService(firstCtx, "ns-c/broker", "10.0.0.2:9000")
+	require.ErrorIs(t, err, context.Canceled)
+}
+
+func TestConnectionPool_ConnectionPerKeyWithoutRouter(t *testing.T) {
+	ctx, cancelFn := context.WithCancel(context.TODO())
+	t.Cleanup(cancelFn)
+
+	memoryNetwork := network.NewMemoryNetwork()
+	ln, err := memoryNetwork.Listen("10.0.0.1:9000")
+	require.NoError(t, err)
+	accepted := make(chan *network.AcceptedConnection, 10)
+	_, err = network.StartControlListener(ctx, nil, func(conn *network.AcceptedConnection) {
+		accepted <- conn
+	}, network.WithListener(ln))
+	require.NoError(t, err)
+
+	connectionPool := reconciler.NewInsecureControlPlaneConnectionPool(reconciler.WithDialer(memoryNetwork))
+	t.Cleanup(func() {
+		connectionPool.Close(ctx)
+	})
+
+	received := make(chan string, 10)
+	for _, key := range []string{"ns-a/broker", "ns-b/broker"} {
+		key := key
+		_, svc, err := connectionPool.DialControlService(ctx, key, "10.0.0.1:9000")
+		require.NoError(t, err)
+		svc.MessageHandler(control.MessageHandlerFunc(func(ctx context.Context, message control.ServiceMessage) {
+			received <- key
+			message.Ack()
+		}))
+	}
+
+	// Every key has its own connection, so the inbound messages reach its handler
+	for i := 0; i < 2; i++ {
+		conn := <-accepted
+		require.NoError(t, conn.Service.SendAndWaitForAck(1, test.SomeMockPayload))
+	}
+	require.ElementsMatch(t, []string{"ns-a/broker", "ns-b/broker"}, []string{<-received, <-received})
+}
+
+// blockingDialer blocks the first dial until its ctx is done
+type blockingDialer struct {
+	network.Dialer
+	dials   atomic.Int32
+	blocked chan struct{}
+}
+
+func (d *blockingDialer) DialContext(ctx context.Context, network string, address string) (net.Conn, error) {
+	if d.dials.Inc() == 1 {
+		close(d.blocked)
+		<-ctx.Done()
+		return nil, ctx.Err()
+	}
+	return d.Dialer.DialContext(ctx, network, address)
+}
+
+func TestConnectionPool_SharedConnectionFirstDialCanceled(t *testing.T) {
+	ctx, cancelFn := context.WithCancel(context.TODO())
+	t.Cleanup(cancelFn)
+
+	memoryNetwork := network.NewMemoryNetwork()
+	ln, err := memoryNetwork.Listen("10.0.0.1:9000")
+	require.NoError(t, err)
+	server, err := network.StartInsecureControlServer(ctx, network.WithListener(ln))
+	require.NoError(t, err)
+	server.MessageHandler(control.MessageHandlerFunc(func(ctx context.Context, message control.ServiceMessage) {
+		message.Ack()
+	}))
+
+	dialer := &blockingDialer{Dialer: memoryNetwork, blocked: make(chan struct{})}
+	connectionPool := reconciler.NewInsecureControlPlaneConnectionPool(
+		reconciler.WithDialer(dialer),
+		reconciler.WithMessageKeyRouter(func(host string, message control.ServiceMessage) (string, bool) {
+			return "", false
+		}),
+	)
+	t.Cleanup(func() {
+		connectionPool.Close(ctx)
+	})
+
+	firstCtx, firstCancelFn := context.WithCancel(ctx)
+	firstErr := make(chan error, 1)
+	go func() {
+		_, _, err := connectionPool.DialControlService(firstCtx, "ns-a/broker", "10.0.0.1:9000")
+		firstErr <- err
+	}()
+	<-dialer.blocked
+
+	type dialResult struct {
+		svc control.Service
+		err error
+	}
+	second := make(chan dialResult, 1)
+	go func() {
+		_, svc, err := connectionPool.DialControlService(ctx, "ns-b/broker", "10.0.0.1:9000")
+		second <- dialResult{svc: svc, err: err}
+	}()
+	// Let the second key wait for the dial of the first one
+	time.Sleep(100 * time.Millisecond)
+
+	// The second key dials again with its own ctx, rather than failing with the ctx error of the first one
+	firstCancelFn()
+	require.ErrorIs(t, <-firstErr, context.Canceled)
+	result := <-second
+	require.NoError(t, result.err)
+	require.NoError(t, result.svc.SendAndWaitForAck(1, test.SomeMockPayload))
+	require.Equal(t, int32(2), dialer.dials.Load())
+}
@@ -0,0 +1,263 @@
+/*
+Copyright 2026 The Knative Authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package reconciler
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strings"
+
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/equality"
+	"k8s.io/client-go/tools/cache"
+	"knative.dev/pkg/logging"
+)
+
+// CredentialsRotator is implemented by the pools which can rotate the tls credentials of their connections.
+// The pools returned by NewControlPlaneConnectionPool and NewLeaderAwareConnectionPool implement it.
+type CredentialsRotator interface {
+	RotateCredentials(ctx context.Context) error
+}
+
+// CredentialsRotationError is returned by RotateCredentials when some connections cannot reconnect with the new credentials.
+// These connections are evicted by the pool once the control client gives up reconnecting.
+type CredentialsRotationError struct {
+	// Failed contains, per key, the hosts that cannot reconnect, with the error
+	Failed map[string]map[string]error
+}
+
+func (e *CredentialsRotationError) Error() string {
+	var conns []string
+	for key, hosts := range e.Failed {
+		for host, err := range hosts {
+			conns = append(conns, fmt.Sprintf("%s/%s: %v", key, host, err))
+		}
+	}
+	sort.Strings(conns)
+	return fmt.Sprintf("cannot cycle %d connection(s): %s", len(conns), strings.Join(conns, "; "))
+}
+
+// RotateCredentials rebuilds the dialer, reading again the tls credentials, and gracefully cycles the connections
+// of the pool onto the new credentials. The connections are cycled one at a time, waiting for every connection
+// to reconnect before cycling the next one, at most for the timeout set with WithConnectionCycleTimeout.
+// If the dialer cannot be rebuilt, no connection is cycled.
+// When some connections cannot reconnect, the error is a *CredentialsRotationError.
+func (cc *controlPlaneConnectionPoolImpl) RotateCredentials(ctx context.Context) error {
+	if err := cc.rebuildDialer(); err != nil {
+		return fmt.Errorf("cannot rebuild the dialer: %w", err)
+	}
+
+	rotationErr := &CredentialsRotationError{
+		Failed: make(map[string]map[string]error),
+	}
+	for _, hc := range cc.hostConnectionsSnapshot() {
+		if ctx.Err() != nil {
+			return ctx.Err()
+		}
+		// The keys are collected before cycling, because a connection failing to reconnect is evicted
+		keys := hc.referencingKeys()
+		if err := cc.cycleConnection(ctx, hc); err != nil {
+			// The connection is shared, so it failed for all its keys
+			for _, key := range keys {
+				logging.FromContext(ctx).Warnf("Cannot cycle the control connection to %s for key %s: %v", hc.host, key, err)
+				if _, ok := rotationErr.Failed[key]; !ok {
+					rotationErr.Failed[key] = make(map[string]error)
+				}
+				rotationErr.Failed[key][hc.host] = err
+			}
+		}
+	}
+
+	if len(rotationErr.Failed) != 0 {
+		return rotationErr
+	}
+	return nil
+}
+
+// hostConnectionsSnapshot returns the host connections of the pool which can be cycled, sorted by host and key
+func (cc *controlPlaneConnectionPoolImpl) hostConnectionsSnapshot() []*hostConnection {
+	cc.connsLock.Lock()
+	defer cc.connsLock.Unlock()
+	conns := make([]*hostConnection, 0, len(cc.hostConns))
+	for _, hc := range cc.hostConns {
+		select {
+		case <-hc.dialed:
+			// The connections dialed by the data planes are not cycled, the data planes dial again
+			if hc.cycler != nil {
+				conns = append(conns, hc)
+			}
+		default:
+			// Still dialing, with the new credentials
+		}
+	}
+	sort.Slice(conns, func(i, j int) bool {
+		if conns[i].host != conns[j].host {
+			return conns[i].host < conns[j].host
+		}
+		return conns[i].id.key < conns[j].id.key
+	})
+	return conns
+}
+
+func (cc *controlPlaneConnectionPoolImpl) cycleConnection(ctx context.Context, hc *hostConnection) error {
+	cc.connsLock.Lock()
+	registered := cc.hostConns[hc.id] == hc
+	cc.connsLock.Unlock()
+	if !registered {
+		// The connection was removed in the meantime
+		return nil
+	}
+
+	cycleCtx, cancelFn := context.WithTimeout(ctx, cc.connectionCycleTimeout)
+	defer cancelFn()
+	return hc.cycler.CycleConnection(cycleCtx)
+}
+
+type tlsCredentialsWatcherOptions struct {
+	rotationCallback func(error)
+}
+
+type TLSCredentialsWatcherOption func(*tlsCredentialsWatcherOptions)
+
+// WithRotationCallback sets the callback invoked after every credentials rotation, with the error returned by RotateCredentials
+func WithRotationCallback(callback func(error)) TLSCredentialsWatcherOption {
+	return func(options *tlsCredentialsWatcherOptions) {
+		options.rotationCallback = callback
+	}
+}
+
+// TLSCredentialsWatcher rotates the credentials of a pool every time the tls secret changes.
+type TLSCredentialsWatcher struct {
+	pool      CredentialsRotator
+	namespace string
+	name      string
+	options   tlsCredentialsWatcherOptions
+
+	// lastData is the data of the secret the last rotation was triggered for.
+	// It's accessed only by the event handler, which is never invoked concurrently.
+	lastData map[string][]byte
+
+	trigger chan struct{}
+}
+
+// StartTLSCredentialsWatcher watches the tls secret namespace/name with the provided secret informer,
+// the same used by the TLSDialerFactory of pool, and rotates the credentials of pool every time the secret data changes.
+// pool must implement CredentialsRotator, look at its RotateCredentials for more details.
+// The failed rotations are logged and notified to the callback set with WithRotationCallback.
+// The watcher stops when ctx is done.
+func StartTLSCredentialsWatcher(ctx context.Context, pool ControlPlaneConnectionPool, informer cache.SharedIndexInformer, namespace string, name string, opts ...TLSCredentialsWatcherOption) (*TLSCredentialsWatcher, error) {
+	rotator, ok := pool.(CredentialsRotator)
+	if !ok {
+		return nil, fmt.Errorf("the pool %T doesn't support the credentials rotation", pool)
+	}
+
+	options := tlsCredentialsWatcherOptions{}
+	for _, fn := range opts {
+		fn(&options)
+	}
+
+	w := &TLSCredentialsWatcher{
+		pool:      rotator,
+		namespace: namespace,
+		name:      name,
+		options:   options,
+		trigger:   make(chan struct{}, 1),
+	}
+
+	// The pool dialed its connections with the secret currently in the cache
+	if obj, ok, err := informer.GetIndexer().GetByKey(namespace + "/" + name); err == nil && ok {
+		if secret, ok := obj.(*corev1.Secret); ok {
+			w.lastData = secret.Data
+		}
+	}
+
+	registration, err := informer.AddEventHandler(cache.FilteringResourceEventHandler{
+		FilterFunc: w.filter,
+		Handler: cache.ResourceEventHandlerFuncs{
+			AddFunc: w.onSecretChange,
+			UpdateFunc: func(_, newObj interface{}) {
+				w.onSecretChange(newObj)
+			},
+			DeleteFunc: func(interface{}) {
+				w.lastData = nil
+				w.Rotate()
+			},
+		},
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	go func() {
+		w.run(ctx)
+		if err := informer.RemoveEventHandler(registration); err != nil {
+			logging.FromContext(ctx).Warnf("Cannot remove the secret event handler for %s/%s: %v", namespace, name, err)
+		}
+	}()
+
+	return w, nil
+}
+
+// Rotate schedules the rotation of the credentials
+func (w *TLSCredentialsWatcher) Rotate() {
+	select {
+	case w.trigger <- struct{}{}:
+	default:
+		// A rotation is already scheduled
+	}
+}
+
+func (w *TLSCredentialsWatcher) run(ctx context.Context) {
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-w.trigger:
+		}
+
+		logging.FromContext(ctx).Infof("Rotating the control connections credentials from secret %s/%s", w.namespace, w.name)
+		err := w.pool.RotateCredentials(ctx)
+		if err != nil {
+			logging.FromContext(ctx).Warnf("Cannot rotate the control connections credentials from secret %s/%s: %v", w.namespace, w.name, err)
+		}
+		if w.options.rotationCallback != nil {
+			w.options.rotationCallback(err)
+		}
+	}
+}
+
+func (w *TLSCredentialsWatcher) onSecretChange(obj interface{}) {
+	secret := obj.(*corev1.Secret)
+	if equality.Semantic.DeepEqual(secret.Data, w.lastData) {
+		// e.g. a resync
+		return
+	}
+	w.lastData = secret.Data
+	w.Rotate()
+}
+
+func (w *TLSCredentialsWatcher) filter(obj interface{}) bool {
+	if tombstone, ok := obj.(cache.DeletedFinalStateUnknown); ok {
+		obj = tombstone.Obj
+	}
+	secret, ok := obj.(*corev1.Secret)
+	if !ok {
+		return false
+	}
+	return secret.Namespace == w.namespace && secret.Name == w.name
+}
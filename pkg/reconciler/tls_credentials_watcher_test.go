@@ -0,0 +1,182 @@
+/*
+Copyright 2026 The Knative Authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package reconciler_test
+
+import (
+	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/rest"
+	fakekubeclient "knative.dev/pkg/client/injection/kube/client/fake"
+	secretinformer "knative.dev/pkg/client/injection/kube/informers/core/v1/secret/fake"
+	"knative.dev/pkg/controller"
+	"knative.dev/pkg/injection"
+
+	"knative.dev/control-protocol/pkg/certificates"
+	"knative.dev/control-protocol/pkg/network"
+	"knative.dev/control-protocol/pkg/reconciler"
+	"knative.dev/control-protocol/pkg/test"
+)
+
+type testCA struct {
+	keyPair *certificates.KeyPair
+	cert    *x509.Certificate
+}
+
+func mustCreateTestCA(t *testing.T) testCA {
+	keyPair, err := certificates.CreateCACerts(context.TODO(), 24*time.Hour)
+	require.NoError(t, err)
+	cert, _, err := keyPair.Parse()
+	require.NoError(t, err)
+	return testCA{keyPair: keyPair, cert: cert}
+}
+
+// secretData returns the data of the control plane secret, signed by ca
+func (ca testCA) secretData(t *testing.T) map[string][]byte {
+	_, caKey, err := ca.keyPair.Parse()
+	require.NoError(t, err)
+	kp, err := certificates.CreateCert(context.TODO(), caKey, ca.cert, 24*time.Hour, certificates.DataPlaneRoutingName(""))
+	require.NoError(t, err)
+	return map[string][]byte{
+		certificates.SecretCaCertKey: ca.keyPair.CertBytes(),
+		certificates.SecretCertKey:   kp.CertBytes(),
+		certificates.SecretPKKey:     kp.PrivateKeyBytes(),
+	}
+}
+
+// serverTLSConfig returns the tls configuration of a data plane in namespace, trusting only the clients signed by ca
+func (ca testCA) serverTLSConfig(t *testing.T, namespace string) *tls.Config {
+	_, caKey, err := ca.keyPair.Parse()
+	require.NoError(t, err)
+	kp, err := certificates.CreateCert(context.TODO(), caKey, ca.cert, 24*time.Hour, certificates.DataPlaneUserName(namespace))
+	require.NoError(t, err)
+	cert, err := tls.X509KeyPair(kp.CertBytes(), kp.PrivateKeyBytes())
+	require.NoError(t, err)
+
+	certPool := x509.NewCertPool()
+	certPool.AddCert(ca.cert)
+	return &tls.Config{
+		Certificates: []tls.Certificate{cert},
+		ClientCAs:    certPool,
+		ClientAuth:   tls.RequireAndVerifyClientCert,
+	}
+}
+
+func TestTLSCredentialsWatcher(t *testing.T) {
+	namespace := "knative-eventing"
+	name := "control-secret"
+
+	ctx, cancelFn := context.WithCancel(context.TODO())
+	t.Cleanup(cancelFn)
+	ctx, informers := injection.Fake.SetupInformers(ctx, &rest.Config{})
+	informer := secretinformer.Get(ctx)
+	require.NoError(t, controller.StartInformers(ctx.Done(), informers...))
+
+	oldCA, newCA := mustCreateTestCA(t), mustCreateTestCA(t)
+
+	// The data plane trusts the clients signed by the current CA
+	var serverTLSConfig atomic.Value
+	serverTLSConfig.Store(oldCA.serverTLSConfig(t, namespace))
+
+	memoryNetwork := network.NewMemoryNetwork()
+	ln, err := memoryNetwork.Listen("10.0.0.1:9000")
+	require.NoError(t, err)
+	server, err := network.StartControlServer(ctx, func() (*tls.Config, error) {
+		return serverTLSConfig.Load().(*tls.Config), nil
+	}, network.WithListener(ln))
+	require.NoError(t, err)
+
+	secrets := fakekubeclient.Get(ctx).CoreV1().Secrets(namespace)
+	secret, err := secrets.Create(ctx, &corev1.Secret{
+		ObjectMeta: metav1.ObjectMeta{Name: name, Namespace: namespace},
+		Data:       oldCA.secretData(t),
+	}, metav1.CreateOptions{})
+	require.NoError(t, err)
+	require.Eventually(t, func() bool {
+		_, err := informer.Lister().Secrets(namespace).Get(name)
+		return err == nil
+	}, 5*time.Second, 10*time.Millisecond)
+
+	connectionPool := reconciler.NewControlPlaneConnectionPool(
+		reconciler.NewCertificateGetter(informer.Lister(), namespace, name),
+		reconciler.WithDialer(memoryNetwork),
+	)
+	t.Cleanup(func() {
+		connectionPool.Close(ctx)
+	})
+	_, svc, err := connectionPool.DialControlService(ctx, "hello", "10.0.0.1:9000")
+	require.NoError(t, err)
+	test.SendReceiveTest(t, svc, server)
+
+	rotations := make(chan error, 10)
+	_, err = reconciler.StartTLSCredentialsWatcher(ctx, connectionPool, informer.Informer(), namespace, name, reconciler.WithRotationCallback(func(err error) {
+		rotations <- err
+	}))
+	require.NoError(t, err)
+
+	// The CA rotates
+	serverTLSConfig.Store(newCA.serverTLSConfig(t, namespace))
+	secret = secret.DeepCopy()
+	secret.Data = newCA.secretData(t)
+	secret, err = secrets.Update(ctx, secret, metav1.UpdateOptions{})
+	require.NoError(t, err)
+
+	select {
+	case err := <-rotations:
+		require.NoError(t, err)
+	case <-time.After(5 * time.Second):
+		require.FailNow(t, "timeout waiting for the credentials rotation")
+	}
+	require.Len(t, rotations, 0)
+
+	// The same service is now connected with the new credentials
+	_, cycledSvc := connectionPool.ResolveControlInterface("hello", "10.0.0.1:9000")
+	require.Equal(t, svc, cycledSvc)
+	state, ok := connectionPool.(reconciler.ConnectionStateObserver).GetConnectionState("hello", "10.0.0.1:9000")
+	require.True(t, ok)
+	require.Equal(t, network.ConnectionReady, state)
+	test.SendReceiveTest(t, svc, server)
+
+	// Invalid credentials are reported, without cycling the connections
+	secret = secret.DeepCopy()
+	delete(secret.Data, certificates.SecretPKKey)
+	_, err = secrets.Update(ctx, secret, metav1.UpdateOptions{})
+	require.NoError(t, err)
+
+	select {
+	case err := <-rotations:
+		require.ErrorContains(t, err, "cannot rebuild the dialer")
+	case <-time.After(5 * time.Second):
+		require.FailNow(t, "timeout waiting for the credentials rotation")
+	}
+	test.SendReceiveTest(t, svc, server)
+}
+
+func TestCredentialsRotationError(t *testing.T) {
+	err := &reconciler.CredentialsRotationError{Failed: map[string]map[string]error{
+		"b": {"10.0.0.1:9000": context.DeadlineExceeded},
+		"a": {"10.0.0.2:9000": context.Canceled},
+	}}
+	require.EqualError(t, err, "cannot cycle 2 connection(s): a/10.0.0.2:9000: context canceled; b/10.0.0.1:9000: context deadline exceeded")
+}
@@ -46,26 +46,10 @@ func NewCertificateGetter(secrets v1.SecretLister, namespace string, name string
 }
 
 func (ch *ListerCertificateGetter) GenerateTLSDialer(baseDialOptions *net.Dialer) (*tls.Dialer, error) {
-	secret, err := ch.secrets.Secrets(ch.namespace).Get(ch.name)
+	controlPlaneCert, certPool, err := ch.loadCertificates()
 	if err != nil {
 		return nil, err
 	}
-	if secret == nil || secret.Data == nil {
-		return nil, fmt.Errorf("no tls configuration available")
-	}
-	caCertBytes := secret.Data[certificates.SecretCaCertKey]
-	certBytes := secret.Data[certificates.SecretCertKey]
-	privateKeyBytes := secret.Data[certificates.SecretPKKey]
-
-	if caCertBytes == nil || certBytes == nil || privateKeyBytes == nil {
-		return nil, fmt.Errorf("no tls configuration available")
-	}
-	controlPlaneCert, err := tls.X509KeyPair(certBytes, privateKeyBytes)
-	if err != nil {
-		return nil, err
-	}
-	certPool := x509.NewCertPool()
-	certPool.AppendCertsFromPEM(caCertBytes)
 
 	tlsConfig := &tls.Config{
 		Certificates: []tls.Certificate{controlPlaneCert},
@@ -81,3 +65,42 @@ func (ch *ListerCertificateGetter) GenerateTLSDialer(baseDialOptions *net.Dialer
 		Config:    tlsConfig,
 	}, nil
 }
+
+// LoadListenerTLSConfig loads the tls configuration of the listener accepting the connections dialed by the data planes,
+// look at StartReverseConnectionListener. The data planes must present a certificate signed by the same CA.
+func (ch *ListerCertificateGetter) LoadListenerTLSConfig() (*tls.Config, error) {
+	controlPlaneCert, certPool, err := ch.loadCertificates()
+	if err != nil {
+		return nil, err
+	}
+
+	return &tls.Config{
+		Certificates: []tls.Certificate{controlPlaneCert},
+		ClientCAs:    certPool,
+		ClientAuth:   tls.RequireAndVerifyClientCert,
+	}, nil
+}
+
+func (ch *ListerCertificateGetter) loadCertificates() (tls.Certificate, *x509.CertPool, error) {
+	secret, err := ch.secrets.Secrets(ch.namespace).Get(ch.name)
+	if err != nil {
+		return tls.Certificate{}, nil, err
+	}
+	if secret == nil || secret.Data == nil {
+		return tls.Certificate{}, nil, fmt.Errorf("no tls configuration available")
+	}
+	caCertBytes := secret.Data[certificates.SecretCaCertKey]
+	certBytes := secret.Data[certificates.SecretCertKey]
+	privateKeyBytes := secret.Data[certificates.SecretPKKey]
+
+	if caCertBytes == nil || certBytes == nil || privateKeyBytes == nil {
+		return tls.Certificate{}, nil, fmt.Errorf("no tls configuration available")
+	}
+	controlPlaneCert, err := tls.X509KeyPair(certBytes, privateKeyBytes)
+	if err != nil {
+		return tls.Certificate{}, nil, err
+	}
+	certPool := x509.NewCertPool()
+	certPool.AppendCertsFromPEM(caCertBytes)
+	return controlPlaneCert, certPool, nil
+}
@@ -19,12 +19,29 @@ package control
 import (
 	"context"
 	"encoding"
+
+	"github.com/google/uuid"
 )
 
 type OpCode uint8
 
 const AckOpCode = OpCode(^uint8(0))
 
+// CreditOpCode is reserved to the credit grants of the flow control, exchanged by the network layer
+const CreditOpCode = OpCode(^uint8(0) - 1)
+
+// RegistrationOpCode is reserved to the registration of the data planes dialing the control plane, look at message.Registration
+const RegistrationOpCode = OpCode(^uint8(0) - 2)
+
+// MinReservedOpCode is the lowest opcode reserved to the protocol.
+// The opcodes from MinReservedOpCode to AckOpCode must not be used by the applications: the control services reject them.
+const MinReservedOpCode = RegistrationOpCode
+
+// IsReserved returns true if the opcode is reserved to the protocol, look at MinReservedOpCode
+func (o OpCode) IsReserved() bool {
+	return o >= MinReservedOpCode
+}
+
 type ServiceMessage struct {
 	inboundMessage *Message
 	ackFunc        func(err error)
@@ -45,6 +62,16 @@ func (c ServiceMessage) Payload() []byte {
 	return c.inboundMessage.payload
 }
 
+// OnAck returns a copy of this message invoking fn, with the eventual error, right before acking it to the other end of the connection.
+func (c ServiceMessage) OnAck(fn func(err error)) ServiceMessage {
+	ackFunc := c.ackFunc
+	c.ackFunc = func(err error) {
+		fn(err)
+		ackFunc(err)
+	}
+	return c
+}
+
 // Ack this message to the other end of the connection.
 func (c ServiceMessage) Ack() {
 	c.ackFunc(nil)
@@ -92,5 +119,26 @@ type Service interface {
 	ErrorHandler(handler ErrorHandler)
 }
 
+// UUIDSender is implemented by the services that can send a message using a provided uuid.
+// Sending again a message with the same uuid allows the other end to deduplicate it, e.g. when retrying.
+type UUIDSender interface {
+	// SendWithUUIDAndWaitForAck sends a message with the provided uuid to the other end and waits for the ack
+	SendWithUUIDAndWaitForAck(uuid uuid.UUID, opcode OpCode, payload encoding.BinaryMarshaler) error
+}
+
+// ContextSender is implemented by the services that can send a message using a context.
+// The context can carry additional information about the message, like the trace context or the message uuid,
+// and it can interrupt the wait for the ack.
+type ContextSender interface {
+	// SendAndWaitForAckWithContext sends a message to the other end and waits for the ack, or for the context to be done
+	SendAndWaitForAckWithContext(ctx context.Context, opcode OpCode, payload encoding.BinaryMarshaler) error
+}
+
 // ServiceWrapper wraps a service in another service to offer additional functionality
 type ServiceWrapper func(Service) Service
+
+// ServiceUnwrapper is implemented by the services returned by a ServiceWrapper, to access the wrapped service
+type ServiceUnwrapper interface {
+	// Unwrap returns the wrapped service
+	Unwrap() Service
+}
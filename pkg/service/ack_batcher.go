@@ -0,0 +1,129 @@
+/*
+Copyright 2026 The Knative Authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package service
+
+import (
+	"sync"
+	"time"
+
+	"github.com/google/uuid"
+	"k8s.io/utils/clock"
+
+	ctrl "knative.dev/control-protocol/pkg"
+)
+
+const (
+	// maxAckBatchDelay bounds the batching delay, so the sender receives the ack well before its timeout
+	maxAckBatchDelay = controlServiceSendTimeout / 10
+)
+
+// ackBatcher accumulates successful acks and writes them in a single batched ack message,
+// either after maxDelay since the first accumulated ack, or when maxBatch acks are accumulated.
+type ackBatcher struct {
+	connection ctrl.Connection
+	maxDelay   time.Duration
+	maxBatch   int
+	clock      clock.WithDelayedExecution
+
+	mutex   sync.Mutex
+	pending []uuid.UUID
+	timer   clock.Timer
+	// stopped is true after the service ctx is done, then the acks are not written anymore
+	stopped bool
+}
+
+func newAckBatcher(connection ctrl.Connection, maxDelay time.Duration, maxBatch int) *ackBatcher {
+	if maxDelay > maxAckBatchDelay {
+		maxDelay = maxAckBatchDelay
+	}
+	if maxBatch < 1 {
+		maxBatch = 1
+	}
+	return &ackBatcher{
+		connection: connection,
+		maxDelay:   maxDelay,
+		maxBatch:   maxBatch,
+		clock:      clock.RealClock{},
+	}
+}
+
+func (b *ackBatcher) add(id uuid.UUID) {
+	b.mutex.Lock()
+	if b.stopped {
+		b.mutex.Unlock()
+		return
+	}
+	b.pending = append(b.pending, id)
+	if len(b.pending) < b.maxBatch {
+		if b.timer == nil {
+			b.timer = b.clock.AfterFunc(b.maxDelay, b.flush)
+		}
+		b.mutex.Unlock()
+		return
+	}
+	batch := b.takeLocked()
+	b.mutex.Unlock()
+
+	b.write(batch)
+}
+
+func (b *ackBatcher) flush() {
+	b.mutex.Lock()
+	batch := b.takeLocked()
+	b.mutex.Unlock()
+
+	b.write(batch)
+}
+
+// stop discards the pending acks and stops the flush timer, since the connection is closing
+func (b *ackBatcher) stop() {
+	b.mutex.Lock()
+	b.stopped = true
+	b.takeLocked()
+	b.mutex.Unlock()
+}
+
+func (b *ackBatcher) takeLocked() []uuid.UUID {
+	if b.timer != nil {
+		b.timer.Stop()
+		b.timer = nil
+	}
+	batch := b.pending
+	b.pending = nil
+	return batch
+}
+
+func (b *ackBatcher) write(batch []uuid.UUID) {
+	if len(batch) == 0 {
+		return
+	}
+	var ackMsg ctrl.Message
+	if len(batch) == 1 {
+		ackMsg = newAckMessage(batch[0], nil)
+	} else {
+		ackMsg = newBatchedAckMessage(batch)
+	}
+	b.connection.WriteMessage(&ackMsg)
+}
+
+func newBatchedAckMessage(ids []uuid.UUID) ctrl.Message {
+	payload := make([]byte, 0, len(ids)*16)
+	for _, id := range ids {
+		payload = append(payload, id[:]...)
+	}
+	return ctrl.NewMessage(uuid.Nil, uint8(ctrl.AckOpCode), payload, ctrl.WithFlags(uint8(ctrl.BatchedAckFlag)))
+}
@@ -25,9 +25,12 @@ import (
 	"time"
 
 	"github.com/google/uuid"
+	"k8s.io/utils/clock"
 	"knative.dev/pkg/logging"
 
 	ctrl "knative.dev/control-protocol/pkg"
+	"knative.dev/control-protocol/pkg/internal/reserved"
+	"knative.dev/control-protocol/pkg/metrics"
 )
 
 const (
@@ -47,21 +50,86 @@ type service struct {
 
 	errorHandlerMutex sync.RWMutex
 	errorHandler      ctrl.ErrorHandler
+
+	ackBatcher *ackBatcher
+
+	clock clock.WithDelayedExecution
+}
+
+var _ ctrl.UUIDSender = (*service)(nil)
+var _ ctrl.ContextSender = (*service)(nil)
+
+type ServiceOption func(*service)
+
+// WithBatchedAcks batches the successful acks of the inbound messages,
+// writing them in a single ack message either after maxDelay, or when maxBatch acks are accumulated.
+// Acks with errors are always written immediately.
+// maxDelay is capped to a fraction of the send timeout, so the other end still receives the acks in time.
+// Both ends of the connection must run a version of the control protocol supporting batched acks.
+func WithBatchedAcks(maxDelay time.Duration, maxBatch int) ServiceOption {
+	return func(s *service) {
+		s.ackBatcher = newAckBatcher(s.connection, maxDelay, maxBatch)
+	}
+}
+
+// WithClock sets the clock used for the ack timeout and the ack batching delay.
+// Tests can provide a fake clock, to advance the time deterministically.
+func WithClock(clock clock.WithDelayedExecution) ServiceOption {
+	return func(s *service) {
+		s.clock = clock
+	}
 }
 
-func NewService(ctx context.Context, connection ctrl.Connection) *service {
+func NewService(ctx context.Context, connection ctrl.Connection, opts ...ServiceOption) *service {
 	cs := &service{
 		ctx:          ctx,
 		connection:   connection,
 		waitingAcks:  make(map[uuid.UUID]chan error),
 		handler:      NoopMessageHandler,
 		errorHandler: LoggerErrorHandler,
+		clock:        clock.RealClock{},
+	}
+
+	for _, fn := range opts {
+		fn(cs)
 	}
+	if cs.ackBatcher != nil {
+		// The clock might have been configured after the batched acks
+		cs.ackBatcher.clock = cs.clock
+		go func() {
+			<-ctx.Done()
+			cs.ackBatcher.stop()
+		}()
+	}
+
 	cs.startPolling()
 	return cs
 }
 
+// AckTimeoutError is returned when the ack of a sent message is not received in time.
+type AckTimeoutError struct {
+	UUID uuid.UUID
+}
+
+func (e *AckTimeoutError) Error() string {
+	return fmt.Sprintf("timeout exceeded for outgoing message: %s", e.UUID.String())
+}
+
+// IsAckTimeout returns true if the error is, or wraps, an AckTimeoutError
+func IsAckTimeout(err error) bool {
+	var timeoutErr *AckTimeoutError
+	return errors.As(err, &timeoutErr)
+}
+
 func (c *service) SendAndWaitForAck(opcode ctrl.OpCode, payload encoding.BinaryMarshaler) error {
+	return c.SendAndWaitForAckWithContext(c.ctx, opcode, payload)
+}
+
+func (c *service) SendWithUUIDAndWaitForAck(id uuid.UUID, opcode ctrl.OpCode, payload encoding.BinaryMarshaler) error {
+	return c.SendAndWaitForAckWithContext(ctrl.ContextWithMessageUUID(c.ctx, id), opcode, payload)
+}
+
+func (c *service) SendAndWaitForAckWithContext(ctx context.Context, opcode ctrl.OpCode, payload encoding.BinaryMarshaler) error {
 	var b []byte
 	var err error
 	if payload != nil {
@@ -70,20 +138,39 @@ func (c *service) SendAndWaitForAck(opcode ctrl.OpCode, payload encoding.BinaryM
 			return err
 		}
 	}
-	return c.sendBinaryAndWaitForAck(opcode, b)
+
+	id, ok := ctrl.MessageUUIDFromContext(ctx)
+	if !ok {
+		id = uuid.New()
+	}
+	var opts []ctrl.MessageOpt
+	if traceContext := ctrl.TraceContextFromContext(ctx); traceContext != nil {
+		opts = append(opts, ctrl.WithTraceContext(traceContext))
+	}
+	return c.sendBinaryAndWaitForAck(ctx, id, opcode, b, opts...)
 }
 
-func (c *service) sendBinaryAndWaitForAck(opcode ctrl.OpCode, payload []byte) error {
+func (c *service) sendBinaryAndWaitForAck(ctx context.Context, id uuid.UUID, opcode ctrl.OpCode, payload []byte, opts ...ctrl.MessageOpt) error {
 	if opcode == ctrl.AckOpCode {
 		return fmt.Errorf("you cannot send an ack manually")
 	}
-	msg := ctrl.NewMessage(uuid.New(), uint8(opcode), payload)
+	if opcode == ctrl.CreditOpCode {
+		return fmt.Errorf("you cannot send a credit grant manually")
+	}
+	if opcode.IsReserved() && !reserved.Allowed(ctx, opcode) {
+		return fmt.Errorf("the opcode %d is reserved to the control protocol", opcode)
+	}
+	msg := ctrl.NewMessage(id, uint8(opcode), payload, opts...)
 
 	logging.FromContext(c.ctx).Debugf("Going to send message with opcode %d and uuid %s", msg.OpCode(), msg.UUID().String())
 
 	// Register the ack between the waiting acks
 	ackCh := make(chan error, 1)
 	c.waitingAcksMutex.Lock()
+	if _, ok := c.waitingAcks[msg.UUID()]; ok {
+		c.waitingAcksMutex.Unlock()
+		return fmt.Errorf("a message with uuid %s is already waiting for the ack", msg.UUID().String())
+	}
 	c.waitingAcks[msg.UUID()] = ackCh
 	c.waitingAcksMutex.Unlock()
 
@@ -93,17 +180,30 @@ func (c *service) sendBinaryAndWaitForAck(opcode ctrl.OpCode, payload []byte) er
 		c.waitingAcksMutex.Unlock()
 	}()
 
+	sentAt := c.clock.Now()
 	c.connection.WriteMessage(&msg)
+	metrics.RecordMessageSent(c.ctx, opcode)
+
+	timeout := c.clock.NewTimer(controlServiceSendTimeout)
+	defer timeout.Stop()
 
 	select {
 	case err := <-ackCh:
+		metrics.RecordAckLatency(c.ctx, opcode, c.clock.Since(sentAt))
+		if err != nil {
+			metrics.RecordAckError(c.ctx, opcode, metrics.AckErrorNack)
+		}
 		return err
 	case <-c.ctx.Done():
 		logging.FromContext(c.ctx).Warnf("Dropping message because context cancelled: %s", msg.UUID().String())
 		return c.ctx.Err()
-	case <-time.After(controlServiceSendTimeout):
+	case <-ctx.Done():
+		logging.FromContext(c.ctx).Debugf("Stopped waiting for the ack because the send context is done: %s", msg.UUID().String())
+		return ctx.Err()
+	case <-timeout.C():
 		logging.FromContext(c.ctx).Debugf("Timeout waiting for the ack: %s", msg.UUID().String())
-		return fmt.Errorf("timeout exceeded for outgoing message: %s", msg.UUID().String())
+		metrics.RecordAckError(c.ctx, opcode, metrics.AckErrorTimeout)
+		return &AckTimeoutError{UUID: msg.UUID()}
 	}
 }
 
@@ -151,32 +251,70 @@ func (c *service) startPolling() {
 
 func (c *service) accept(msg *ctrl.Message) {
 	if msg.OpCode() == uint8(ctrl.AckOpCode) {
-		// Propagate the ack
-		c.waitingAcksMutex.Lock()
-		ackCh := c.waitingAcks[msg.UUID()]
-		c.waitingAcksMutex.Unlock()
+		if msg.Check(ctrl.BatchedAckFlag) {
+			payload := msg.Payload()
+			if len(payload)%16 != 0 {
+				logging.FromContext(c.ctx).Warnf("Discarding malformed batched ack with payload length %d", len(payload))
+				return
+			}
+			for i := 0; i < len(payload); i += 16 {
+				var id uuid.UUID
+				copy(id[:], payload[i:i+16])
+				c.propagateAck(id, nil)
+			}
+			return
+		}
+
 		var err error
 		if msg.Length() != 0 {
 			err = errors.New(string(msg.Payload()))
 		}
-		if ackCh != nil {
-			ackCh <- err
-			close(ackCh)
-			logging.FromContext(c.ctx).Debugf("Acked message: %s", msg.UUID().String())
-		} else {
-			logging.FromContext(c.ctx).Debugf("Ack received but no channel available: %s", msg.UUID().String())
-		}
+		c.propagateAck(msg.UUID(), err)
 	} else {
+		opcode := ctrl.OpCode(msg.OpCode())
+		metrics.RecordMessageReceived(c.ctx, opcode)
+		deliveredAt := c.clock.Now()
 		ackFunc := func(err error) {
+			metrics.RecordHandlerDuration(c.ctx, opcode, c.clock.Since(deliveredAt))
+			if err == nil && c.ackBatcher != nil {
+				c.ackBatcher.add(msg.UUID())
+				return
+			}
 			ackMsg := newAckMessage(msg.UUID(), err)
 			c.connection.WriteMessage(&ackMsg)
 		}
+		ctx := c.ctx
+		traceContext, err := msg.StripTraceContext()
+		if err != nil {
+			logging.FromContext(c.ctx).Warnf("Discarding message %s with malformed trace context: %v", msg.UUID().String(), err)
+			ackFunc(err)
+			return
+		}
+		if traceContext != nil {
+			ctx = ctrl.ContextWithTraceContext(ctx, traceContext)
+		}
+
 		c.handlerMutex.RLock()
-		c.handler.HandleServiceMessage(c.ctx, ctrl.NewServiceMessage(msg, ackFunc))
+		c.handler.HandleServiceMessage(ctx, ctrl.NewServiceMessage(msg, ackFunc))
 		c.handlerMutex.RUnlock()
 	}
 }
 
+func (c *service) propagateAck(id uuid.UUID, err error) {
+	// The ack channel is removed while holding the lock, so a duplicate ack cannot write to the closed channel
+	c.waitingAcksMutex.Lock()
+	ackCh := c.waitingAcks[id]
+	delete(c.waitingAcks, id)
+	c.waitingAcksMutex.Unlock()
+	if ackCh != nil {
+		ackCh <- err
+		close(ackCh)
+		logging.FromContext(c.ctx).Debugf("Acked message: %s", id.String())
+	} else {
+		logging.FromContext(c.ctx).Debugf("Ack received but no channel available: %s", id.String())
+	}
+}
+
 func (c *service) acceptError(err error) {
 	c.errorHandlerMutex.RLock()
 	c.errorHandler.HandleServiceError(c.ctx, err)
@@ -190,3 +328,24 @@ func newAckMessage(uuid [16]byte, err error) ctrl.Message {
 	}
 	return ctrl.NewMessage(uuid, uint8(ctrl.AckOpCode), payload)
 }
+
+// sendWithContext sends the message using the provided context if the service supports it,
+// otherwise it falls back to SendAndWaitForAck, ignoring the context.
+func sendWithContext(ctx context.Context, svc ctrl.Service, opcode ctrl.OpCode, payload encoding.BinaryMarshaler) error {
+	if sender, ok := svc.(ctrl.ContextSender); ok {
+		return sender.SendAndWaitForAckWithContext(ctx, opcode, payload)
+	}
+	return svc.SendAndWaitForAck(opcode, payload)
+}
+
+// sendWithUUID sends the message with the provided uuid if the service supports it,
+// otherwise it falls back to SendAndWaitForAck, which generates a new uuid.
+func sendWithUUID(svc ctrl.Service, id uuid.UUID, opcode ctrl.OpCode, payload encoding.BinaryMarshaler) error {
+	if sender, ok := svc.(ctrl.UUIDSender); ok {
+		return sender.SendWithUUIDAndWaitForAck(id, opcode, payload)
+	}
+	if sender, ok := svc.(ctrl.ContextSender); ok {
+		return sender.SendAndWaitForAckWithContext(ctrl.ContextWithMessageUUID(context.Background(), id), opcode, payload)
+	}
+	return svc.SendAndWaitForAck(opcode, payload)
+}
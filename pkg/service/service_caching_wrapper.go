@@ -22,6 +22,7 @@ import (
 	"reflect"
 	"sync"
 
+	"github.com/google/uuid"
 	"knative.dev/pkg/logging"
 
 	control "knative.dev/control-protocol/pkg"
@@ -37,6 +38,9 @@ type cachingService struct {
 }
 
 var _ control.Service = (*cachingService)(nil)
+var _ control.UUIDSender = (*cachingService)(nil)
+var _ control.ContextSender = (*cachingService)(nil)
+var _ control.ServiceUnwrapper = (*cachingService)(nil)
 
 // WithCachingService will cache last message sent for each opcode and,
 // in case you try to send a message again with the same opcode and payload, the message won't be sent again.
@@ -50,7 +54,27 @@ func WithCachingService(ctx context.Context) control.ServiceWrapper {
 	}
 }
 
+func (c *cachingService) Unwrap() control.Service {
+	return c.Service
+}
+
 func (c *cachingService) SendAndWaitForAck(opcode control.OpCode, payload encoding.BinaryMarshaler) error {
+	return c.send(opcode, payload, c.Service.SendAndWaitForAck)
+}
+
+func (c *cachingService) SendWithUUIDAndWaitForAck(id uuid.UUID, opcode control.OpCode, payload encoding.BinaryMarshaler) error {
+	return c.send(opcode, payload, func(opcode control.OpCode, payload encoding.BinaryMarshaler) error {
+		return sendWithUUID(c.Service, id, opcode, payload)
+	})
+}
+
+func (c *cachingService) SendAndWaitForAckWithContext(ctx context.Context, opcode control.OpCode, payload encoding.BinaryMarshaler) error {
+	return c.send(opcode, payload, func(opcode control.OpCode, payload encoding.BinaryMarshaler) error {
+		return sendWithContext(ctx, c.Service, opcode, payload)
+	})
+}
+
+func (c *cachingService) send(opcode control.OpCode, payload encoding.BinaryMarshaler, sendFn func(control.OpCode, encoding.BinaryMarshaler) error) error {
 	c.sentMessageMutex.RLock()
 	lastPayload, ok := c.sentMessages[opcode]
 	c.sentMessageMutex.RUnlock()
@@ -58,7 +82,7 @@ func (c *cachingService) SendAndWaitForAck(opcode control.OpCode, payload encodi
 		logging.FromContext(c.ctx).Debugf("Message with opcode %d already sent with payload: %v", opcode, lastPayload)
 		return nil
 	}
-	err := c.Service.SendAndWaitForAck(opcode, payload)
+	err := sendFn(opcode, payload)
 	if err != nil {
 		return err
 	}
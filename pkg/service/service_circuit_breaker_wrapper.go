@@ -0,0 +1,336 @@
+/*
+Copyright 2026 The Knative Authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package service
+
+import (
+	"context"
+	"encoding"
+	"errors"
+	"sync"
+	"time"
+
+	"github.com/google/uuid"
+	"k8s.io/utils/clock"
+	"knative.dev/pkg/logging"
+
+	control "knative.dev/control-protocol/pkg"
+)
+
+const (
+	defaultCircuitBreakerFailureThreshold = 5
+	defaultCircuitBreakerCoolDown         = 30 * time.Second
+	defaultCircuitBreakerHalfOpenProbes   = 1
+)
+
+// ErrCircuitOpen is returned by a service wrapped with WithCircuitBreakerService when the circuit is open
+var ErrCircuitOpen = errors.New("circuit breaker is open")
+
+// CircuitState is the state of a circuit breaker
+type CircuitState int
+
+const (
+	// CircuitClosed lets all the messages through
+	CircuitClosed CircuitState = iota
+	// CircuitOpen fails fast all the messages, until the cool-down period expires
+	CircuitOpen
+	// CircuitHalfOpen lets a limited number of probe messages through, to test whether the other end recovered
+	CircuitHalfOpen
+)
+
+func (s CircuitState) String() string {
+	switch s {
+	case CircuitClosed:
+		return "closed"
+	case CircuitOpen:
+		return "open"
+	case CircuitHalfOpen:
+		return "half-open"
+	default:
+		return "unknown"
+	}
+}
+
+// CircuitStateChangeCallback is invoked every time the circuit of a service changes its state.
+// name identifies the circuit, look at WithCircuitBreakerName.
+// svc is the service returned by the circuit breaker wrapper, which the wrappers applied after it might wrap further.
+type CircuitStateChangeCallback func(name string, svc control.Service, from CircuitState, to CircuitState)
+
+type circuitBreakerOptions struct {
+	name             string
+	failureThreshold int
+	coolDown         time.Duration
+	halfOpenProbes   int
+	isFailure        func(error) bool
+	callbacks        []CircuitStateChangeCallback
+	clock            clock.PassiveClock
+}
+
+type CircuitBreakerOption func(*circuitBreakerOptions)
+
+// WithCircuitBreakerName sets the name identifying the circuit in the state change callbacks and in the logs,
+// e.g. the host of the wrapped service. Look at reconciler.WithHostServiceWrapper to name the circuit of every host of a pool.
+func WithCircuitBreakerName(name string) CircuitBreakerOption {
+	return func(options *circuitBreakerOptions) {
+		options.name = name
+	}
+}
+
+// WithFailureThreshold sets the number of consecutive failures that opens the circuit
+func WithFailureThreshold(failures int) CircuitBreakerOption {
+	return func(options *circuitBreakerOptions) {
+		options.failureThreshold = failures
+	}
+}
+
+// WithCoolDown sets for how long the circuit stays open before letting the probe messages through
+func WithCoolDown(coolDown time.Duration) CircuitBreakerOption {
+	return func(options *circuitBreakerOptions) {
+		options.coolDown = coolDown
+	}
+}
+
+// WithHalfOpenProbes sets the maximum number of concurrent probe messages let through when the circuit is half-open
+func WithHalfOpenProbes(probes int) CircuitBreakerOption {
+	return func(options *circuitBreakerOptions) {
+		options.halfOpenProbes = probes
+	}
+}
+
+// WithFailurePredicate sets the function deciding whether a send error counts as a failure.
+// By default, every error except the context cancellation counts as a failure.
+func WithFailurePredicate(isFailure func(error) bool) CircuitBreakerOption {
+	return func(options *circuitBreakerOptions) {
+		options.isFailure = isFailure
+	}
+}
+
+// WithStateChangeCallback adds a callback invoked every time the circuit changes its state.
+// Callbacks are invoked outside the lock of the circuit breaker, one at a time and in the order of the state changes,
+// possibly by a goroutine sending through the service other than the one which changed the state.
+func WithStateChangeCallback(callback CircuitStateChangeCallback) CircuitBreakerOption {
+	return func(options *circuitBreakerOptions) {
+		options.callbacks = append(options.callbacks, callback)
+	}
+}
+
+// WithCircuitBreakerClock sets the clock used to measure the cool-down period
+func WithCircuitBreakerClock(clock clock.PassiveClock) CircuitBreakerOption {
+	return func(options *circuitBreakerOptions) {
+		options.clock = clock
+	}
+}
+
+func defaultIsFailure(err error) bool {
+	return !errors.Is(err, context.Canceled)
+}
+
+type circuitBreakerService struct {
+	control.Service
+
+	ctx     context.Context
+	options circuitBreakerOptions
+
+	mutex               sync.Mutex
+	state               CircuitState
+	consecutiveFailures int
+	openedAt            time.Time
+	inFlightProbes      int
+	// transitions are the state changes not yet notified, in order
+	transitions []circuitTransition
+	// notifying is true while a goroutine is notifying the transitions
+	notifying bool
+}
+
+type circuitTransition struct {
+	from CircuitState
+	to   CircuitState
+}
+
+var _ control.Service = (*circuitBreakerService)(nil)
+var _ control.UUIDSender = (*circuitBreakerService)(nil)
+var _ control.ContextSender = (*circuitBreakerService)(nil)
+var _ control.ServiceUnwrapper = (*circuitBreakerService)(nil)
+
+// WithCircuitBreakerService opens the circuit after a number of consecutive failures,
+// failing fast with ErrCircuitOpen every message for a cool-down period.
+// After the cool-down, the circuit becomes half-open and lets some probe messages through:
+// if a probe succeeds the circuit is closed again, otherwise it's open for another cool-down period.
+// Every wrapped service has its own circuit.
+func WithCircuitBreakerService(ctx context.Context, opts ...CircuitBreakerOption) control.ServiceWrapper {
+	options := circuitBreakerOptions{
+		failureThreshold: defaultCircuitBreakerFailureThreshold,
+		coolDown:         defaultCircuitBreakerCoolDown,
+		halfOpenProbes:   defaultCircuitBreakerHalfOpenProbes,
+		isFailure:        defaultIsFailure,
+		clock:            clock.RealClock{},
+	}
+	for _, fn := range opts {
+		fn(&options)
+	}
+
+	return func(service control.Service) control.Service {
+		return &circuitBreakerService{
+			Service: service,
+			ctx:     ctx,
+			options: options,
+		}
+	}
+}
+
+// GetCircuitState returns the state of the circuit of a service wrapped with WithCircuitBreakerService,
+// looking through the other wrappers implementing control.ServiceUnwrapper.
+// It returns false if the service is not wrapped with a circuit breaker.
+func GetCircuitState(svc control.Service) (CircuitState, bool) {
+	for svc != nil {
+		if cb, ok := svc.(*circuitBreakerService); ok {
+			cb.mutex.Lock()
+			defer cb.mutex.Unlock()
+			return cb.state, true
+		}
+		unwrapper, ok := svc.(control.ServiceUnwrapper)
+		if !ok {
+			break
+		}
+		svc = unwrapper.Unwrap()
+	}
+	return CircuitClosed, false
+}
+
+func (c *circuitBreakerService) Unwrap() control.Service {
+	return c.Service
+}
+
+func (c *circuitBreakerService) SendAndWaitForAck(opcode control.OpCode, payload encoding.BinaryMarshaler) error {
+	return c.send(func() error {
+		return c.Service.SendAndWaitForAck(opcode, payload)
+	})
+}
+
+func (c *circuitBreakerService) SendWithUUIDAndWaitForAck(id uuid.UUID, opcode control.OpCode, payload encoding.BinaryMarshaler) error {
+	return c.send(func() error {
+		return sendWithUUID(c.Service, id, opcode, payload)
+	})
+}
+
+func (c *circuitBreakerService) SendAndWaitForAckWithContext(ctx context.Context, opcode control.OpCode, payload encoding.BinaryMarshaler) error {
+	return c.send(func() error {
+		return sendWithContext(ctx, c.Service, opcode, payload)
+	})
+}
+
+func (c *circuitBreakerService) send(sendFn func() error) error {
+	probe, err := c.allow()
+	if err != nil {
+		return err
+	}
+	err = sendFn()
+	c.record(probe, err)
+	return err
+}
+
+// allow checks whether the message can be sent, returning true if it's sent as a probe
+func (c *circuitBreakerService) allow() (bool, error) {
+	c.mutex.Lock()
+	switch c.state {
+	case CircuitClosed:
+		c.mutex.Unlock()
+		return false, nil
+	case CircuitOpen:
+		if c.options.clock.Since(c.openedAt) < c.options.coolDown {
+			c.mutex.Unlock()
+			return false, ErrCircuitOpen
+		}
+		c.inFlightProbes++
+		c.transitionLocked(CircuitHalfOpen)
+		c.mutex.Unlock()
+		c.notifyTransitions()
+		return true, nil
+	default:
+		if c.inFlightProbes >= c.options.halfOpenProbes {
+			c.mutex.Unlock()
+			return false, ErrCircuitOpen
+		}
+		c.inFlightProbes++
+		c.mutex.Unlock()
+		return true, nil
+	}
+}
+
+func (c *circuitBreakerService) record(probe bool, err error) {
+	failed := err != nil && c.options.isFailure(err)
+
+	c.mutex.Lock()
+	if probe {
+		c.inFlightProbes--
+	}
+	switch {
+	case !failed && err != nil:
+		// Not a failure, e.g. the context was cancelled, nothing to record
+	case !failed:
+		c.consecutiveFailures = 0
+		if c.state == CircuitHalfOpen && probe {
+			c.transitionLocked(CircuitClosed)
+		}
+	case c.state == CircuitHalfOpen && probe:
+		c.transitionLocked(CircuitOpen)
+	case c.state == CircuitClosed:
+		c.consecutiveFailures++
+		if c.consecutiveFailures >= c.options.failureThreshold {
+			c.transitionLocked(CircuitOpen)
+		}
+	}
+	c.mutex.Unlock()
+
+	c.notifyTransitions()
+}
+
+func (c *circuitBreakerService) transitionLocked(to CircuitState) {
+	c.transitions = append(c.transitions, circuitTransition{from: c.state, to: to})
+	c.state = to
+	switch to {
+	case CircuitOpen:
+		c.openedAt = c.options.clock.Now()
+	case CircuitClosed:
+		c.consecutiveFailures = 0
+	}
+}
+
+// notifyTransitions notifies the pending transitions, unless another goroutine is already notifying them
+func (c *circuitBreakerService) notifyTransitions() {
+	c.mutex.Lock()
+	if c.notifying {
+		c.mutex.Unlock()
+		return
+	}
+	c.notifying = true
+	for len(c.transitions) != 0 {
+		t := c.transitions[0]
+		c.transitions = c.transitions[1:]
+		c.mutex.Unlock()
+		c.notify(t.from, t.to)
+		c.mutex.Lock()
+	}
+	c.notifying = false
+	c.mutex.Unlock()
+}
+
+func (c *circuitBreakerService) notify(from CircuitState, to CircuitState) {
+	logging.FromContext(c.ctx).Debugf("Circuit breaker %q changed state from %s to %s", c.options.name, from, to)
+	for _, cb := range c.options.callbacks {
+		cb(c.options.name, c, from, to)
+	}
+}
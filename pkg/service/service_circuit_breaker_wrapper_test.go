@@ -0,0 +1,160 @@
+/*
+Copyright 2026 The Knative Authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package service_test
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+	clocktesting "k8s.io/utils/clock/testing"
+
+	control "knative.dev/control-protocol/pkg"
+	"knative.dev/control-protocol/pkg/service"
+	"knative.dev/control-protocol/pkg/test"
+)
+
+type stateChangesRecorder struct {
+	mutex   sync.Mutex
+	changes []service.CircuitState
+}
+
+func (r *stateChangesRecorder) callback(name string, svc control.Service, from service.CircuitState, to service.CircuitState) {
+	r.mutex.Lock()
+	r.changes = append(r.changes, to)
+	r.mutex.Unlock()
+}
+
+func (r *stateChangesRecorder) get() []service.CircuitState {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+	return append([]service.CircuitState(nil), r.changes...)
+}
+
+func TestCircuitBreakerService_OpensAfterConsecutiveFailures(t *testing.T) {
+	failure := errors.New("failure")
+	mock := &failingUUIDSenderMock{errs: []error{failure, nil, failure, failure, failure}}
+	recorder := &stateChangesRecorder{}
+	svc := service.WithCircuitBreakerService(context.TODO(),
+		service.WithFailureThreshold(3),
+		service.WithCoolDown(time.Hour),
+		service.WithStateChangeCallback(recorder.callback),
+	)(mock)
+
+	// The success resets the consecutive failures
+	require.ErrorIs(t, svc.SendAndWaitForAck(1, test.SomeMockPayload), failure)
+	require.NoError(t, svc.SendAndWaitForAck(1, test.SomeMockPayload))
+	require.ErrorIs(t, svc.SendAndWaitForAck(1, test.SomeMockPayload), failure)
+	require.ErrorIs(t, svc.SendAndWaitForAck(1, test.SomeMockPayload), failure)
+
+	state, ok := service.GetCircuitState(svc)
+	require.True(t, ok)
+	require.Equal(t, service.CircuitClosed, state)
+
+	require.ErrorIs(t, svc.SendAndWaitForAck(1, test.SomeMockPayload), failure)
+	state, _ = service.GetCircuitState(svc)
+	require.Equal(t, service.CircuitOpen, state)
+	require.Equal(t, []service.CircuitState{service.CircuitOpen}, recorder.get())
+
+	// Fails fast without sending
+	require.ErrorIs(t, svc.SendAndWaitForAck(1, test.SomeMockPayload), service.ErrCircuitOpen)
+	require.Len(t, mock.uuids, 5)
+}
+
+func TestCircuitBreakerService_ProbeClosesCircuit(t *testing.T) {
+	failure := errors.New("failure")
+	mock := &failingUUIDSenderMock{errs: []error{failure}}
+	recorder := &stateChangesRecorder{}
+	fakeClock := clocktesting.NewFakePassiveClock(time.Now())
+	svc := service.WithCircuitBreakerService(context.TODO(),
+		service.WithFailureThreshold(1),
+		service.WithCoolDown(time.Minute),
+		service.WithCircuitBreakerClock(fakeClock),
+		service.WithStateChangeCallback(recorder.callback),
+	)(mock)
+
+	require.ErrorIs(t, svc.SendAndWaitForAck(1, test.SomeMockPayload), failure)
+	require.ErrorIs(t, svc.SendAndWaitForAck(1, test.SomeMockPayload), service.ErrCircuitOpen)
+
+	fakeClock.SetTime(fakeClock.Now().Add(time.Minute))
+
+	require.NoError(t, svc.SendAndWaitForAck(1, test.SomeMockPayload))
+	state, _ := service.GetCircuitState(svc)
+	require.Equal(t, service.CircuitClosed, state)
+	require.Equal(t, []service.CircuitState{
+		service.CircuitOpen,
+		service.CircuitHalfOpen,
+		service.CircuitClosed,
+	}, recorder.get())
+}
+
+func TestCircuitBreakerService_ProbeFailureReopensCircuit(t *testing.T) {
+	failure := errors.New("failure")
+	mock := &failingUUIDSenderMock{errs: []error{failure, failure}}
+	recorder := &stateChangesRecorder{}
+	fakeClock := clocktesting.NewFakePassiveClock(time.Now())
+	svc := service.WithCircuitBreakerService(context.TODO(),
+		service.WithFailureThreshold(1),
+		service.WithCoolDown(time.Minute),
+		service.WithCircuitBreakerClock(fakeClock),
+		service.WithStateChangeCallback(recorder.callback),
+	)(mock)
+
+	require.ErrorIs(t, svc.SendAndWaitForAck(1, test.SomeMockPayload), failure)
+
+	fakeClock.SetTime(fakeClock.Now().Add(time.Minute))
+
+	require.ErrorIs(t, svc.SendAndWaitForAck(1, test.SomeMockPayload), failure)
+	require.ErrorIs(t, svc.SendAndWaitForAck(1, test.SomeMockPayload), service.ErrCircuitOpen)
+	require.Equal(t, []service.CircuitState{
+		service.CircuitOpen,
+		service.CircuitHalfOpen,
+		service.CircuitOpen,
+	}, recorder.get())
+}
+
+func TestCircuitBreakerService_IgnoresNonFailures(t *testing.T) {
+	mock := &failingUUIDSenderMock{errs: []error{context.Canceled, context.Canceled}}
+	svc := service.WithCircuitBreakerService(context.TODO(), service.WithFailureThreshold(1))(mock)
+
+	require.ErrorIs(t, svc.SendAndWaitForAck(1, test.SomeMockPayload), context.Canceled)
+	require.ErrorIs(t, svc.SendAndWaitForAck(1, test.SomeMockPayload), context.Canceled)
+
+	state, _ := service.GetCircuitState(svc)
+	require.Equal(t, service.CircuitClosed, state)
+}
+
+func TestGetCircuitState_NotWrapped(t *testing.T) {
+	_, ok := service.GetCircuitState(sentMessagesSvcMock{})
+	require.False(t, ok)
+}
+
+func TestGetCircuitState_ThroughOtherWrappers(t *testing.T) {
+	failure := errors.New("failure")
+	mock := &failingUUIDSenderMock{errs: []error{failure}}
+	svc := service.WithCircuitBreakerService(context.TODO(), service.WithFailureThreshold(1))(mock)
+	svc = service.WithTracingService(context.TODO())(svc)
+
+	require.ErrorIs(t, svc.SendAndWaitForAck(1, test.SomeMockPayload), failure)
+
+	state, ok := service.GetCircuitState(svc)
+	require.True(t, ok)
+	require.Equal(t, service.CircuitOpen, state)
+}
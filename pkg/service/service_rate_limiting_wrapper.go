@@ -0,0 +1,230 @@
+/*
+Copyright 2026 The Knative Authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package service
+
+import (
+	"context"
+	"encoding"
+	"fmt"
+
+	"github.com/google/uuid"
+	"golang.org/x/time/rate"
+	"k8s.io/utils/clock"
+
+	control "knative.dev/control-protocol/pkg"
+)
+
+// RateLimitedError is returned by a rate limited service configured with RateLimitFailFast
+// when sending a message would exceed the limits.
+type RateLimitedError struct {
+	// OpCode is the opcode of the rejected message
+	OpCode control.OpCode
+	// PerOpCode is true when the per opcode limit was hit, false when the overall limit was hit
+	PerOpCode bool
+}
+
+func (e *RateLimitedError) Error() string {
+	if e.PerOpCode {
+		return fmt.Sprintf("rate limit exceeded for opcode %d", e.OpCode)
+	}
+	return fmt.Sprintf("overall rate limit exceeded while sending opcode %d", e.OpCode)
+}
+
+type rateLimit struct {
+	limit rate.Limit
+	burst int
+}
+
+type rateLimitingOptions struct {
+	overall  *rateLimit
+	opcodes  map[control.OpCode]rateLimit
+	failFast bool
+	clock    clock.Clock
+}
+
+type RateLimitingOption func(*rateLimitingOptions)
+
+// WithOverallRateLimit limits the rate of all the messages sent through all the services wrapped by the same wrapper,
+// e.g. all the connections of a pool configured with reconciler.WithServiceWrapper
+func WithOverallRateLimit(limit rate.Limit, burst int) RateLimitingOption {
+	return func(options *rateLimitingOptions) {
+		options.overall = &rateLimit{limit: limit, burst: burst}
+	}
+}
+
+// WithOpCodeRateLimit limits the rate of the messages with the provided opcode sent through every wrapped service
+func WithOpCodeRateLimit(opcode control.OpCode, limit rate.Limit, burst int) RateLimitingOption {
+	return func(options *rateLimitingOptions) {
+		options.opcodes[opcode] = rateLimit{limit: limit, burst: burst}
+	}
+}
+
+// RateLimitFailFast makes the service return a RateLimitedError when the limits are hit,
+// rather than waiting for the limits to allow the message.
+func RateLimitFailFast() RateLimitingOption {
+	return func(options *rateLimitingOptions) {
+		options.failFast = true
+	}
+}
+
+// WithRateLimitingClock sets the clock used to take the tokens of the limits and to wait for them
+func WithRateLimitingClock(clock clock.Clock) RateLimitingOption {
+	return func(options *rateLimitingOptions) {
+		options.clock = clock
+	}
+}
+
+type rateLimitingService struct {
+	control.Service
+
+	ctx context.Context
+
+	overall  *rate.Limiter
+	opcodes  map[control.OpCode]*rate.Limiter
+	failFast bool
+	clock    clock.Clock
+}
+
+var _ control.Service = (*rateLimitingService)(nil)
+var _ control.UUIDSender = (*rateLimitingService)(nil)
+var _ control.ContextSender = (*rateLimitingService)(nil)
+var _ control.ServiceUnwrapper = (*rateLimitingService)(nil)
+
+// WithRateLimitingService applies token bucket limits to the messages sent through the service, per opcode and overall.
+// By default, when a limit is hit SendAndWaitForAck waits until the message is allowed, or the provided context is closed.
+// The overall limit is shared by all the wrapped services, while every wrapped service has its own per opcode limits.
+func WithRateLimitingService(ctx context.Context, opts ...RateLimitingOption) control.ServiceWrapper {
+	options := rateLimitingOptions{
+		opcodes: make(map[control.OpCode]rateLimit),
+		clock:   clock.RealClock{},
+	}
+	for _, fn := range opts {
+		fn(&options)
+	}
+
+	var overall *rate.Limiter
+	if options.overall != nil {
+		overall = rate.NewLimiter(options.overall.limit, options.overall.burst)
+	}
+
+	return func(service control.Service) control.Service {
+		svc := &rateLimitingService{
+			Service:  service,
+			ctx:      ctx,
+			overall:  overall,
+			opcodes:  make(map[control.OpCode]*rate.Limiter, len(options.opcodes)),
+			failFast: options.failFast,
+			clock:    options.clock,
+		}
+		for opcode, l := range options.opcodes {
+			svc.opcodes[opcode] = rate.NewLimiter(l.limit, l.burst)
+		}
+		return svc
+	}
+}
+
+func (r *rateLimitingService) Unwrap() control.Service {
+	return r.Service
+}
+
+func (r *rateLimitingService) SendAndWaitForAck(opcode control.OpCode, payload encoding.BinaryMarshaler) error {
+	if err := r.waitLimits(r.ctx, opcode); err != nil {
+		return err
+	}
+	return r.Service.SendAndWaitForAck(opcode, payload)
+}
+
+func (r *rateLimitingService) SendWithUUIDAndWaitForAck(id uuid.UUID, opcode control.OpCode, payload encoding.BinaryMarshaler) error {
+	if err := r.waitLimits(r.ctx, opcode); err != nil {
+		return err
+	}
+	return sendWithUUID(r.Service, id, opcode, payload)
+}
+
+func (r *rateLimitingService) SendAndWaitForAckWithContext(ctx context.Context, opcode control.OpCode, payload encoding.BinaryMarshaler) error {
+	if err := r.waitLimits(ctx, opcode); err != nil {
+		return err
+	}
+	return sendWithContext(ctx, r.Service, opcode, payload)
+}
+
+func (r *rateLimitingService) waitLimits(ctx context.Context, opcode control.OpCode) error {
+	opcodeLimiter := r.opcodes[opcode]
+
+	if r.failFast {
+		now := r.clock.Now()
+		var opcodeReservation *rate.Reservation
+		if opcodeLimiter != nil {
+			opcodeReservation = opcodeLimiter.ReserveN(now, 1)
+			if !opcodeReservation.OK() || opcodeReservation.DelayFrom(now) > 0 {
+				opcodeReservation.CancelAt(now)
+				return &RateLimitedError{OpCode: opcode, PerOpCode: true}
+			}
+		}
+		if r.overall != nil {
+			overallReservation := r.overall.ReserveN(now, 1)
+			if !overallReservation.OK() || overallReservation.DelayFrom(now) > 0 {
+				overallReservation.CancelAt(now)
+				if opcodeReservation != nil {
+					// Give back the token of the opcode limiter, since the message is not sent
+					opcodeReservation.CancelAt(now)
+				}
+				return &RateLimitedError{OpCode: opcode}
+			}
+		}
+		return nil
+	}
+
+	if opcodeLimiter != nil {
+		if err := r.wait(ctx, opcodeLimiter); err != nil {
+			return err
+		}
+	}
+	if r.overall != nil {
+		if err := r.wait(ctx, r.overall); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// wait is like rate.Limiter.Wait, but it uses r.clock
+func (r *rateLimitingService) wait(ctx context.Context, limiter *rate.Limiter) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	now := r.clock.Now()
+	reservation := limiter.ReserveN(now, 1)
+	if !reservation.OK() {
+		return fmt.Errorf("rate: Wait(n=1) exceeds limiter's burst %d", limiter.Burst())
+	}
+	delay := reservation.DelayFrom(now)
+	if delay == 0 {
+		return nil
+	}
+
+	timer := r.clock.NewTimer(delay)
+	defer timer.Stop()
+	select {
+	case <-timer.C():
+		return nil
+	case <-ctx.Done():
+		// Give back the token, since the message is not sent
+		reservation.CancelAt(r.clock.Now())
+		return ctx.Err()
+	}
+}
@@ -0,0 +1,157 @@
+/*
+Copyright 2026 The Knative Authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package service_test
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+	"golang.org/x/time/rate"
+	clocktesting "k8s.io/utils/clock/testing"
+
+	control "knative.dev/control-protocol/pkg"
+	"knative.dev/control-protocol/pkg/service"
+	"knative.dev/control-protocol/pkg/test"
+)
+
+func TestRateLimitingService_FailFastPerOpCode(t *testing.T) {
+	sent := sentMessagesSvcMock{}
+	svc := service.WithRateLimitingService(context.TODO(),
+		service.WithOpCodeRateLimit(1, rate.Every(time.Hour), 1),
+		service.RateLimitFailFast(),
+	)(sent)
+
+	require.NoError(t, svc.SendAndWaitForAck(1, test.SomeMockPayload))
+
+	err := svc.SendAndWaitForAck(1, test.SomeMockPayload)
+	var rateLimitedErr *service.RateLimitedError
+	require.True(t, errors.As(err, &rateLimitedErr))
+	require.Equal(t, control.OpCode(1), rateLimitedErr.OpCode)
+	require.True(t, rateLimitedErr.PerOpCode)
+
+	// Other opcodes are not limited
+	require.NoError(t, svc.SendAndWaitForAck(2, test.SomeMockPayload))
+	require.NoError(t, svc.SendAndWaitForAck(2, test.SomeMockPayload))
+}
+
+func TestRateLimitingService_FailFastOverall(t *testing.T) {
+	sent := sentMessagesSvcMock{}
+	svc := service.WithRateLimitingService(context.TODO(),
+		service.WithOverallRateLimit(rate.Every(time.Hour), 2),
+		service.WithOpCodeRateLimit(3, rate.Every(time.Hour), 1),
+		service.RateLimitFailFast(),
+	)(sent)
+
+	require.NoError(t, svc.SendAndWaitForAck(1, test.SomeMockPayload))
+	require.NoError(t, svc.SendAndWaitForAck(2, test.SomeMockPayload))
+
+	err := svc.SendAndWaitForAck(3, test.SomeMockPayload)
+	var rateLimitedErr *service.RateLimitedError
+	require.True(t, errors.As(err, &rateLimitedErr))
+	require.Equal(t, control.OpCode(3), rateLimitedErr.OpCode)
+	require.False(t, rateLimitedErr.PerOpCode)
+	require.NotContains(t, sent, control.OpCode(3))
+}
+
+func TestRateLimitingService_FailFastOnClock(t *testing.T) {
+	fakeClock := clocktesting.NewFakeClock(time.Now())
+	svc := service.WithRateLimitingService(context.TODO(),
+		service.WithOpCodeRateLimit(1, rate.Every(time.Minute), 1),
+		service.WithOverallRateLimit(rate.Every(time.Minute), 1),
+		service.WithRateLimitingClock(fakeClock),
+		service.RateLimitFailFast(),
+	)(sentMessagesSvcMock{})
+
+	require.NoError(t, svc.SendAndWaitForAck(1, test.SomeMockPayload))
+	var rateLimitedErr *service.RateLimitedError
+	require.ErrorAs(t, svc.SendAndWaitForAck(1, test.SomeMockPayload), &rateLimitedErr)
+
+	// The tokens are refilled when the clock advances
+	fakeClock.Step(time.Minute)
+	require.NoError(t, svc.SendAndWaitForAck(1, test.SomeMockPayload))
+}
+
+func TestRateLimitingService_OverallSharedByWrappedServices(t *testing.T) {
+	wrapper := service.WithRateLimitingService(context.TODO(),
+		service.WithOverallRateLimit(rate.Every(time.Hour), 2),
+		service.WithOpCodeRateLimit(1, rate.Every(time.Hour), 1),
+		service.RateLimitFailFast(),
+	)
+	svcA := wrapper(sentMessagesSvcMock{})
+	svcB := wrapper(sentMessagesSvcMock{})
+
+	// The per opcode limits are per service
+	require.NoError(t, svcA.SendAndWaitForAck(1, test.SomeMockPayload))
+	require.NoError(t, svcB.SendAndWaitForAck(1, test.SomeMockPayload))
+
+	err := svcA.SendAndWaitForAck(2, test.SomeMockPayload)
+	var rateLimitedErr *service.RateLimitedError
+	require.True(t, errors.As(err, &rateLimitedErr))
+	require.False(t, rateLimitedErr.PerOpCode)
+}
+
+func TestRateLimitingService_Wait(t *testing.T) {
+	sent := sentMessagesSvcMock{}
+	svc := service.WithRateLimitingService(context.TODO(),
+		service.WithOpCodeRateLimit(1, rate.Every(50*time.Millisecond), 1),
+	)(sent)
+
+	start := time.Now()
+	require.NoError(t, svc.SendAndWaitForAck(1, test.SomeMockPayload))
+	require.NoError(t, svc.SendAndWaitForAck(1, test.SomeMockPayload))
+	require.GreaterOrEqual(t, time.Since(start), 40*time.Millisecond)
+}
+
+func TestRateLimitingService_WaitContextClosed(t *testing.T) {
+	ctx, cancelFn := context.WithCancel(context.TODO())
+
+	sent := sentMessagesSvcMock{}
+	svc := service.WithRateLimitingService(ctx,
+		service.WithOverallRateLimit(rate.Every(time.Hour), 1),
+	)(sent)
+
+	require.NoError(t, svc.SendAndWaitForAck(1, test.SomeMockPayload))
+
+	cancelFn()
+	require.Error(t, svc.SendAndWaitForAck(2, test.SomeMockPayload))
+	require.NotContains(t, sent, control.OpCode(2))
+}
+
+func TestRateLimitingService_WaitOnClock(t *testing.T) {
+	fakeClock := clocktesting.NewFakeClock(time.Now())
+	sent := sentMessagesSvcMock{}
+	svc := service.WithRateLimitingService(context.TODO(),
+		service.WithOpCodeRateLimit(1, rate.Every(time.Minute), 1),
+		service.WithRateLimitingClock(fakeClock),
+	)(sent)
+
+	require.NoError(t, svc.SendAndWaitForAck(1, test.SomeMockPayload))
+
+	errCh := make(chan error, 1)
+	go func() {
+		errCh <- svc.SendAndWaitForAck(1, test.SomeMockPayload)
+	}()
+
+	// The second message waits for the clock to refill the token
+	require.Eventually(t, fakeClock.HasWaiters, time.Second, time.Millisecond)
+	require.Len(t, errCh, 0)
+	fakeClock.Step(time.Minute)
+	require.NoError(t, <-errCh)
+}
@@ -0,0 +1,210 @@
+/*
+Copyright 2026 The Knative Authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package service
+
+import (
+	"context"
+	"encoding"
+	"math/rand"
+	"time"
+
+	"github.com/google/uuid"
+	"k8s.io/utils/clock"
+	"knative.dev/pkg/logging"
+
+	control "knative.dev/control-protocol/pkg"
+)
+
+const (
+	defaultRetryMaxAttempts    = 5
+	defaultRetryMaxElapsedTime = 2 * time.Minute
+	defaultRetryInitialBackoff = 100 * time.Millisecond
+	defaultRetryMaxBackoff     = 10 * time.Second
+	defaultRetryMultiplier     = 2
+	defaultRetryJitter         = 0.5
+)
+
+type retryOptions struct {
+	maxAttempts    int
+	maxElapsedTime time.Duration
+	initialBackoff time.Duration
+	maxBackoff     time.Duration
+	multiplier     float64
+	jitter         float64
+	retryable      func(error) bool
+	clock          clock.Clock
+}
+
+type RetryOption func(*retryOptions)
+
+// WithRetryMaxAttempts sets the maximum number of attempts, including the first one.
+// 0 means no limit on the number of attempts.
+func WithRetryMaxAttempts(maxAttempts int) RetryOption {
+	return func(options *retryOptions) {
+		options.maxAttempts = maxAttempts
+	}
+}
+
+// WithRetryMaxElapsedTime sets the maximum time spent sending a message, after which no more attempts are performed.
+// 0 means no limit on the elapsed time.
+func WithRetryMaxElapsedTime(maxElapsedTime time.Duration) RetryOption {
+	return func(options *retryOptions) {
+		options.maxElapsedTime = maxElapsedTime
+	}
+}
+
+// WithRetryBackoff configures the exponential backoff between attempts:
+// the first retry waits initial, then every retry waits multiplier times the previous one, up to max.
+func WithRetryBackoff(initial time.Duration, max time.Duration, multiplier float64) RetryOption {
+	return func(options *retryOptions) {
+		options.initialBackoff = initial
+		options.maxBackoff = max
+		options.multiplier = multiplier
+	}
+}
+
+// WithRetryJitter sets the jitter factor in [0, 1] applied to the backoff,
+// so every backoff is randomly picked between (1 - jitter) * backoff and backoff.
+func WithRetryJitter(jitter float64) RetryOption {
+	return func(options *retryOptions) {
+		options.jitter = jitter
+	}
+}
+
+// WithRetryableErrors sets the function deciding whether a failed attempt should be retried.
+// By default, only the ack timeouts are retried.
+func WithRetryableErrors(retryable func(error) bool) RetryOption {
+	return func(options *retryOptions) {
+		options.retryable = retryable
+	}
+}
+
+// WithRetryClock sets the clock used to measure the elapsed time and to wait the backoff
+func WithRetryClock(clock clock.Clock) RetryOption {
+	return func(options *retryOptions) {
+		options.clock = clock
+	}
+}
+
+type retryService struct {
+	control.Service
+
+	ctx     context.Context
+	options retryOptions
+}
+
+var _ control.Service = (*retryService)(nil)
+var _ control.UUIDSender = (*retryService)(nil)
+var _ control.ContextSender = (*retryService)(nil)
+var _ control.ServiceUnwrapper = (*retryService)(nil)
+
+// WithRetryService retries sending the messages failed with a retryable error, using an exponential backoff with jitter.
+// All the attempts use the same message uuid, so the other end can deduplicate the message,
+// as long as the wrapped service implements control.UUIDSender.
+func WithRetryService(ctx context.Context, opts ...RetryOption) control.ServiceWrapper {
+	options := retryOptions{
+		maxAttempts:    defaultRetryMaxAttempts,
+		maxElapsedTime: defaultRetryMaxElapsedTime,
+		initialBackoff: defaultRetryInitialBackoff,
+		maxBackoff:     defaultRetryMaxBackoff,
+		multiplier:     defaultRetryMultiplier,
+		jitter:         defaultRetryJitter,
+		retryable:      IsAckTimeout,
+		clock:          clock.RealClock{},
+	}
+	for _, fn := range opts {
+		fn(&options)
+	}
+
+	return func(service control.Service) control.Service {
+		return &retryService{
+			Service: service,
+			ctx:     ctx,
+			options: options,
+		}
+	}
+}
+
+func (r *retryService) Unwrap() control.Service {
+	return r.Service
+}
+
+func (r *retryService) SendAndWaitForAck(opcode control.OpCode, payload encoding.BinaryMarshaler) error {
+	return r.SendWithUUIDAndWaitForAck(uuid.New(), opcode, payload)
+}
+
+func (r *retryService) SendWithUUIDAndWaitForAck(id uuid.UUID, opcode control.OpCode, payload encoding.BinaryMarshaler) error {
+	return r.retry(r.ctx, id, func() error {
+		return sendWithUUID(r.Service, id, opcode, payload)
+	})
+}
+
+func (r *retryService) SendAndWaitForAckWithContext(ctx context.Context, opcode control.OpCode, payload encoding.BinaryMarshaler) error {
+	id, ok := control.MessageUUIDFromContext(ctx)
+	if !ok {
+		id = uuid.New()
+		ctx = control.ContextWithMessageUUID(ctx, id)
+	}
+	return r.retry(ctx, id, func() error {
+		return sendWithContext(ctx, r.Service, opcode, payload)
+	})
+}
+
+func (r *retryService) retry(ctx context.Context, id uuid.UUID, sendFn func() error) error {
+	start := r.options.clock.Now()
+	backoff := r.options.initialBackoff
+	for attempt := 1; ; attempt++ {
+		err := sendFn()
+		if err == nil || !r.options.retryable(err) {
+			return err
+		}
+		if r.options.maxAttempts > 0 && attempt >= r.options.maxAttempts {
+			logging.FromContext(r.ctx).Debugf("Giving up sending message %s after %d attempts: %v", id.String(), attempt, err)
+			return err
+		}
+
+		wait := r.jittered(backoff)
+		if r.options.maxElapsedTime > 0 && r.options.clock.Since(start)+wait > r.options.maxElapsedTime {
+			logging.FromContext(r.ctx).Debugf("Giving up sending message %s after %v: %v", id.String(), r.options.clock.Since(start), err)
+			return err
+		}
+
+		logging.FromContext(r.ctx).Debugf("Retrying sending message %s in %v, attempt %d failed: %v", id.String(), wait, attempt, err)
+		timer := r.options.clock.NewTimer(wait)
+		select {
+		case <-timer.C():
+		case <-r.ctx.Done():
+			timer.Stop()
+			return r.ctx.Err()
+		case <-ctx.Done():
+			timer.Stop()
+			return ctx.Err()
+		}
+
+		backoff = time.Duration(float64(backoff) * r.options.multiplier)
+		if backoff > r.options.maxBackoff {
+			backoff = r.options.maxBackoff
+		}
+	}
+}
+
+func (r *retryService) jittered(backoff time.Duration) time.Duration {
+	if r.options.jitter <= 0 {
+		return backoff
+	}
+	return backoff - time.Duration(rand.Float64()*r.options.jitter*float64(backoff))
+}
@@ -0,0 +1,173 @@
+/*
+Copyright 2026 The Knative Authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package service_test
+
+import (
+	"context"
+	"encoding"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/require"
+	clocktesting "k8s.io/utils/clock/testing"
+
+	control "knative.dev/control-protocol/pkg"
+	"knative.dev/control-protocol/pkg/service"
+	"knative.dev/control-protocol/pkg/test"
+)
+
+type failingUUIDSenderMock struct {
+	errs  []error
+	uuids []uuid.UUID
+}
+
+func (f *failingUUIDSenderMock) SendAndWaitForAck(opcode control.OpCode, payload encoding.BinaryMarshaler) error {
+	return f.SendWithUUIDAndWaitForAck(uuid.New(), opcode, payload)
+}
+
+func (f *failingUUIDSenderMock) SendWithUUIDAndWaitForAck(id uuid.UUID, opcode control.OpCode, payload encoding.BinaryMarshaler) error {
+	f.uuids = append(f.uuids, id)
+	if len(f.errs) == 0 {
+		return nil
+	}
+	err := f.errs[0]
+	f.errs = f.errs[1:]
+	return err
+}
+
+func (f *failingUUIDSenderMock) MessageHandler(control.MessageHandler) {
+	panic("this shouldn't be invoked")
+}
+
+func (f *failingUUIDSenderMock) ErrorHandler(control.ErrorHandler) {
+	panic("this shouldn't be invoked")
+}
+
+func TestRetryService_RetriesTimeoutsWithSameUUID(t *testing.T) {
+	mock := &failingUUIDSenderMock{errs: []error{
+		&service.AckTimeoutError{},
+		&service.AckTimeoutError{},
+	}}
+	svc := service.WithRetryService(context.TODO(), service.WithRetryBackoff(time.Millisecond, 10*time.Millisecond, 2))(mock)
+
+	require.NoError(t, svc.SendAndWaitForAck(1, test.SomeMockPayload))
+	require.Len(t, mock.uuids, 3)
+	require.Equal(t, mock.uuids[0], mock.uuids[1])
+	require.Equal(t, mock.uuids[0], mock.uuids[2])
+}
+
+func TestRetryService_DoesntRetryNonRetryableErrors(t *testing.T) {
+	mock := &failingUUIDSenderMock{errs: []error{errors.New("bad payload")}}
+	svc := service.WithRetryService(context.TODO(), service.WithRetryBackoff(time.Millisecond, 10*time.Millisecond, 2))(mock)
+
+	require.EqualError(t, svc.SendAndWaitForAck(1, test.SomeMockPayload), "bad payload")
+	require.Len(t, mock.uuids, 1)
+}
+
+func TestRetryService_MaxAttempts(t *testing.T) {
+	transientErr := errors.New("transient")
+	mock := &failingUUIDSenderMock{errs: []error{transientErr, transientErr, transientErr, transientErr}}
+	svc := service.WithRetryService(context.TODO(),
+		service.WithRetryBackoff(time.Millisecond, 10*time.Millisecond, 2),
+		service.WithRetryMaxAttempts(3),
+		service.WithRetryableErrors(func(err error) bool {
+			return errors.Is(err, transientErr)
+		}),
+	)(mock)
+
+	require.ErrorIs(t, svc.SendAndWaitForAck(1, test.SomeMockPayload), transientErr)
+	require.Len(t, mock.uuids, 3)
+}
+
+func TestRetryService_MaxElapsedTime(t *testing.T) {
+	mock := &failingUUIDSenderMock{errs: []error{&service.AckTimeoutError{}, &service.AckTimeoutError{}}}
+	svc := service.WithRetryService(context.TODO(),
+		service.WithRetryBackoff(time.Hour, time.Hour, 2),
+		service.WithRetryMaxElapsedTime(time.Minute),
+	)(mock)
+
+	require.True(t, service.IsAckTimeout(svc.SendAndWaitForAck(1, test.SomeMockPayload)))
+	require.Len(t, mock.uuids, 1)
+}
+
+func TestRetryService_WaitsBackoffOnClock(t *testing.T) {
+	fakeClock := clocktesting.NewFakeClock(time.Now())
+	mock := &failingUUIDSenderMock{errs: []error{&service.AckTimeoutError{}}}
+	svc := service.WithRetryService(context.TODO(),
+		service.WithRetryBackoff(time.Hour, time.Hour, 2),
+		service.WithRetryJitter(0),
+		service.WithRetryMaxElapsedTime(0),
+		service.WithRetryClock(fakeClock),
+	)(mock)
+
+	errCh := make(chan error)
+	go func() {
+		errCh <- svc.SendAndWaitForAck(1, test.SomeMockPayload)
+	}()
+
+	require.Eventually(t, fakeClock.HasWaiters, time.Second, time.Millisecond)
+	fakeClock.Step(time.Hour)
+	require.NoError(t, <-errCh)
+	require.Len(t, mock.uuids, 2)
+}
+
+func TestRetryService_ContextCancelled(t *testing.T) {
+	ctx, cancelFn := context.WithCancel(context.TODO())
+	mock := &failingUUIDSenderMock{errs: []error{&service.AckTimeoutError{}}}
+	svc := service.WithRetryService(ctx,
+		service.WithRetryBackoff(time.Hour, time.Hour, 2),
+		service.WithRetryMaxElapsedTime(0),
+	)(mock)
+
+	cancelFn()
+	require.ErrorIs(t, svc.SendAndWaitForAck(1, test.SomeMockPayload), context.Canceled)
+	require.Len(t, mock.uuids, 1)
+}
+
+func TestRetryService_ResendsSameUUIDOnTheWire(t *testing.T) {
+	mockConnection := test.NewConnectionMock()
+
+	ctx, cancelFn := context.WithCancel(context.TODO())
+	t.Cleanup(cancelFn)
+
+	svc := service.WithRetryService(ctx,
+		service.WithRetryBackoff(time.Millisecond, 10*time.Millisecond, 2),
+		service.WithRetryableErrors(func(err error) bool { return true }),
+	)(service.WithCachingService(ctx)(service.NewService(ctx, mockConnection)))
+
+	errCh := make(chan error, 1)
+	go func() {
+		errCh <- svc.SendAndWaitForAck(10, test.SomeMockPayload)
+	}()
+
+	outboundMessages := mockConnection.WaitAtLeastOneOutboundMessage()
+	nack := control.NewMessage(outboundMessages[0].UUID(), uint8(control.AckOpCode), []byte("transient"))
+	mockConnection.PushInboundMessage(&nack)
+
+	require.Eventually(t, func() bool {
+		return len(mockConnection.WaitAtLeastOneOutboundMessage()) == 2
+	}, 5*time.Second, 10*time.Millisecond)
+	outboundMessages = mockConnection.WaitAtLeastOneOutboundMessage()
+	require.Equal(t, outboundMessages[0].UUID(), outboundMessages[1].UUID())
+
+	ack := control.NewMessage(outboundMessages[1].UUID(), uint8(control.AckOpCode), nil)
+	mockConnection.PushInboundMessage(&ack)
+
+	require.NoError(t, <-errCh)
+}
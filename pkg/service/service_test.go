@@ -21,12 +21,15 @@ import (
 	"errors"
 	"sync"
 	"testing"
+	"time"
 
 	"github.com/google/uuid"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
+	clocktesting "k8s.io/utils/clock/testing"
 
 	ctrl "knative.dev/control-protocol/pkg"
+	"knative.dev/control-protocol/pkg/internal/reserved"
 	"knative.dev/control-protocol/pkg/service"
 	"knative.dev/control-protocol/pkg/test"
 )
@@ -60,6 +63,30 @@ func TestService_SendAndWaitForAck(t *testing.T) {
 	wg.Wait()
 }
 
+func TestService_SendAndWaitForAck_Timeout(t *testing.T) {
+	mockConnection := test.NewConnectionMock()
+	fakeClock := clocktesting.NewFakeClock(time.Now())
+
+	ctx, cancelFn := context.WithCancel(context.TODO())
+	t.Cleanup(cancelFn)
+
+	svc := service.NewService(ctx, mockConnection, service.WithClock(fakeClock))
+
+	errCh := make(chan error)
+	go func() {
+		errCh <- svc.SendAndWaitForAck(10, test.SomeMockPayload)
+	}()
+
+	outboundMessages := mockConnection.WaitAtLeastOneOutboundMessage()
+	require.Len(t, outboundMessages, 1)
+	require.Eventually(t, fakeClock.HasWaiters, time.Second, time.Millisecond)
+
+	fakeClock.Step(time.Minute)
+	err := <-errCh
+	require.True(t, service.IsAckTimeout(err))
+	require.Equal(t, &service.AckTimeoutError{UUID: outboundMessages[0].UUID()}, err)
+}
+
 func TestService_SendEmptyPayload(t *testing.T) {
 	mockConnection := test.NewConnectionMock()
 
@@ -91,6 +118,35 @@ func TestService_SendEmptyPayload(t *testing.T) {
 	wg.Wait()
 }
 
+func TestService_SendReservedOpCode(t *testing.T) {
+	mockConnection := test.NewConnectionMock()
+
+	ctx, cancelFn := context.WithCancel(context.TODO())
+	t.Cleanup(cancelFn)
+
+	svc := service.NewService(ctx, mockConnection)
+
+	require.EqualError(t, svc.SendAndWaitForAck(ctrl.AckOpCode, nil), "you cannot send an ack manually")
+	require.EqualError(t, svc.SendAndWaitForAck(ctrl.CreditOpCode, nil), "you cannot send a credit grant manually")
+	require.EqualError(t, svc.SendAndWaitForAck(ctrl.RegistrationOpCode, test.SomeMockPayload), "the opcode 253 is reserved to the control protocol")
+	require.EqualError(t, svc.SendAndWaitForAckWithContext(reserved.WithReservedOpCode(ctx, ctrl.CreditOpCode), ctrl.RegistrationOpCode, test.SomeMockPayload), "the opcode 253 is reserved to the control protocol")
+
+	// The control protocol itself can send the registrations
+	errCh := make(chan error, 1)
+	go func() {
+		errCh <- svc.SendAndWaitForAckWithContext(reserved.WithReservedOpCode(ctx, ctrl.RegistrationOpCode), ctrl.RegistrationOpCode, test.SomeMockPayload)
+	}()
+
+	// The rejected messages were never written
+	outboundMessages := mockConnection.WaitAtLeastOneOutboundMessage()
+	require.Len(t, outboundMessages, 1)
+	require.Equal(t, uint8(ctrl.RegistrationOpCode), outboundMessages[0].OpCode())
+
+	inboundMessage := ctrl.NewMessage(outboundMessages[0].UUID(), uint8(ctrl.AckOpCode), nil)
+	mockConnection.PushInboundMessage(&inboundMessage)
+	require.NoError(t, <-errCh)
+}
+
 func TestService_SendAndWaitForAckWithError(t *testing.T) {
 	mockConnection := test.NewConnectionMock()
 
@@ -204,3 +260,145 @@ func TestService_ErrorHandler(t *testing.T) {
 
 	wg.Wait()
 }
+
+func TestService_BatchedAcks(t *testing.T) {
+	mockConnection := test.NewConnectionMock()
+
+	ctx, cancelFn := context.WithCancel(context.TODO())
+	t.Cleanup(cancelFn)
+
+	svc := service.NewService(ctx, mockConnection, service.WithBatchedAcks(time.Second, 3))
+
+	svc.MessageHandler(ctrl.MessageHandlerFunc(func(ctx context.Context, message ctrl.ServiceMessage) {
+		message.Ack()
+	}))
+
+	var msgUuids []uuid.UUID
+	for i := 0; i < 3; i++ {
+		msgUuid := uuid.New()
+		msgUuids = append(msgUuids, msgUuid)
+		inboundMessage := ctrl.NewMessage(msgUuid, uint8(10), []byte(test.SomeMockPayload))
+		mockConnection.PushInboundMessage(&inboundMessage)
+	}
+
+	outboundMessages := mockConnection.WaitAtLeastOneOutboundMessage()
+	require.Len(t, outboundMessages, 1)
+	require.Equal(t, uint8(ctrl.AckOpCode), outboundMessages[0].OpCode())
+	require.True(t, outboundMessages[0].Check(ctrl.BatchedAckFlag))
+	require.Len(t, outboundMessages[0].Payload(), 3*16)
+
+	var ackedUuids []uuid.UUID
+	for i := 0; i < 3; i++ {
+		var id uuid.UUID
+		copy(id[:], outboundMessages[0].Payload()[i*16:(i+1)*16])
+		ackedUuids = append(ackedUuids, id)
+	}
+	require.ElementsMatch(t, msgUuids, ackedUuids)
+}
+
+func TestService_BatchedAcks_FlushAfterDelay(t *testing.T) {
+	mockConnection := test.NewConnectionMock()
+
+	ctx, cancelFn := context.WithCancel(context.TODO())
+	t.Cleanup(cancelFn)
+
+	svc := service.NewService(ctx, mockConnection, service.WithBatchedAcks(50*time.Millisecond, 10))
+
+	svc.MessageHandler(ctrl.MessageHandlerFunc(func(ctx context.Context, message ctrl.ServiceMessage) {
+		message.Ack()
+	}))
+
+	msgUuid := uuid.New()
+	inboundMessage := ctrl.NewMessage(msgUuid, uint8(10), []byte(test.SomeMockPayload))
+	mockConnection.PushInboundMessage(&inboundMessage)
+
+	// A single ack is sent as a plain ack
+	outboundMessages := mockConnection.WaitAtLeastOneOutboundMessage()
+	require.Len(t, outboundMessages, 1)
+	require.Equal(t, uint8(ctrl.AckOpCode), outboundMessages[0].OpCode())
+	require.False(t, outboundMessages[0].Check(ctrl.BatchedAckFlag))
+	require.Equal(t, msgUuid, outboundMessages[0].UUID())
+}
+
+func TestService_BatchedAcks_StopOnContextDone(t *testing.T) {
+	mockConnection := test.NewConnectionMock()
+	fakeClock := clocktesting.NewFakeClock(time.Now())
+
+	ctx, cancelFn := context.WithCancel(context.TODO())
+	t.Cleanup(cancelFn)
+
+	svc := service.NewService(ctx, mockConnection, service.WithBatchedAcks(time.Second, 10), service.WithClock(fakeClock))
+
+	acked := make(chan struct{})
+	svc.MessageHandler(ctrl.MessageHandlerFunc(func(ctx context.Context, message ctrl.ServiceMessage) {
+		message.Ack()
+		close(acked)
+	}))
+
+	inboundMessage := ctrl.NewMessage(uuid.New(), uint8(10), []byte(test.SomeMockPayload))
+	mockConnection.PushInboundMessage(&inboundMessage)
+	<-acked
+	require.True(t, fakeClock.HasWaiters())
+
+	// The pending batch is discarded, rather than flushed into the closed connection
+	cancelFn()
+	require.Eventually(t, func() bool {
+		return !fakeClock.HasWaiters()
+	}, 5*time.Second, 10*time.Millisecond)
+}
+
+func TestService_BatchedAcks_AckWithErrorIsImmediate(t *testing.T) {
+	mockConnection := test.NewConnectionMock()
+
+	ctx, cancelFn := context.WithCancel(context.TODO())
+	t.Cleanup(cancelFn)
+
+	svc := service.NewService(ctx, mockConnection, service.WithBatchedAcks(time.Minute, 10))
+
+	svc.MessageHandler(ctrl.MessageHandlerFunc(func(ctx context.Context, message ctrl.ServiceMessage) {
+		message.AckWithError(errors.New("some wacky error"))
+	}))
+
+	msgUuid := uuid.New()
+	inboundMessage := ctrl.NewMessage(msgUuid, uint8(10), []byte(test.SomeMockPayload))
+	mockConnection.PushInboundMessage(&inboundMessage)
+
+	outboundMessages := mockConnection.WaitAtLeastOneOutboundMessage()
+	require.Len(t, outboundMessages, 1)
+	require.Equal(t, msgUuid, outboundMessages[0].UUID())
+	require.Equal(t, []byte("some wacky error"), outboundMessages[0].Payload())
+}
+
+func TestService_ReceiveBatchedAck(t *testing.T) {
+	mockConnection := test.NewConnectionMock()
+
+	ctx, cancelFn := context.WithCancel(context.TODO())
+	t.Cleanup(cancelFn)
+
+	svc := service.NewService(ctx, mockConnection)
+
+	var wg sync.WaitGroup
+	wg.Add(2)
+	for i := 0; i < 2; i++ {
+		go func() {
+			defer wg.Done()
+			assert.NoError(t, svc.SendAndWaitForAck(10, test.SomeMockPayload))
+		}()
+	}
+
+	var outboundMessages []*ctrl.Message
+	require.Eventually(t, func() bool {
+		outboundMessages = mockConnection.WaitAtLeastOneOutboundMessage()
+		return len(outboundMessages) == 2
+	}, time.Second, 10*time.Millisecond)
+
+	var payload []byte
+	for _, msg := range outboundMessages {
+		id := msg.UUID()
+		payload = append(payload, id[:]...)
+	}
+	inboundMessage := ctrl.NewMessage(uuid.Nil, uint8(ctrl.AckOpCode), payload, ctrl.WithFlags(uint8(ctrl.BatchedAckFlag)))
+	mockConnection.PushInboundMessage(&inboundMessage)
+
+	wg.Wait()
+}
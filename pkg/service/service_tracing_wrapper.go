@@ -0,0 +1,131 @@
+/*
+Copyright 2026 The Knative Authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package service
+
+import (
+	"context"
+	"encoding"
+	"errors"
+
+	"github.com/google/uuid"
+	"go.opencensus.io/trace"
+	"go.opencensus.io/trace/propagation"
+
+	control "knative.dev/control-protocol/pkg"
+)
+
+const (
+	sendSpanName   = "control.SendAndWaitForAck"
+	handleSpanName = "control.HandleServiceMessage"
+
+	opCodeAttribute = "control.opcode"
+	uuidAttribute   = "control.uuid"
+)
+
+type tracingService struct {
+	control.Service
+
+	ctx context.Context
+}
+
+var _ control.Service = (*tracingService)(nil)
+var _ control.UUIDSender = (*tracingService)(nil)
+var _ control.ContextSender = (*tracingService)(nil)
+var _ control.ServiceUnwrapper = (*tracingService)(nil)
+
+// WithTracingService starts a span for every sent message, child of the span in the context used to send it,
+// and ends it when the ack is received, with a status reflecting the ack outcome.
+// The span context is propagated to the other end in the message, so use NewTracingMessageHandler
+// on the other end to continue the trace in the message handler.
+// SendAndWaitForAck and SendWithUUIDAndWaitForAck use the context provided to the wrapper, use
+// control.ContextSender.SendAndWaitForAckWithContext to propagate the span of the caller.
+// Both ends of the connection must run a version of the control protocol supporting the trace context, look at control.WithTraceContext.
+func WithTracingService(ctx context.Context) control.ServiceWrapper {
+	return func(service control.Service) control.Service {
+		return &tracingService{
+			Service: service,
+			ctx:     ctx,
+		}
+	}
+}
+
+func (t *tracingService) Unwrap() control.Service {
+	return t.Service
+}
+
+func (t *tracingService) SendAndWaitForAck(opcode control.OpCode, payload encoding.BinaryMarshaler) error {
+	return t.SendAndWaitForAckWithContext(t.ctx, opcode, payload)
+}
+
+func (t *tracingService) SendWithUUIDAndWaitForAck(id uuid.UUID, opcode control.OpCode, payload encoding.BinaryMarshaler) error {
+	return t.SendAndWaitForAckWithContext(control.ContextWithMessageUUID(t.ctx, id), opcode, payload)
+}
+
+func (t *tracingService) SendAndWaitForAckWithContext(ctx context.Context, opcode control.OpCode, payload encoding.BinaryMarshaler) error {
+	ctx, span := trace.StartSpan(ctx, sendSpanName, trace.WithSpanKind(trace.SpanKindClient))
+	defer span.End()
+	span.AddAttributes(trace.Int64Attribute(opCodeAttribute, int64(opcode)))
+
+	ctx = control.ContextWithTraceContext(ctx, propagation.Binary(span.SpanContext()))
+	err := sendWithContext(ctx, t.Service, opcode, payload)
+	span.SetStatus(sendSpanStatus(err))
+	return err
+}
+
+// NewTracingMessageHandler creates a message handler that starts a span for every message, before invoking the provided handler,
+// and ends it when the message is acked.
+// If the message carries a trace context, e.g. because the other end uses WithTracingService, the span is a child of the remote span.
+// The context passed to the provided handler carries the span.
+func NewTracingMessageHandler(handler control.MessageHandler) control.MessageHandler {
+	return control.MessageHandlerFunc(func(ctx context.Context, message control.ServiceMessage) {
+		var span *trace.Span
+		if sc, ok := propagation.FromBinary(control.TraceContextFromContext(ctx)); ok {
+			ctx, span = trace.StartSpanWithRemoteParent(ctx, handleSpanName, sc, trace.WithSpanKind(trace.SpanKindServer))
+		} else {
+			ctx, span = trace.StartSpan(ctx, handleSpanName, trace.WithSpanKind(trace.SpanKindServer))
+		}
+		span.AddAttributes(
+			trace.Int64Attribute(opCodeAttribute, int64(message.Headers().OpCode())),
+			trace.StringAttribute(uuidAttribute, message.Headers().UUID().String()),
+		)
+
+		handler.HandleServiceMessage(ctx, message.OnAck(func(err error) {
+			if err != nil {
+				span.SetStatus(trace.Status{Code: trace.StatusCodeUnknown, Message: err.Error()})
+			}
+			span.End()
+		}))
+	})
+}
+
+func sendSpanStatus(err error) trace.Status {
+	var rateLimitedErr *RateLimitedError
+	switch {
+	case err == nil:
+		return trace.Status{Code: trace.StatusCodeOK}
+	case IsAckTimeout(err), errors.Is(err, context.DeadlineExceeded):
+		return trace.Status{Code: trace.StatusCodeDeadlineExceeded, Message: err.Error()}
+	case errors.Is(err, context.Canceled):
+		return trace.Status{Code: trace.StatusCodeCancelled, Message: err.Error()}
+	case errors.Is(err, ErrCircuitOpen):
+		return trace.Status{Code: trace.StatusCodeUnavailable, Message: err.Error()}
+	case errors.As(err, &rateLimitedErr):
+		return trace.Status{Code: trace.StatusCodeResourceExhausted, Message: err.Error()}
+	default:
+		return trace.Status{Code: trace.StatusCodeUnknown, Message: err.Error()}
+	}
+}
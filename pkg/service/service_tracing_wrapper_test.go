@@ -0,0 +1,162 @@
+/*
+Copyright 2026 The Knative Authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package service_test
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+	"go.opencensus.io/trace"
+
+	control "knative.dev/control-protocol/pkg"
+	"knative.dev/control-protocol/pkg/service"
+	"knative.dev/control-protocol/pkg/test"
+)
+
+type spansRecorder struct {
+	mutex sync.Mutex
+	spans []*trace.SpanData
+}
+
+func (r *spansRecorder) ExportSpan(s *trace.SpanData) {
+	r.mutex.Lock()
+	r.spans = append(r.spans, s)
+	r.mutex.Unlock()
+}
+
+func (r *spansRecorder) get(name string) *trace.SpanData {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+	for _, s := range r.spans {
+		if s.Name == name {
+			return s
+		}
+	}
+	return nil
+}
+
+func mustRecordSpans(t *testing.T) *spansRecorder {
+	recorder := &spansRecorder{}
+	trace.RegisterExporter(recorder)
+	trace.ApplyConfig(trace.Config{DefaultSampler: trace.AlwaysSample()})
+	t.Cleanup(func() {
+		trace.UnregisterExporter(recorder)
+		trace.ApplyConfig(trace.Config{DefaultSampler: trace.ProbabilitySampler(1e-4)})
+	})
+	return recorder
+}
+
+func TestTracingService_PropagatesTraceContext(t *testing.T) {
+	recorder := mustRecordSpans(t)
+	_, server, clientCtx, client := test.MustSetupInsecureControlPair(t)
+
+	var handlerSpanContext trace.SpanContext
+	var handlerPayload string
+	server.MessageHandler(service.NewTracingMessageHandler(control.MessageHandlerFunc(func(ctx context.Context, message control.ServiceMessage) {
+		handlerSpanContext = trace.FromContext(ctx).SpanContext()
+		handlerPayload = string(message.Payload())
+		message.Ack()
+	})))
+
+	tracingClient := service.WithTracingService(clientCtx)(client)
+
+	parentCtx, parentSpan := trace.StartSpan(context.TODO(), "reconcile")
+	require.NoError(t, tracingClient.(control.ContextSender).SendAndWaitForAckWithContext(parentCtx, 1, test.MockPayload("Funky!")))
+	parentSpan.End()
+
+	require.Equal(t, "Funky!", handlerPayload)
+	require.Equal(t, parentSpan.SpanContext().TraceID, handlerSpanContext.TraceID)
+
+	sendSpan := recorder.get("control.SendAndWaitForAck")
+	require.NotNil(t, sendSpan)
+	require.Equal(t, parentSpan.SpanContext().SpanID, sendSpan.ParentSpanID)
+	require.Equal(t, int32(trace.StatusCodeOK), sendSpan.Status.Code)
+
+	handleSpan := recorder.get("control.HandleServiceMessage")
+	require.NotNil(t, handleSpan)
+	require.Equal(t, sendSpan.SpanID, handleSpan.ParentSpanID)
+	require.True(t, handleSpan.HasRemoteParent)
+}
+
+func TestTracingService_AckWithErrorSetsStatus(t *testing.T) {
+	recorder := mustRecordSpans(t)
+	_, server, clientCtx, client := test.MustSetupInsecureControlPair(t)
+
+	server.MessageHandler(service.NewTracingMessageHandler(control.MessageHandlerFunc(func(ctx context.Context, message control.ServiceMessage) {
+		message.AckWithError(errors.New("yuck"))
+	})))
+
+	tracingClient := service.WithTracingService(clientCtx)(client)
+	require.EqualError(t, tracingClient.SendAndWaitForAck(1, test.MockPayload("Funky!")), "yuck")
+
+	sendSpan := recorder.get("control.SendAndWaitForAck")
+	require.NotNil(t, sendSpan)
+	require.Equal(t, int32(trace.StatusCodeUnknown), sendSpan.Status.Code)
+	require.Equal(t, "yuck", sendSpan.Status.Message)
+
+	handleSpan := recorder.get("control.HandleServiceMessage")
+	require.NotNil(t, handleSpan)
+	require.Equal(t, int32(trace.StatusCodeUnknown), handleSpan.Status.Code)
+}
+
+func TestService_TraceContextIsStripped(t *testing.T) {
+	mockConnection := test.NewConnectionMock()
+
+	ctx, cancelFn := context.WithCancel(context.TODO())
+	t.Cleanup(cancelFn)
+
+	svc := service.NewService(ctx, mockConnection)
+
+	received := make(chan []byte, 1)
+	svc.MessageHandler(control.MessageHandlerFunc(func(ctx context.Context, message control.ServiceMessage) {
+		require.Equal(t, []byte("Funky!"), message.Payload())
+		require.Equal(t, uint32(len("Funky!")), message.Headers().Length())
+		require.False(t, message.Headers().Check(control.TraceContextFlag))
+		received <- control.TraceContextFromContext(ctx)
+		message.Ack()
+	}))
+
+	msg := control.NewMessage([16]byte{1}, 1, []byte("Funky!"), control.WithTraceContext([]byte{1, 2, 3}))
+	require.True(t, msg.Check(control.TraceContextFlag))
+	mockConnection.PushInboundMessage(&msg)
+
+	require.Equal(t, []byte{1, 2, 3}, <-received)
+}
+
+func TestService_SendWithContextAttachesTraceContext(t *testing.T) {
+	mockConnection := test.NewConnectionMock()
+
+	ctx, cancelFn := context.WithCancel(context.TODO())
+	t.Cleanup(cancelFn)
+
+	svc := service.NewService(ctx, mockConnection)
+
+	go func() {
+		_ = svc.SendAndWaitForAckWithContext(control.ContextWithTraceContext(ctx, []byte{1, 2, 3}), 1, test.MockPayload("Funky!"))
+	}()
+
+	outbound := mockConnection.WaitAtLeastOneOutboundMessage()[0]
+	require.True(t, outbound.Check(control.TraceContextFlag))
+
+	traceContext, err := outbound.StripTraceContext()
+	require.NoError(t, err)
+	require.Equal(t, []byte{1, 2, 3}, traceContext)
+	require.Equal(t, []byte("Funky!"), outbound.Payload())
+}
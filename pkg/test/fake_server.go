@@ -0,0 +1,385 @@
+/*
+Copyright 2026 The Knative Authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package test
+
+import (
+	"context"
+	"crypto/tls"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/google/uuid"
+	"go.uber.org/zap"
+	"k8s.io/utils/clock"
+	"knative.dev/pkg/logging"
+	"sigs.k8s.io/yaml"
+
+	control "knative.dev/control-protocol/pkg"
+	"knative.dev/control-protocol/pkg/message"
+	"knative.dev/control-protocol/pkg/network"
+	"knative.dev/control-protocol/pkg/service"
+)
+
+// FakeServerAction is the action performed by the FakeServer when receiving a message
+type FakeServerAction string
+
+const (
+	// FakeServerAck acks the message
+	FakeServerAck FakeServerAction = "ack"
+	// FakeServerAckWithError acks the message with the error of the rule
+	FakeServerAckWithError FakeServerAction = "ack-with-error"
+	// FakeServerIgnore never acks the message, useful to exercise the ack timeouts
+	FakeServerIgnore FakeServerAction = "ignore"
+)
+
+// FakeServerScript describes how the FakeServer answers to the received messages.
+// It can be parsed from YAML or JSON with ParseFakeServerScript, for example:
+//
+//	default:
+//	  action: ack
+//	rules:
+//	- opcode: 1
+//	  delay: 500ms
+//	  asyncResult:
+//	    opcode: 2
+//	    after: 3s
+//	    commandIdLength: 8
+//	- opcode: 3
+//	  action: ack-with-error
+//	  error: funky error
+type FakeServerScript struct {
+	// Default is the rule applied to the opcodes without a rule. If not set, the messages are acked.
+	Default *FakeServerRule `json:"default,omitempty"`
+	// Rules are the rules per opcode
+	Rules []FakeServerRule `json:"rules,omitempty"`
+}
+
+// FakeServerRule describes how to answer to a message
+type FakeServerRule struct {
+	// OpCode is the opcode of the messages this rule applies to. Ignored in FakeServerScript.Default.
+	OpCode uint8 `json:"opcode"`
+	// Action is the action to perform. Defaults to FakeServerAck, or FakeServerAckWithError if Error is set.
+	Action FakeServerAction `json:"action,omitempty"`
+	// Error is the error used with FakeServerAckWithError
+	Error string `json:"error,omitempty"`
+	// Delay is the delay before performing the action
+	Delay Duration `json:"delay,omitempty"`
+	// AsyncResult, if set, makes the server send back a message.AsyncCommandResult after acking the message
+	AsyncResult *FakeAsyncResult `json:"asyncResult,omitempty"`
+}
+
+// FakeAsyncResult describes the message.AsyncCommandResult sent back after acking a command
+type FakeAsyncResult struct {
+	// OpCode is the opcode of the result message
+	OpCode uint8 `json:"opcode"`
+	// After is the delay between the ack and the result
+	After Duration `json:"after,omitempty"`
+	// Error is the error of the result. If empty, the result is successful.
+	Error string `json:"error,omitempty"`
+	// CommandIdOffset and CommandIdLength locate the command id inside the payload of the command.
+	// A zero CommandIdLength means until the end of the payload.
+	CommandIdOffset int `json:"commandIdOffset,omitempty"`
+	CommandIdLength int `json:"commandIdLength,omitempty"`
+}
+
+// Duration is a time.Duration which can be unmarshalled from strings like "1.5s", or from numbers of seconds
+type Duration time.Duration
+
+func (d Duration) MarshalJSON() ([]byte, error) {
+	return json.Marshal(time.Duration(d).String())
+}
+
+func (d *Duration) UnmarshalJSON(b []byte) error {
+	var s string
+	if err := json.Unmarshal(b, &s); err == nil {
+		parsed, err := time.ParseDuration(s)
+		if err != nil {
+			return err
+		}
+		*d = Duration(parsed)
+		return nil
+	}
+	seconds, err := strconv.ParseFloat(string(b), 64)
+	if err != nil {
+		return fmt.Errorf("invalid duration %s: expecting a string like \"1.5s\" or a number of seconds", string(b))
+	}
+	*d = Duration(seconds * float64(time.Second))
+	return nil
+}
+
+// ParseFakeServerScript parses and validates a FakeServerScript written in YAML or JSON
+func ParseFakeServerScript(b []byte) (*FakeServerScript, error) {
+	var script FakeServerScript
+	if err := yaml.UnmarshalStrict(b, &script); err != nil {
+		return nil, err
+	}
+	if err := script.Validate(); err != nil {
+		return nil, err
+	}
+	return &script, nil
+}
+
+// Validate checks the script is well formed
+func (s *FakeServerScript) Validate() error {
+	seen := make(map[uint8]bool)
+	for i, rule := range s.Rules {
+		if control.OpCode(rule.OpCode).IsReserved() {
+			return fmt.Errorf("rule %d: opcode %d is reserved", i, rule.OpCode)
+		}
+		if seen[rule.OpCode] {
+			return fmt.Errorf("rule %d: duplicate rule for opcode %d", i, rule.OpCode)
+		}
+		seen[rule.OpCode] = true
+		if err := rule.validate(); err != nil {
+			return fmt.Errorf("rule %d: %w", i, err)
+		}
+	}
+	if s.Default != nil {
+		if err := s.Default.validate(); err != nil {
+			return fmt.Errorf("default rule: %w", err)
+		}
+	}
+	return nil
+}
+
+func (r *FakeServerRule) validate() error {
+	switch r.action() {
+	case FakeServerAck, FakeServerIgnore:
+	case FakeServerAckWithError:
+		if r.Error == "" {
+			return errors.New("action ack-with-error requires an error")
+		}
+	default:
+		return fmt.Errorf("unknown action %q", r.Action)
+	}
+	if r.Delay < 0 {
+		return errors.New("negative delay")
+	}
+	if r.AsyncResult != nil {
+		if control.OpCode(r.AsyncResult.OpCode).IsReserved() {
+			return fmt.Errorf("async result opcode %d is reserved", r.AsyncResult.OpCode)
+		}
+		if r.AsyncResult.After < 0 || r.AsyncResult.CommandIdOffset < 0 || r.AsyncResult.CommandIdLength < 0 {
+			return errors.New("negative async result delay or command id position")
+		}
+	}
+	return nil
+}
+
+func (r *FakeServerRule) action() FakeServerAction {
+	if r.Action == "" {
+		if r.Error != "" {
+			return FakeServerAckWithError
+		}
+		return FakeServerAck
+	}
+	return r.Action
+}
+
+func (s *FakeServerScript) ruleFor(opcode uint8) FakeServerRule {
+	for _, rule := range s.Rules {
+		if rule.OpCode == opcode {
+			return rule
+		}
+	}
+	if s.Default != nil {
+		return *s.Default
+	}
+	return FakeServerRule{Action: FakeServerAck}
+}
+
+// FakeServerMessage is a message received by the FakeServer
+type FakeServerMessage struct {
+	Time    time.Time
+	OpCode  uint8
+	UUID    uuid.UUID
+	Payload []byte
+}
+
+type fakeServerOptions struct {
+	clock         clock.WithDelayedExecution
+	serverOptions []network.ControlServerOption
+}
+
+type FakeServerOption func(*fakeServerOptions)
+
+// WithFakeServerClock sets the clock used for the delays of the script and for the ack timeouts.
+// Tests can provide a fake clock, to advance the time deterministically.
+func WithFakeServerClock(clock clock.WithDelayedExecution) FakeServerOption {
+	return func(options *fakeServerOptions) {
+		options.clock = clock
+	}
+}
+
+// WithControlServerOptions configures the control server, e.g. the port
+func WithControlServerOptions(opts ...network.ControlServerOption) FakeServerOption {
+	return func(options *fakeServerOptions) {
+		options.serverOptions = append(options.serverOptions, opts...)
+	}
+}
+
+// FakeServer is a control server answering according to a FakeServerScript.
+// It can be used to develop and test controllers without running the real data plane.
+type FakeServer struct {
+	*network.ControlServer
+
+	ctx    context.Context
+	script *FakeServerScript
+	clock  clock.WithDelayedExecution
+
+	receivedMutex sync.Mutex
+	received      []FakeServerMessage
+}
+
+// StartFakeServer starts a FakeServer. If tlsConfigLoader is nil, the server accepts plain connections.
+func StartFakeServer(ctx context.Context, script *FakeServerScript, tlsConfigLoader func() (*tls.Config, error), opts ...FakeServerOption) (*FakeServer, error) {
+	if script == nil {
+		script = &FakeServerScript{}
+	}
+	if err := script.Validate(); err != nil {
+		return nil, err
+	}
+
+	options := fakeServerOptions{
+		clock: clock.RealClock{},
+	}
+	for _, fn := range opts {
+		fn(&options)
+	}
+	// Prepend the clock, so it can still be overridden by the service options
+	serverOptions := append([]network.ControlServerOption{network.WithServiceOptions(service.WithClock(options.clock))}, options.serverOptions...)
+
+	server, err := network.StartControlServer(ctx, tlsConfigLoader, serverOptions...)
+	if err != nil {
+		return nil, err
+	}
+
+	fs := &FakeServer{
+		ControlServer: server,
+		ctx:           ctx,
+		script:        script,
+		clock:         options.clock,
+	}
+	server.MessageHandler(control.MessageHandlerFunc(fs.handle))
+	server.ErrorHandler(control.ErrorHandlerFunc(func(ctx context.Context, err error) {
+		logging.FromContext(ctx).Warnw("Control connection error", "error", err)
+	}))
+	return fs, nil
+}
+
+// Received returns the messages received so far
+func (fs *FakeServer) Received() []FakeServerMessage {
+	fs.receivedMutex.Lock()
+	defer fs.receivedMutex.Unlock()
+	return append([]FakeServerMessage(nil), fs.received...)
+}
+
+func (fs *FakeServer) handle(ctx context.Context, msg control.ServiceMessage) {
+	received := FakeServerMessage{
+		Time:    fs.clock.Now(),
+		OpCode:  msg.Headers().OpCode(),
+		UUID:    msg.Headers().UUID(),
+		Payload: msg.Payload(),
+	}
+	fs.receivedMutex.Lock()
+	fs.received = append(fs.received, received)
+	fs.receivedMutex.Unlock()
+
+	rule := fs.script.ruleFor(received.OpCode)
+	logger := logging.FromContext(fs.ctx).With(
+		"opcode", received.OpCode,
+		"uuid", received.UUID.String(),
+	)
+	logger.Infow("Received message",
+		"length", len(received.Payload),
+		"payload", describePayload(received.Payload),
+		"action", rule.action(),
+	)
+
+	if !fs.wait(rule.Delay) {
+		return
+	}
+
+	switch rule.action() {
+	case FakeServerIgnore:
+		return
+	case FakeServerAckWithError:
+		msg.AckWithError(errors.New(rule.Error))
+	default:
+		msg.Ack()
+	}
+
+	if rule.AsyncResult != nil {
+		fs.sendAsyncResult(logger, rule.AsyncResult, received.Payload)
+	}
+}
+
+// wait waits for the delay, returning false if the server stopped in the meantime
+func (fs *FakeServer) wait(delay Duration) bool {
+	if delay <= 0 {
+		return true
+	}
+	timer := fs.clock.NewTimer(time.Duration(delay))
+	defer timer.Stop()
+	select {
+	case <-timer.C():
+		return true
+	case <-fs.ctx.Done():
+		return false
+	}
+}
+
+func (fs *FakeServer) sendAsyncResult(logger *zap.SugaredLogger, asyncResult *FakeAsyncResult, commandPayload []byte) {
+	if !fs.wait(asyncResult.After) {
+		return
+	}
+
+	result := message.AsyncCommandResult{
+		CommandId: asyncResult.commandId(commandPayload),
+		Error:     asyncResult.Error,
+	}
+	if err := fs.SendAndWaitForAck(control.OpCode(asyncResult.OpCode), result); err != nil {
+		logger.Warnw("Failed to send the async command result", "resultOpcode", asyncResult.OpCode, "error", err)
+		return
+	}
+	logger.Infow("Sent async command result", "resultOpcode", asyncResult.OpCode, "commandId", hex.EncodeToString(result.CommandId))
+}
+
+func (r *FakeAsyncResult) commandId(payload []byte) []byte {
+	if r.CommandIdOffset >= len(payload) {
+		return []byte{}
+	}
+	id := payload[r.CommandIdOffset:]
+	if r.CommandIdLength > 0 && r.CommandIdLength < len(id) {
+		id = id[:r.CommandIdLength]
+	}
+	return id
+}
+
+func describePayload(payload []byte) string {
+	for _, c := range payload {
+		if c < 0x20 || c > 0x7e {
+			return hex.EncodeToString(payload)
+		}
+	}
+	return string(payload)
+}
@@ -0,0 +1,165 @@
+/*
+Copyright 2026 The Knative Authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package test_test
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+	clocktesting "k8s.io/utils/clock/testing"
+	"knative.dev/pkg/logging"
+
+	control "knative.dev/control-protocol/pkg"
+	"knative.dev/control-protocol/pkg/message"
+	"knative.dev/control-protocol/pkg/network"
+	"knative.dev/control-protocol/pkg/test"
+)
+
+const script = `
+default:
+  action: ignore
+rules:
+- opcode: 1
+  delay: 100ms
+  asyncResult:
+    opcode: 2
+    after: 0.1
+    commandIdLength: 8
+- opcode: 3
+  error: funky error
+`
+
+func TestParseFakeServerScript(t *testing.T) {
+	s, err := test.ParseFakeServerScript([]byte(script))
+	require.NoError(t, err)
+	require.Len(t, s.Rules, 2)
+	require.Equal(t, test.Duration(100*time.Millisecond), s.Rules[0].Delay)
+	require.Equal(t, test.Duration(100*time.Millisecond), s.Rules[0].AsyncResult.After)
+	require.Equal(t, test.FakeServerIgnore, s.Default.Action)
+
+	for name, invalid := range map[string]string{
+		"unknown field":       "rules: [{opcode: 1, nope: true}]",
+		"unknown action":      "rules: [{opcode: 1, action: explode}]",
+		"missing error":       "rules: [{opcode: 1, action: ack-with-error}]",
+		"duplicate opcode":    "rules: [{opcode: 1}, {opcode: 1}]",
+		"reserved opcode":     "rules: [{opcode: 255}]",
+		"registration opcode": "rules: [{opcode: 253}]",
+		"invalid duration":    "rules: [{opcode: 1, delay: soon}]",
+		"negative duration":   "rules: [{opcode: 1, delay: -1s}]",
+	} {
+		t.Run(name, func(t *testing.T) {
+			_, err := test.ParseFakeServerScript([]byte(invalid))
+			require.Error(t, err)
+		})
+	}
+}
+
+func TestFakeServer(t *testing.T) {
+	logger, _ := zap.NewDevelopment()
+	ctx, cancelFn := context.WithCancel(logging.WithLogger(context.TODO(), logger.Sugar()))
+	t.Cleanup(cancelFn)
+
+	s, err := test.ParseFakeServerScript([]byte(script))
+	require.NoError(t, err)
+	server, err := test.StartFakeServer(ctx, s, nil, test.WithControlServerOptions(network.WithPort(0)))
+	require.NoError(t, err)
+
+	client, err := network.StartControlClient(ctx, &net.Dialer{}, fmt.Sprintf("127.0.0.1:%d", server.ListeningPort()))
+	require.NoError(t, err)
+
+	results := make(chan message.AsyncCommandResult, 1)
+	client.MessageHandler(control.MessageHandlerFunc(func(ctx context.Context, msg control.ServiceMessage) {
+		require.Equal(t, uint8(2), msg.Headers().OpCode())
+		var result message.AsyncCommandResult
+		require.NoError(t, result.UnmarshalBinary(msg.Payload()))
+		msg.Ack()
+		results <- result
+	}))
+
+	start := time.Now()
+	require.NoError(t, client.SendAndWaitForAck(1, test.MockPayload("\x00\x00\x00\x00\x00\x00\x00\x2aFunky!")))
+	require.GreaterOrEqual(t, time.Since(start), 100*time.Millisecond)
+	select {
+	case result := <-results:
+		require.Equal(t, message.Int64CommandId(42), result.CommandId)
+		require.False(t, result.IsFailed())
+	case <-time.After(5 * time.Second):
+		require.Fail(t, "async command result not received")
+	}
+
+	require.EqualError(t, client.SendAndWaitForAck(3, test.MockPayload("Funky!")), "funky error")
+
+	sendCtx, sendCancelFn := context.WithTimeout(ctx, 200*time.Millisecond)
+	defer sendCancelFn()
+	require.Error(t, client.(control.ContextSender).SendAndWaitForAckWithContext(sendCtx, 4, test.MockPayload("Funky!")))
+
+	received := server.Received()
+	require.Len(t, received, 3)
+	require.Equal(t, []uint8{1, 3, 4}, []uint8{received[0].OpCode, received[1].OpCode, received[2].OpCode})
+}
+
+func TestFakeServer_Clock(t *testing.T) {
+	ctx, cancelFn := context.WithCancel(context.TODO())
+	t.Cleanup(cancelFn)
+
+	s, err := test.ParseFakeServerScript([]byte(`
+rules:
+- opcode: 1
+  delay: 1m
+  asyncResult:
+    opcode: 2
+    after: 1m
+`))
+	require.NoError(t, err)
+	fakeClock := clocktesting.NewFakeClock(time.Now())
+	server, err := test.StartFakeServer(ctx, s, nil,
+		test.WithFakeServerClock(fakeClock),
+		test.WithControlServerOptions(network.WithPort(0)),
+	)
+	require.NoError(t, err)
+
+	client, err := network.StartControlClient(ctx, &net.Dialer{}, fmt.Sprintf("127.0.0.1:%d", server.ListeningPort()))
+	require.NoError(t, err)
+	results := make(chan uint8, 1)
+	client.MessageHandler(control.MessageHandlerFunc(func(ctx context.Context, msg control.ServiceMessage) {
+		msg.Ack()
+		results <- msg.Headers().OpCode()
+	}))
+
+	errCh := make(chan error, 1)
+	go func() {
+		errCh <- client.SendAndWaitForAck(1, test.MockPayload("Funky!"))
+	}()
+
+	// The ack waits for the delay
+	require.Eventually(t, fakeClock.HasWaiters, 5*time.Second, 10*time.Millisecond)
+	require.Len(t, errCh, 0)
+	fakeClock.Step(time.Minute)
+	require.NoError(t, <-errCh)
+
+	// The async result waits for its delay
+	require.Eventually(t, fakeClock.HasWaiters, 5*time.Second, 10*time.Millisecond)
+	require.Len(t, results, 0)
+	fakeClock.Step(time.Minute)
+	require.Equal(t, uint8(2), <-results)
+	require.Equal(t, fakeClock.Now().Add(-2*time.Minute), server.Received()[0].Time)
+}
@@ -0,0 +1,311 @@
+/*
+Copyright 2026 The Knative Authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package test
+
+import (
+	"bytes"
+	"context"
+	"encoding/binary"
+	"errors"
+	"math/rand"
+	"net"
+	"sync"
+	"time"
+
+	control "knative.dev/control-protocol/pkg"
+	"knative.dev/control-protocol/pkg/network"
+)
+
+// FaultKind is a kind of fault injected by the FaultInjector
+type FaultKind int
+
+const (
+	// FaultDrop drops the frame
+	FaultDrop FaultKind = iota
+	// FaultDelay delays the frame by FaultRule.Delay, delaying the following frames too
+	FaultDelay
+	// FaultDuplicate writes the frame twice
+	FaultDuplicate
+	// FaultReorder holds the frame, writing it after the next one
+	FaultReorder
+	// FaultCorrupt flips a random byte of the frame. The version and length fields are never corrupted,
+	// so the other end can still find the frame boundaries.
+	FaultCorrupt
+	// FaultCut writes part of the frame and then closes the connection
+	FaultCut
+)
+
+func (k FaultKind) String() string {
+	switch k {
+	case FaultDrop:
+		return "drop"
+	case FaultDelay:
+		return "delay"
+	case FaultDuplicate:
+		return "duplicate"
+	case FaultReorder:
+		return "reorder"
+	case FaultCorrupt:
+		return "corrupt"
+	case FaultCut:
+		return "cut"
+	default:
+		return "unknown"
+	}
+}
+
+// ErrInjectedCut is returned by the writes interrupted by FaultCut
+var ErrInjectedCut = errors.New("connection cut by the fault injector")
+
+// FaultRule describes when to inject a fault
+type FaultRule struct {
+	Kind FaultKind
+	// Match selects the frames this rule applies to. If nil, the rule applies to every frame.
+	Match func(msg *control.Message) bool
+	// Probability of injecting the fault in a matching frame. If 0, the fault is injected in every matching frame.
+	Probability float64
+	// Skip is the number of matching frames to skip before injecting the fault
+	Skip int
+	// Times is the maximum number of injected faults. If 0, there's no limit.
+	Times int
+	// Delay is the delay used by FaultDelay
+	Delay time.Duration
+}
+
+// MatchOpCodes matches the frames with one of the provided opcodes
+func MatchOpCodes(opcodes ...control.OpCode) func(msg *control.Message) bool {
+	return func(msg *control.Message) bool {
+		for _, opcode := range opcodes {
+			if control.OpCode(msg.OpCode()) == opcode {
+				return true
+			}
+		}
+		return false
+	}
+}
+
+type faultRuleState struct {
+	FaultRule
+	matched  int
+	injected int
+}
+
+// FaultInjector injects faults in the frames written to the wrapped net.Conn.
+// The faults are reproducible: given the same seed and the same sequence of written frames, the same faults are injected.
+// The same FaultInjector can wrap several connections, e.g. to keep the rule limits across reconnections.
+//
+// The wrapped connection must carry the plain frames: with tls, wrap the tls connection rather than the one beneath it.
+type FaultInjector struct {
+	mutex    sync.Mutex
+	rand     *rand.Rand
+	rules    []*faultRuleState
+	injected map[FaultKind]int
+}
+
+func NewFaultInjector(seed int64, rules ...FaultRule) *FaultInjector {
+	states := make([]*faultRuleState, len(rules))
+	for i, rule := range rules {
+		states[i] = &faultRuleState{FaultRule: rule}
+	}
+	return &FaultInjector{
+		rand:     rand.New(rand.NewSource(seed)),
+		rules:    states,
+		injected: make(map[FaultKind]int),
+	}
+}
+
+// Injected returns the number of injected faults of the provided kind
+func (f *FaultInjector) Injected(kind FaultKind) int {
+	f.mutex.Lock()
+	defer f.mutex.Unlock()
+	return f.injected[kind]
+}
+
+// WrapConn wraps the connection, injecting faults in the frames written to it
+func (f *FaultInjector) WrapConn(conn net.Conn) net.Conn {
+	return &faultyConn{Conn: conn, injector: f}
+}
+
+// Dialer wraps the dialer, so the dialed connections are wrapped with WrapConn.
+// This can be used with network.StartControlClient: with tls, wrap the tls dialer, e.g. f.Dialer(network.NewTLSDialer(dialer, config)).
+// With reconciler.WithDialer, this can be used only for pools without tls,
+// because the pool upgrades the dialed connections to tls, so the faults would hit the encrypted frames.
+func (f *FaultInjector) Dialer(dialer network.Dialer) network.Dialer {
+	return &faultyDialer{Dialer: dialer, injector: f}
+}
+
+// Listener wraps the listener, so the accepted connections are wrapped with WrapConn.
+// This can be used with network.WithListener, only for servers without tls,
+// because the server upgrades the accepted connections to tls.
+func (f *FaultInjector) Listener(ln net.Listener) net.Listener {
+	return &faultyListener{Listener: ln, injector: f}
+}
+
+type faultDecision struct {
+	kinds       map[FaultKind]bool
+	delay       time.Duration
+	corruptByte int
+	cutAt       int
+}
+
+func (f *FaultInjector) decide(frame []byte) faultDecision {
+	var msg control.Message
+	_, _ = msg.ReadFrom(bytes.NewReader(frame))
+
+	f.mutex.Lock()
+	defer f.mutex.Unlock()
+
+	decision := faultDecision{kinds: make(map[FaultKind]bool)}
+	for _, rule := range f.rules {
+		if rule.Match != nil && !rule.Match(&msg) {
+			continue
+		}
+		rule.matched++
+		if rule.matched <= rule.Skip {
+			continue
+		}
+		if rule.Times > 0 && rule.injected >= rule.Times {
+			continue
+		}
+		if rule.Probability > 0 && f.rand.Float64() >= rule.Probability {
+			continue
+		}
+		rule.injected++
+		f.injected[rule.Kind]++
+		decision.kinds[rule.Kind] = true
+		switch rule.Kind {
+		case FaultDelay:
+			decision.delay += rule.Delay
+		case FaultCorrupt:
+			decision.corruptByte = corruptibleByte(f.rand, len(frame))
+		case FaultCut:
+			decision.cutAt = 1 + f.rand.Intn(len(frame)-1)
+		}
+	}
+	return decision
+}
+
+// corruptibleByte picks a byte which is neither the version nor part of the length
+func corruptibleByte(r *rand.Rand, frameLength int) int {
+	// flags, unused, opcode and uuid are in the range [1, 20), the payload starts at 24
+	candidates := 19 + (frameLength - headerLength)
+	i := 1 + r.Intn(candidates)
+	if i >= 20 {
+		i += 4
+	}
+	return i
+}
+
+const headerLength = 24
+
+type faultyConn struct {
+	net.Conn
+	injector *FaultInjector
+
+	mutex sync.Mutex
+	// buf contains the partially written frame
+	buf []byte
+	// held is the frame held by FaultReorder
+	held []byte
+}
+
+func (c *faultyConn) Write(p []byte) (int, error) {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+
+	c.buf = append(c.buf, p...)
+	for len(c.buf) >= headerLength {
+		frameLength := headerLength + int(binary.BigEndian.Uint32(c.buf[20:24]))
+		if len(c.buf) < frameLength {
+			break
+		}
+		frame := make([]byte, frameLength)
+		copy(frame, c.buf)
+		c.buf = c.buf[frameLength:]
+		if err := c.writeFrame(frame); err != nil {
+			c.buf = nil
+			return 0, err
+		}
+	}
+	return len(p), nil
+}
+
+func (c *faultyConn) writeFrame(frame []byte) error {
+	decision := c.injector.decide(frame)
+
+	if decision.kinds[FaultCut] {
+		_, _ = c.Conn.Write(frame[:decision.cutAt])
+		_ = c.Conn.Close()
+		return &net.OpError{Op: "write", Net: "tcp", Source: c.LocalAddr(), Addr: c.RemoteAddr(), Err: ErrInjectedCut}
+	}
+	if decision.kinds[FaultDrop] {
+		return nil
+	}
+	if decision.delay > 0 {
+		time.Sleep(decision.delay)
+	}
+	if decision.kinds[FaultCorrupt] {
+		frame[decision.corruptByte] ^= 0xff
+	}
+	if decision.kinds[FaultReorder] && c.held == nil {
+		c.held = frame
+		return nil
+	}
+
+	if _, err := c.Conn.Write(frame); err != nil {
+		return err
+	}
+	if decision.kinds[FaultDuplicate] {
+		if _, err := c.Conn.Write(frame); err != nil {
+			return err
+		}
+	}
+	if c.held != nil {
+		held := c.held
+		c.held = nil
+		if _, err := c.Conn.Write(held); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+type faultyDialer struct {
+	network.Dialer
+	injector *FaultInjector
+}
+
+func (d *faultyDialer) DialContext(ctx context.Context, network string, address string) (net.Conn, error) {
+	conn, err := d.Dialer.DialContext(ctx, network, address)
+	if err != nil {
+		return nil, err
+	}
+	return d.injector.WrapConn(conn), nil
+}
+
+type faultyListener struct {
+	net.Listener
+	injector *FaultInjector
+}
+
+func (l *faultyListener) Accept() (net.Conn, error) {
+	conn, err := l.Listener.Accept()
+	if err != nil {
+		return nil, err
+	}
+	return l.injector.WrapConn(conn), nil
+}
@@ -0,0 +1,214 @@
+/*
+Copyright 2026 The Knative Authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package test_test
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"net"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+	"knative.dev/pkg/logging"
+
+	control "knative.dev/control-protocol/pkg"
+	"knative.dev/control-protocol/pkg/network"
+	"knative.dev/control-protocol/pkg/test"
+)
+
+func TestFaultInjector_Kinds(t *testing.T) {
+	msgs := []control.Message{
+		control.NewMessage(uuid.New(), 1, []byte("a")),
+		control.NewMessage(uuid.New(), 2, []byte("b")),
+		control.NewMessage(uuid.New(), 1, []byte("c")),
+	}
+
+	tests := map[string]struct {
+		rule   test.FaultRule
+		expect func(t *testing.T, written []control.Message)
+	}{
+		"drop": {
+			rule: test.FaultRule{Kind: test.FaultDrop, Match: test.MatchOpCodes(2)},
+			expect: func(t *testing.T, written []control.Message) {
+				requirePayloads(t, written, "a", "c")
+			},
+		},
+		"duplicate": {
+			rule: test.FaultRule{Kind: test.FaultDuplicate, Skip: 1, Times: 1},
+			expect: func(t *testing.T, written []control.Message) {
+				requirePayloads(t, written, "a", "b", "b", "c")
+			},
+		},
+		"reorder": {
+			rule: test.FaultRule{Kind: test.FaultReorder, Times: 1},
+			expect: func(t *testing.T, written []control.Message) {
+				requirePayloads(t, written, "b", "a", "c")
+			},
+		},
+		"corrupt": {
+			rule: test.FaultRule{Kind: test.FaultCorrupt, Match: test.MatchOpCodes(2)},
+			expect: func(t *testing.T, written []control.Message) {
+				require.Len(t, written, 3)
+				corrupted := written[1]
+				require.Equal(t, msgs[1].Version(), corrupted.Version())
+				require.Equal(t, msgs[1].Length(), corrupted.Length())
+				require.False(t, corrupted.OpCode() == 2 && corrupted.UUID() == msgs[1].UUID() && string(corrupted.Payload()) == "b")
+			},
+		},
+	}
+	for name, tc := range tests {
+		t.Run(name, func(t *testing.T) {
+			conn := &recordingConn{}
+			faulty := test.NewFaultInjector(0, tc.rule).WrapConn(conn)
+			for _, msg := range msgs {
+				msg := msg
+				// The frames are written in several chunks, like the network code does
+				_, err := msg.WriteTo(faulty)
+				require.NoError(t, err)
+			}
+			tc.expect(t, conn.messages(t))
+		})
+	}
+}
+
+func TestFaultInjector_Cut(t *testing.T) {
+	conn := &recordingConn{}
+	injector := test.NewFaultInjector(0, test.FaultRule{Kind: test.FaultCut, Skip: 1})
+	faulty := injector.WrapConn(conn)
+
+	msg := control.NewMessage(uuid.New(), 1, []byte("Funky!"))
+	_, err := msg.WriteTo(faulty)
+	require.NoError(t, err)
+	_, err = msg.WriteTo(faulty)
+	require.True(t, errors.Is(err, test.ErrInjectedCut))
+
+	require.True(t, conn.closed)
+	require.Greater(t, conn.buf.Len(), 30)
+	require.Less(t, conn.buf.Len(), 60)
+	require.Equal(t, 1, injector.Injected(test.FaultCut))
+}
+
+func TestFaultInjector_Reproducible(t *testing.T) {
+	run := func(seed int64) []byte {
+		conn := &recordingConn{}
+		faulty := test.NewFaultInjector(seed,
+			test.FaultRule{Kind: test.FaultDrop, Probability: 0.3},
+			test.FaultRule{Kind: test.FaultDuplicate, Probability: 0.3},
+			test.FaultRule{Kind: test.FaultCorrupt, Probability: 0.3},
+		).WrapConn(conn)
+		for i := 0; i < 100; i++ {
+			msg := control.NewMessage(uuid.UUID{byte(i)}, 1, []byte("Funky!"))
+			_, err := msg.WriteTo(faulty)
+			require.NoError(t, err)
+		}
+		return conn.buf.Bytes()
+	}
+
+	require.Equal(t, run(42), run(42))
+	require.NotEqual(t, run(42), run(43))
+}
+
+func TestFaultInjector_CutTriggersRedialAndResend(t *testing.T) {
+	ctx, memoryNetwork, server := startInMemoryServer(t)
+
+	injector := test.NewFaultInjector(0, test.FaultRule{Kind: test.FaultCut, Match: test.MatchOpCodes(1), Times: 1})
+	client, err := network.StartControlClient(ctx, injector.Dialer(memoryNetwork), "10.0.0.1:9000")
+	require.NoError(t, err)
+
+	// The message is re-enqueued after the cut, and sent again on the new connection
+	test.SendReceiveTest(t, client, server)
+	require.Equal(t, 1, injector.Injected(test.FaultCut))
+}
+
+func TestFaultInjector_DropTriggersTimeout(t *testing.T) {
+	ctx, memoryNetwork, server := startInMemoryServer(t)
+	server.MessageHandler(control.MessageHandlerFunc(func(ctx context.Context, message control.ServiceMessage) {
+		message.Ack()
+	}))
+
+	injector := test.NewFaultInjector(0, test.FaultRule{Kind: test.FaultDrop, Match: test.MatchOpCodes(1), Times: 1})
+	client, err := network.StartControlClient(ctx, injector.Dialer(memoryNetwork), "10.0.0.1:9000")
+	require.NoError(t, err)
+
+	sendCtx, cancelFn := context.WithTimeout(ctx, 200*time.Millisecond)
+	defer cancelFn()
+	require.Error(t, client.(control.ContextSender).SendAndWaitForAckWithContext(sendCtx, 1, test.MockPayload("Funky!")))
+
+	require.NoError(t, client.SendAndWaitForAck(1, test.MockPayload("Funky!")))
+	require.Equal(t, 1, injector.Injected(test.FaultDrop))
+}
+
+func startInMemoryServer(t *testing.T) (context.Context, *network.MemoryNetwork, *network.ControlServer) {
+	logger, _ := zap.NewDevelopment()
+	ctx, cancelFn := context.WithCancel(logging.WithLogger(context.TODO(), logger.Sugar()))
+	t.Cleanup(cancelFn)
+
+	memoryNetwork := network.NewMemoryNetwork()
+	ln, err := memoryNetwork.Listen("10.0.0.1:9000")
+	require.NoError(t, err)
+	server, err := network.StartInsecureControlServer(ctx, network.WithListener(ln))
+	require.NoError(t, err)
+	return ctx, memoryNetwork, server
+}
+
+func requirePayloads(t *testing.T, written []control.Message, payloads ...string) {
+	var got []string
+	for _, msg := range written {
+		got = append(got, string(msg.Payload()))
+	}
+	require.Equal(t, payloads, got)
+}
+
+// recordingConn records the written bytes
+type recordingConn struct {
+	net.Conn
+	buf    bytes.Buffer
+	closed bool
+}
+
+func (c *recordingConn) Write(p []byte) (int, error) {
+	return c.buf.Write(p)
+}
+
+func (c *recordingConn) Close() error {
+	c.closed = true
+	return nil
+}
+
+func (c *recordingConn) LocalAddr() net.Addr {
+	return &net.TCPAddr{}
+}
+
+func (c *recordingConn) RemoteAddr() net.Addr {
+	return &net.TCPAddr{}
+}
+
+func (c *recordingConn) messages(t *testing.T) []control.Message {
+	var msgs []control.Message
+	r := bytes.NewReader(c.buf.Bytes())
+	for r.Len() > 0 {
+		var msg control.Message
+		_, err := msg.ReadFrom(r)
+		require.NoError(t, err)
+		msgs = append(msgs, msg)
+	}
+	return msgs
+}
@@ -82,6 +82,32 @@ func MustSetupInsecureControlPair(t *testing.T) (context.Context, control.Servic
 	return serverCtx, controlServer, clientCtx, client
 }
 
+// MustSetupInMemoryControlPair setup a server and a client connected through a network.MemoryNetwork, without sockets
+func MustSetupInMemoryControlPair(t *testing.T) (context.Context, control.Service, context.Context, control.Service) {
+	logger, _ := zap.NewDevelopment()
+	ctx := logging.WithLogger(context.TODO(), logger.Sugar())
+
+	clientCtx, clientCancelFn := context.WithCancel(ctx)
+	serverCtx, serverCancelFn := context.WithCancel(ctx)
+
+	memoryNetwork := network.NewMemoryNetwork()
+	ln, err := memoryNetwork.Listen("127.0.0.1:0")
+	require.NoError(t, err)
+
+	controlServer, err := network.StartInsecureControlServer(serverCtx, network.WithListener(ln))
+	require.NoError(t, err)
+	t.Cleanup(func() {
+		serverCancelFn()
+		<-controlServer.ClosedCh()
+	})
+
+	client, err := network.StartControlClient(clientCtx, memoryNetwork, ln.Addr().String())
+	require.NoError(t, err)
+	t.Cleanup(clientCancelFn)
+
+	return serverCtx, controlServer, clientCtx, client
+}
+
 type MockTLSDialerFactory tls.Dialer
 
 func (m *MockTLSDialerFactory) GenerateTLSDialer(*net.Dialer) (*tls.Dialer, error) {
@@ -126,6 +152,40 @@ func MustSetupSecureControlWithPool(t *testing.T, ctx context.Context, opts ...r
 	return server, connectionPool
 }
 
+// MustSetupInMemoryControlWithPool setup a server and a pool connected through a network.MemoryNetwork.
+// The server listens on 127.0.0.1:<ListeningPort> of the in-memory network.
+func MustSetupInMemoryControlWithPool(t *testing.T, ctx context.Context, opts ...reconciler.ControlPlaneConnectionPoolOption) (*network.ControlServer, reconciler.ControlPlaneConnectionPool) {
+	return mustSetupInMemoryControlWithPool(t, ctx, nil, nil, opts...)
+}
+
+// MustSetupSecureInMemoryControlWithPool is like MustSetupInMemoryControlWithPool, but using tls
+func MustSetupSecureInMemoryControlWithPool(t *testing.T, ctx context.Context, opts ...reconciler.ControlPlaneConnectionPoolOption) (*network.ControlServer, reconciler.ControlPlaneConnectionPool) {
+	serverTlsConf, clientDialer := MustGenerateTestTLSConf(t, ctx)
+	return mustSetupInMemoryControlWithPool(t, ctx, serverTlsConf, (*MockTLSDialerFactory)(clientDialer), opts...)
+}
+
+func mustSetupInMemoryControlWithPool(t *testing.T, ctx context.Context, serverTlsConf func() (*tls.Config, error), tlsDialerFactory reconciler.TLSDialerFactory, opts ...reconciler.ControlPlaneConnectionPoolOption) (*network.ControlServer, reconciler.ControlPlaneConnectionPool) {
+	serverCtx, serverCancelFn := context.WithCancel(ctx)
+
+	memoryNetwork := network.NewMemoryNetwork()
+	ln, err := memoryNetwork.Listen("127.0.0.1:0")
+	require.NoError(t, err)
+
+	server, err := network.StartControlServer(serverCtx, serverTlsConf, network.WithListener(ln))
+	require.NoError(t, err)
+	t.Cleanup(func() {
+		serverCancelFn()
+		<-server.ClosedCh()
+	})
+
+	connectionPool := reconciler.NewControlPlaneConnectionPool(tlsDialerFactory, append([]reconciler.ControlPlaneConnectionPoolOption{reconciler.WithDialer(memoryNetwork)}, opts...)...)
+	t.Cleanup(func() {
+		connectionPool.Close(ctx)
+	})
+
+	return server, connectionPool
+}
+
 func SendReceiveTest(t *testing.T, sender control.Service, receiver control.Service) {
 	wg := sync.WaitGroup{}
 	wg.Add(1)
@@ -144,7 +204,9 @@ func SendReceiveTest(t *testing.T, sender control.Service, receiver control.Serv
 
 func ConnectionPoolTestCases() map[string]func(t *testing.T, ctx context.Context, opts ...reconciler.ControlPlaneConnectionPoolOption) (*network.ControlServer, reconciler.ControlPlaneConnectionPool) {
 	return map[string]func(t *testing.T, ctx context.Context, opts ...reconciler.ControlPlaneConnectionPoolOption) (*network.ControlServer, reconciler.ControlPlaneConnectionPool){
-		"InsecureConnectionPool": MustSetupInsecureControlWithPool,
-		"TLSConnectionPool":      MustSetupSecureControlWithPool,
+		"InsecureConnectionPool":    MustSetupInsecureControlWithPool,
+		"TLSConnectionPool":         MustSetupSecureControlWithPool,
+		"InMemoryConnectionPool":    MustSetupInMemoryControlWithPool,
+		"InMemoryTLSConnectionPool": MustSetupSecureInMemoryControlWithPool,
 	}
 }
@@ -0,0 +1,212 @@
+/*
+Copyright 2026 The Knative Authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+// Package wiretap records the control protocol traffic of a connection and replays it.
+package wiretap
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"time"
+
+	ctrl "knative.dev/control-protocol/pkg"
+)
+
+/*
+A recording starts with the 4 bytes magic "CPWT" followed by the 1 byte format version,
+then contains a sequence of records. Every record is prefixed by its length as uvarint and contains:
+
++-----------+-----------------------------------------+
+| 1 byte    | direction                               |
+| 8 bytes   | timestamp, unix nanoseconds, big endian |
+| uvarint   | peer length                             |
+| variable  | peer                                    |
+| variable  | message, as written on the wire         |
++-----------+-----------------------------------------+
+*/
+
+const (
+	magic         = "CPWT"
+	formatVersion = uint8(1)
+
+	// maxRecordLength protects the reader from corrupted recordings
+	maxRecordLength = 64 * 1024 * 1024
+)
+
+// Direction is the direction of a recorded message
+type Direction uint8
+
+const (
+	// Inbound messages are read from the connection
+	Inbound Direction = iota
+	// Outbound messages are written to the connection
+	Outbound
+)
+
+func (d Direction) String() string {
+	switch d {
+	case Inbound:
+		return "inbound"
+	case Outbound:
+		return "outbound"
+	default:
+		return "unknown"
+	}
+}
+
+// Record is a recorded message
+type Record struct {
+	Time      time.Time
+	Direction Direction
+	// Peer is the remote address of the connection, if known
+	Peer    string
+	Message ctrl.Message
+}
+
+// Writer writes records in the wire tap format
+type Writer struct {
+	w             io.Writer
+	headerWritten bool
+	buf           bytes.Buffer
+}
+
+func NewWriter(w io.Writer) *Writer {
+	return &Writer{w: w}
+}
+
+// Write writes the record, returning the number of written bytes
+func (w *Writer) Write(record Record) (int, error) {
+	written := 0
+	if !w.headerWritten {
+		n, err := w.w.Write(append([]byte(magic), formatVersion))
+		written += n
+		if err != nil {
+			return written, err
+		}
+		w.headerWritten = true
+	}
+
+	w.buf.Reset()
+	w.buf.WriteByte(byte(record.Direction))
+	var b [binary.MaxVarintLen64]byte
+	binary.BigEndian.PutUint64(b[0:8], uint64(record.Time.UnixNano()))
+	w.buf.Write(b[0:8])
+	w.buf.Write(b[:binary.PutUvarint(b[:], uint64(len(record.Peer)))])
+	w.buf.WriteString(record.Peer)
+	if _, err := record.Message.WriteTo(&w.buf); err != nil {
+		return written, err
+	}
+
+	n, err := w.w.Write(b[:binary.PutUvarint(b[:], uint64(w.buf.Len()))])
+	written += n
+	if err != nil {
+		return written, err
+	}
+	n, err = w.w.Write(w.buf.Bytes())
+	written += n
+	return written, err
+}
+
+// IsRecording returns true if the data starts with the wire tap header
+func IsRecording(data []byte) bool {
+	return len(data) >= len(magic)+1 && string(data[:len(magic)]) == magic && data[len(magic)] == formatVersion
+}
+
+// Reader reads records written in the wire tap format
+type Reader struct {
+	r            *bufio.Reader
+	headerParsed bool
+}
+
+func NewReader(r io.Reader) *Reader {
+	return &Reader{r: bufio.NewReader(r)}
+}
+
+// Next reads the next record. It returns io.EOF when there are no more records.
+func (r *Reader) Next() (*Record, error) {
+	if !r.headerParsed {
+		var header [len(magic) + 1]byte
+		if _, err := io.ReadFull(r.r, header[:]); err != nil {
+			if errors.Is(err, io.ErrUnexpectedEOF) {
+				return nil, fmt.Errorf("truncated wire tap header: %w", err)
+			}
+			return nil, err
+		}
+		if string(header[:len(magic)]) != magic {
+			return nil, fmt.Errorf("not a wire tap recording")
+		}
+		if header[len(magic)] != formatVersion {
+			return nil, fmt.Errorf("unsupported wire tap format version: %d", header[len(magic)])
+		}
+		r.headerParsed = true
+	}
+
+	length, err := binary.ReadUvarint(r.r)
+	if err != nil {
+		return nil, err
+	}
+	if length > maxRecordLength {
+		return nil, fmt.Errorf("record too long: %d", length)
+	}
+	b := make([]byte, length)
+	if _, err := io.ReadFull(r.r, b); err != nil {
+		return nil, fmt.Errorf("truncated record: %w", err)
+	}
+
+	return parseRecord(b)
+}
+
+// ReadAll reads all the records
+func (r *Reader) ReadAll() ([]Record, error) {
+	var records []Record
+	for {
+		record, err := r.Next()
+		if errors.Is(err, io.EOF) {
+			return records, nil
+		}
+		if err != nil {
+			return records, err
+		}
+		records = append(records, *record)
+	}
+}
+
+func parseRecord(b []byte) (*Record, error) {
+	if len(b) < 9 {
+		return nil, fmt.Errorf("record too short: %d", len(b))
+	}
+	record := &Record{
+		Direction: Direction(b[0]),
+		Time:      time.Unix(0, int64(binary.BigEndian.Uint64(b[1:9]))),
+	}
+	b = b[9:]
+
+	peerLength, n := binary.Uvarint(b)
+	if n <= 0 || uint64(len(b)-n) < peerLength {
+		return nil, fmt.Errorf("malformed record peer")
+	}
+	record.Peer = string(b[n : n+int(peerLength)])
+	b = b[n+int(peerLength):]
+
+	if _, err := record.Message.ReadFrom(bytes.NewReader(b)); err != nil {
+		return nil, fmt.Errorf("malformed record message: %w", err)
+	}
+	return record, nil
+}
@@ -0,0 +1,136 @@
+/*
+Copyright 2026 The Knative Authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package wiretap
+
+import (
+	"context"
+	"sync"
+
+	ctrl "knative.dev/control-protocol/pkg"
+)
+
+// ReplayResult is the outcome of a replayed message
+type ReplayResult struct {
+	Record Record
+	// AckErr is the error the handler acked the message with
+	AckErr error
+}
+
+// ReplayToHandler delivers the recorded messages with the provided direction to the handler, in the recorded order.
+// Every message must be acked before the next one is delivered. Recorded acks are skipped.
+// Like the control service, the trace context is stripped from the payload and attached to the context passed to the handler.
+// It returns the results of the delivered messages, and the context error if the context is done before all the messages are acked.
+func ReplayToHandler(ctx context.Context, records []Record, direction Direction, handler ctrl.MessageHandler) ([]ReplayResult, error) {
+	var results []ReplayResult
+	for _, record := range records {
+		if record.Direction != direction || record.Message.OpCode() == uint8(ctrl.AckOpCode) {
+			continue
+		}
+
+		msg := record.Message
+		handlerCtx := ctx
+		traceContext, err := msg.StripTraceContext()
+		if err != nil {
+			results = append(results, ReplayResult{Record: record, AckErr: err})
+			continue
+		}
+		if traceContext != nil {
+			handlerCtx = ctrl.ContextWithTraceContext(ctx, traceContext)
+		}
+
+		ackCh := make(chan error, 1)
+		var once sync.Once
+		handler.HandleServiceMessage(handlerCtx, ctrl.NewServiceMessage(&msg, func(err error) {
+			once.Do(func() {
+				ackCh <- err
+			})
+		}))
+
+		select {
+		case err := <-ackCh:
+			results = append(results, ReplayResult{Record: record, AckErr: err})
+		case <-ctx.Done():
+			return results, ctx.Err()
+		}
+	}
+	return results, nil
+}
+
+// ReplayConnection is a control.Connection returning the recorded messages from ReadMessage,
+// so they can be fed to a control service, e.g. created with service.NewService.
+type ReplayConnection struct {
+	inbound chan *ctrl.Message
+	errors  chan error
+	closeCh chan struct{}
+	once    sync.Once
+
+	mutex   sync.Mutex
+	written []*ctrl.Message
+}
+
+var _ ctrl.Connection = (*ReplayConnection)(nil)
+
+// NewReplayConnection creates a connection reading the recorded messages with the provided direction, in the recorded order.
+// Once all the messages are read, ReadMessage blocks until the connection is closed.
+func NewReplayConnection(records []Record, direction Direction) *ReplayConnection {
+	c := &ReplayConnection{
+		inbound: make(chan *ctrl.Message, len(records)),
+		errors:  make(chan error),
+		closeCh: make(chan struct{}),
+	}
+	for _, record := range records {
+		if record.Direction == direction {
+			msg := record.Message
+			c.inbound <- &msg
+		}
+	}
+	return c
+}
+
+func (c *ReplayConnection) WriteMessage(msg *ctrl.Message) {
+	c.mutex.Lock()
+	c.written = append(c.written, msg)
+	c.mutex.Unlock()
+}
+
+func (c *ReplayConnection) ReadMessage() *ctrl.Message {
+	select {
+	case msg := <-c.inbound:
+		return msg
+	case <-c.closeCh:
+		return nil
+	}
+}
+
+func (c *ReplayConnection) Errors() <-chan error {
+	return c.errors
+}
+
+// Written returns the messages written to this connection, e.g. the acks of the replayed messages
+func (c *ReplayConnection) Written() []*ctrl.Message {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+	return append([]*ctrl.Message(nil), c.written...)
+}
+
+// Close closes the connection, unblocking ReadMessage
+func (c *ReplayConnection) Close() {
+	c.once.Do(func() {
+		close(c.closeCh)
+		close(c.errors)
+	})
+}
@@ -0,0 +1,172 @@
+/*
+Copyright 2026 The Knative Authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package wiretap
+
+import (
+	"io"
+	"net"
+	"sync"
+	"time"
+
+	ctrl "knative.dev/control-protocol/pkg"
+)
+
+type TapOption func(*Tap)
+
+// WithMaxBytes bounds the size of the recording. Once the limit is reached, the capture is stopped.
+func WithMaxBytes(maxBytes int64) TapOption {
+	return func(t *Tap) {
+		t.maxBytes = maxBytes
+	}
+}
+
+// StartDisabled creates the Tap with the capture disabled, use Tap.Enable to start capturing
+func StartDisabled() TapOption {
+	return func(t *Tap) {
+		t.enabled = false
+	}
+}
+
+// Tap records the messages exchanged through the wrapped connections.
+// The same Tap can wrap several connections, all writing to the same recording.
+type Tap struct {
+	mutex    sync.Mutex
+	writer   *Writer
+	enabled  bool
+	maxBytes int64
+	written  int64
+	// limitReached is true once a record didn't fit in the size limit
+	limitReached bool
+	err          error
+}
+
+// NewTap creates a new Tap writing the recording to w. The capture is enabled by default.
+func NewTap(w io.Writer, opts ...TapOption) *Tap {
+	t := &Tap{
+		writer:  NewWriter(w),
+		enabled: true,
+	}
+	for _, fn := range opts {
+		fn(t)
+	}
+	return t
+}
+
+// Enable starts the capture, unless the size limit was already reached or an error occurred while writing
+func (t *Tap) Enable() {
+	t.mutex.Lock()
+	t.enabled = t.err == nil && !t.limitReached
+	t.mutex.Unlock()
+}
+
+// Disable stops the capture
+func (t *Tap) Disable() {
+	t.mutex.Lock()
+	t.enabled = false
+	t.mutex.Unlock()
+}
+
+// Enabled returns true if the capture is enabled
+func (t *Tap) Enabled() bool {
+	t.mutex.Lock()
+	defer t.mutex.Unlock()
+	return t.enabled
+}
+
+// Written returns the number of bytes written to the recording
+func (t *Tap) Written() int64 {
+	t.mutex.Lock()
+	defer t.mutex.Unlock()
+	return t.written
+}
+
+// Err returns the error which stopped the capture, if any
+func (t *Tap) Err() error {
+	t.mutex.Lock()
+	defer t.mutex.Unlock()
+	return t.err
+}
+
+// Wrap wraps the connection, recording every message written to and read from it.
+// This method can be used as control.ConnectionWrapper.
+func (t *Tap) Wrap(conn ctrl.Connection) ctrl.Connection {
+	return &tappedConnection{
+		Connection: conn,
+		tap:        t,
+	}
+}
+
+func (t *Tap) record(direction Direction, peer string, msg *ctrl.Message) {
+	t.mutex.Lock()
+	defer t.mutex.Unlock()
+	if !t.enabled {
+		return
+	}
+	// The size of the record is known only after serializing it, let's check the limit with the upper bound
+	if t.maxBytes > 0 && t.written+recordUpperBound(peer, msg) > t.maxBytes {
+		t.limitReached = true
+		t.enabled = false
+		return
+	}
+
+	n, err := t.writer.Write(Record{
+		Time:      time.Now(),
+		Direction: direction,
+		Peer:      peer,
+		Message:   *msg,
+	})
+	t.written += int64(n)
+	if err != nil {
+		t.err = err
+		t.enabled = false
+	}
+}
+
+func recordUpperBound(peer string, msg *ctrl.Message) int64 {
+	// header + length prefix + direction + timestamp + peer length prefix + peer + message
+	return int64(len(magic)+1) + 10 + 1 + 8 + 10 + int64(len(peer)) + 24 + int64(msg.Length())
+}
+
+type tappedConnection struct {
+	ctrl.Connection
+
+	tap *Tap
+}
+
+var _ ctrl.Connection = (*tappedConnection)(nil)
+
+func (c *tappedConnection) WriteMessage(msg *ctrl.Message) {
+	c.tap.record(Outbound, c.peer(), msg)
+	c.Connection.WriteMessage(msg)
+}
+
+func (c *tappedConnection) ReadMessage() *ctrl.Message {
+	msg := c.Connection.ReadMessage()
+	if msg != nil {
+		c.tap.record(Inbound, c.peer(), msg)
+	}
+	return msg
+}
+
+func (c *tappedConnection) peer() string {
+	if withAddr, ok := c.Connection.(interface{ RemoteAddr() net.Addr }); ok {
+		if addr := withAddr.RemoteAddr(); addr != nil {
+			return addr.String()
+		}
+	}
+	return ""
+}
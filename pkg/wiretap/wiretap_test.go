@@ -0,0 +1,225 @@
+/*
+Copyright 2026 The Knative Authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package wiretap_test
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+	"knative.dev/pkg/logging"
+
+	ctrl "knative.dev/control-protocol/pkg"
+	"knative.dev/control-protocol/pkg/network"
+	"knative.dev/control-protocol/pkg/service"
+	"knative.dev/control-protocol/pkg/test"
+	"knative.dev/control-protocol/pkg/wiretap"
+)
+
+func TestWriterReader_RoundTrip(t *testing.T) {
+	var buf bytes.Buffer
+	w := wiretap.NewWriter(&buf)
+
+	now := time.Unix(0, time.Now().UnixNano())
+	records := []wiretap.Record{{
+		Time:      now,
+		Direction: wiretap.Outbound,
+		Peer:      "10.0.0.1:9000",
+		Message:   ctrl.NewMessage(uuid.New(), 1, []byte("Funky!")),
+	}, {
+		Time:      now.Add(time.Second),
+		Direction: wiretap.Inbound,
+		Message:   ctrl.NewMessage(uuid.New(), uint8(ctrl.AckOpCode), nil),
+	}}
+	for _, r := range records {
+		_, err := w.Write(r)
+		require.NoError(t, err)
+	}
+
+	read, err := wiretap.NewReader(&buf).ReadAll()
+	require.NoError(t, err)
+	require.Len(t, read, 2)
+	for i := range records {
+		require.True(t, records[i].Time.Equal(read[i].Time))
+		require.Equal(t, records[i].Direction, read[i].Direction)
+		require.Equal(t, records[i].Peer, read[i].Peer)
+		require.Equal(t, records[i].Message.MessageHeader, read[i].Message.MessageHeader)
+		require.Equal(t, records[i].Message.Payload(), read[i].Message.Payload())
+	}
+}
+
+func TestReader_Malformed(t *testing.T) {
+	_, err := wiretap.NewReader(bytes.NewReader([]byte("nope!"))).Next()
+	require.Error(t, err)
+
+	var buf bytes.Buffer
+	_, err = wiretap.NewWriter(&buf).Write(wiretap.Record{Message: ctrl.NewMessage(uuid.New(), 1, []byte("Funky!"))})
+	require.NoError(t, err)
+	_, err = wiretap.NewReader(bytes.NewReader(buf.Bytes()[:buf.Len()-2])).Next()
+	require.Error(t, err)
+}
+
+func TestTap_SwitchableAndBounded(t *testing.T) {
+	var buf bytes.Buffer
+	tap := wiretap.NewTap(&buf, wiretap.StartDisabled(), wiretap.WithMaxBytes(200))
+	conn := tap.Wrap(test.NewConnectionMock())
+
+	msg := ctrl.NewMessage(uuid.New(), 1, []byte("Funky!"))
+	conn.WriteMessage(&msg)
+	require.Zero(t, tap.Written())
+
+	tap.Enable()
+	for i := 0; i < 10; i++ {
+		conn.WriteMessage(&msg)
+	}
+	require.NoError(t, tap.Err())
+	require.LessOrEqual(t, tap.Written(), int64(200))
+	require.False(t, tap.Enabled())
+
+	records, err := wiretap.NewReader(&buf).ReadAll()
+	require.NoError(t, err)
+	require.NotEmpty(t, records)
+	require.Less(t, len(records), 10)
+
+	// Cannot re-enable once the limit is reached
+	tap.Enable()
+	require.False(t, tap.Enabled())
+}
+
+func TestTap_RecordsConnectionTraffic(t *testing.T) {
+	logger, _ := zap.NewDevelopment()
+	ctx, cancelFn := context.WithCancel(logging.WithLogger(context.TODO(), logger.Sugar()))
+	t.Cleanup(cancelFn)
+
+	var buf syncBuffer
+	tap := wiretap.NewTap(&buf)
+
+	server, err := network.StartInsecureControlServer(ctx, network.WithPort(0), network.WithConnectionWrappers(tap.Wrap))
+	require.NoError(t, err)
+	client, err := network.StartControlClient(ctx, &net.Dialer{}, fmt.Sprintf("127.0.0.1:%d", server.ListeningPort()))
+	require.NoError(t, err)
+
+	server.MessageHandler(ctrl.MessageHandlerFunc(func(ctx context.Context, message ctrl.ServiceMessage) {
+		message.Ack()
+	}))
+	require.NoError(t, client.SendAndWaitForAck(1, test.MockPayload("Funky!")))
+
+	var records []wiretap.Record
+	require.Eventually(t, func() bool {
+		records, err = wiretap.NewReader(bytes.NewReader(buf.Bytes())).ReadAll()
+		return err == nil && len(records) == 2
+	}, 5*time.Second, 10*time.Millisecond)
+
+	require.Equal(t, wiretap.Inbound, records[0].Direction)
+	require.Equal(t, uint8(1), records[0].Message.OpCode())
+	require.Equal(t, []byte("Funky!"), records[0].Message.Payload())
+	require.NotEmpty(t, records[0].Peer)
+
+	require.Equal(t, wiretap.Outbound, records[1].Direction)
+	require.Equal(t, uint8(ctrl.AckOpCode), records[1].Message.OpCode())
+	require.Equal(t, records[0].Message.UUID(), records[1].Message.UUID())
+}
+
+func TestReplayToHandler(t *testing.T) {
+	records := []wiretap.Record{
+		{Direction: wiretap.Inbound, Message: ctrl.NewMessage(uuid.New(), 1, []byte("a"))},
+		{Direction: wiretap.Outbound, Message: ctrl.NewMessage(uuid.New(), 1, []byte("b"))},
+		{Direction: wiretap.Inbound, Message: ctrl.NewMessage(uuid.New(), uint8(ctrl.AckOpCode), nil)},
+		{Direction: wiretap.Inbound, Message: ctrl.NewMessage(uuid.New(), 2, []byte("c"))},
+	}
+
+	var payloads []string
+	results, err := wiretap.ReplayToHandler(context.TODO(), records, wiretap.Inbound, ctrl.MessageHandlerFunc(func(ctx context.Context, message ctrl.ServiceMessage) {
+		payloads = append(payloads, string(message.Payload()))
+		if message.Headers().OpCode() == 2 {
+			go message.AckWithError(errors.New("yuck"))
+			return
+		}
+		message.Ack()
+	}))
+	require.NoError(t, err)
+	require.Equal(t, []string{"a", "c"}, payloads)
+	require.Len(t, results, 2)
+	require.NoError(t, results[0].AckErr)
+	require.EqualError(t, results[1].AckErr, "yuck")
+}
+
+func TestReplayToHandler_StripsTraceContext(t *testing.T) {
+	records := []wiretap.Record{
+		{Direction: wiretap.Inbound, Message: ctrl.NewMessage(uuid.New(), 1, []byte("a"), ctrl.WithTraceContext([]byte("trace")))},
+	}
+
+	var payloads, traceContexts []string
+	results, err := wiretap.ReplayToHandler(context.TODO(), records, wiretap.Inbound, ctrl.MessageHandlerFunc(func(ctx context.Context, message ctrl.ServiceMessage) {
+		payloads = append(payloads, string(message.Payload()))
+		traceContexts = append(traceContexts, string(ctrl.TraceContextFromContext(ctx)))
+		message.Ack()
+	}))
+	require.NoError(t, err)
+	require.Len(t, results, 1)
+	require.Equal(t, []string{"a"}, payloads)
+	require.Equal(t, []string{"trace"}, traceContexts)
+}
+
+func TestReplayConnection_FeedsService(t *testing.T) {
+	ctx, cancelFn := context.WithCancel(context.TODO())
+	t.Cleanup(cancelFn)
+
+	records := []wiretap.Record{
+		{Direction: wiretap.Inbound, Message: ctrl.NewMessage(uuid.New(), 1, []byte("a"))},
+		{Direction: wiretap.Inbound, Message: ctrl.NewMessage(uuid.New(), 1, []byte("b"))},
+	}
+	conn := wiretap.NewReplayConnection(records, wiretap.Inbound)
+	t.Cleanup(conn.Close)
+
+	svc := service.NewService(ctx, conn)
+	svc.MessageHandler(ctrl.MessageHandlerFunc(func(ctx context.Context, message ctrl.ServiceMessage) {
+		message.Ack()
+	}))
+
+	require.Eventually(t, func() bool {
+		return len(conn.Written()) == 2
+	}, 5*time.Second, 10*time.Millisecond)
+	for _, ack := range conn.Written() {
+		require.Equal(t, uint8(ctrl.AckOpCode), ack.OpCode())
+	}
+}
+
+type syncBuffer struct {
+	mutex sync.Mutex
+	buf   bytes.Buffer
+}
+
+func (b *syncBuffer) Write(p []byte) (int, error) {
+	b.mutex.Lock()
+	defer b.mutex.Unlock()
+	return b.buf.Write(p)
+}
+
+func (b *syncBuffer) Bytes() []byte {
+	b.mutex.Lock()
+	defer b.mutex.Unlock()
+	return append([]byte(nil), b.buf.Bytes()...)
+}